@@ -5,19 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
 	"sync"
+	"syscall"
+	"time"
 
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
 	"accidentallycoded.com/fredboard/v3/internal/config"
 	"accidentallycoded.com/fredboard/v3/internal/discord"
+	"accidentallycoded.com/fredboard/v3/internal/soundboard"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 	_ "accidentallycoded.com/fredboard/v3/internal/telemetry/pprof"
 	"accidentallycoded.com/fredboard/v3/internal/version"
 )
 
+// how long to wait for audio sessions to finish before exiting anyway
+const shutdownTimeout = 10 * time.Second
+
 var logger *logging.Logger
 
 func init() {
@@ -117,6 +125,13 @@ func main() {
 		}
 	}()
 
+	// clips are decoded up front so that playing one never has to start ffmpeg
+	err = soundboard.Init(logger)
+	if err != nil {
+		logger.Warn("failed to load soundboard. the soundboard is disabled", "error", err)
+		err = nil
+	}
+
 	bot := discord.NewBot(config.Get().Discord.AppId, config.Get().Discord.Token, logger)
 
 	wg.Add(1)
@@ -125,13 +140,53 @@ func main() {
 		bot.Run(ctx)
 	}()
 
+	// listeners can stream a session over http with the token that it was shared with
+	var webServer *http.Server
+
+	if config.Get().Web.StreamEnabled {
+		mux := http.NewServeMux()
+		mux.Handle("GET /sessions/{session}/stream", audiosession.NewHTTPStreamHandler(logger))
+		webServer = &http.Server{Addr: config.Get().Web.Address, Handler: mux}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := webServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Warn("web server stopped", "address", webServer.Addr, "error", err)
+			}
+		}()
+	}
+
 	logger.Info("press ^c to exit")
 
+	// SIGTERM is sent by container runtimes when stopping
 	intSig := make(chan os.Signal, 1)
-	signal.Notify(intSig, os.Interrupt)
-	<-intSig
+	signal.Notify(intSig, os.Interrupt, syscall.SIGTERM)
+	sig := <-intSig
+
+	logger.Info("received shutdown signal", "signal", sig)
+
+	// the audio sessions are shutdown before the bot so that voice connections can still be disconnected
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	shutdownErr := audiosession.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		logger.Warn("audio sessions did not shutdown before the deadline", "timeout", shutdownTimeout, "error", shutdownErr)
+	} else {
+		logger.Info("audio sessions shutdown")
+	}
 
-	logger.Info("received interrupt signal")
+	// the http streams have ended along with their sessions
+	if webServer != nil {
+		err = webServer.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Warn("web server did not shutdown before the deadline", "error", err)
+			err = nil
+		}
+	}
 
 	cancel()
 	wg.Wait()
@@ -0,0 +1,178 @@
+package codecs
+
+import (
+	"errors"
+	"io"
+)
+
+var crc8Table, crc16Table = func() (crc8 [256]byte, crc16 [256]uint16) {
+	for i := range 256 {
+		c8 := byte(i)
+		for range 8 {
+			if c8&0x80 != 0 {
+				c8 = c8<<1 ^ 0x07
+			} else {
+				c8 <<= 1
+			}
+		}
+		crc8[i] = c8
+
+		c16 := uint16(i) << 8
+		for range 8 {
+			if c16&0x8000 != 0 {
+				c16 = c16<<1 ^ 0x8005
+			} else {
+				c16 <<= 1
+			}
+		}
+		crc16[i] = c16
+	}
+
+	return crc8, crc16
+}()
+
+// crc-8 with polynomial x^8 + x^2 + x^1 + x^0
+func crc8(crc byte, p []byte) byte {
+	for _, b := range p {
+		crc = crc8Table[crc^b]
+	}
+
+	return crc
+}
+
+// crc-16 with polynomial x^16 + x^15 + x^2 + x^0
+func crc16(crc uint16, p []byte) uint16 {
+	for _, b := range p {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^b]
+	}
+
+	return crc
+}
+
+// writes values with an arbitrary number of bits, most significant bit first
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint // number of bits in acc that have not been written to buf
+}
+
+func (w *bitWriter) writeBits(v uint64, nBits uint) {
+	if nBits > 32 {
+		w.writeBits(v>>32, nBits-32)
+		nBits = 32
+	}
+
+	w.acc = w.acc<<nBits | (v & (1<<nBits - 1))
+	w.n += nBits
+
+	for w.n >= 8 {
+		w.n -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.n))
+	}
+}
+
+func (w *bitWriter) writeSigned(v int64, nBits uint) {
+	w.writeBits(uint64(v), nBits)
+}
+
+// writes q zero bits followed by a single one bit
+func (w *bitWriter) writeUnary(q uint64) {
+	for ; q >= 32; q -= 32 {
+		w.writeBits(0, 32)
+	}
+
+	w.writeBits(1, uint(q)+1)
+}
+
+// pads with zero bits until the next byte boundary
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.writeBits(0, 8-w.n)
+	}
+}
+
+var errBitReaderUnexpectedEOF = errors.New("unexpected EOF while reading bits")
+
+// reads values with an arbitrary number of bits, most significant bit first. a crc-8 and crc-16
+// is computed over every byte that is read
+type bitReader struct {
+	r   io.ByteReader
+	acc uint64
+	n   uint // number of bits in acc that have not been read
+
+	crc8  byte
+	crc16 uint16
+}
+
+func (r *bitReader) resetCRC() {
+	r.crc8 = 0
+	r.crc16 = 0
+}
+
+func (r *bitReader) readBits(nBits uint) (uint64, error) {
+	if nBits > 32 {
+		hi, err := r.readBits(nBits - 32)
+		if err != nil {
+			return 0, err
+		}
+
+		lo, err := r.readBits(32)
+		return hi<<32 | lo, err
+	}
+
+	for r.n < nBits {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return 0, errBitReaderUnexpectedEOF
+			}
+
+			return 0, err
+		}
+
+		r.crc8 = crc8Table[r.crc8^b]
+		r.crc16 = r.crc16<<8 ^ crc16Table[byte(r.crc16>>8)^b]
+
+		r.acc = r.acc<<8 | uint64(b)
+		r.n += 8
+	}
+
+	r.n -= nBits
+	return (r.acc >> r.n) & (1<<nBits - 1), nil
+}
+
+func (r *bitReader) readSigned(nBits uint) (int64, error) {
+	v, err := r.readBits(nBits)
+	if err != nil || nBits == 0 {
+		return 0, err
+	}
+
+	if v&(1<<(nBits-1)) != 0 {
+		return int64(v) - int64(1)<<nBits, nil
+	}
+
+	return int64(v), nil
+}
+
+// counts the zero bits before the next one bit
+func (r *bitReader) readUnary() (uint64, error) {
+	var q uint64
+
+	for {
+		bit, err := r.readBits(1)
+		if err != nil {
+			return 0, err
+		}
+
+		if bit == 1 {
+			return q, nil
+		}
+
+		q++
+	}
+}
+
+// discards bits until the next byte boundary
+func (r *bitReader) align() {
+	r.n -= r.n % 8
+}
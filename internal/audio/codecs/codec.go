@@ -0,0 +1,229 @@
+package codecs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCodecNotFound     = errors.New("codec not found")
+	ErrUnsupportedFormat = errors.New("codec does not support audio format")
+)
+
+var registry = struct {
+	sync.Mutex
+	codecs map[string]Codec
+}{codecs: make(map[string]Codec)}
+
+// writes encoded packets
+type PacketWriter interface {
+	Write(p []Packet) (n int /* number of packets consumed */, err error)
+}
+
+// reads encoded packets one at a time
+type PacketReader interface {
+	// returns [io.EOF] when there are no more packets
+	ReadPacket() (Packet, error)
+}
+
+// an [io.WriteCloser] that encodes 16-bit signed little endian PCM into packets
+type EncoderWriter interface {
+	io.WriteCloser
+
+	// encodes any buffered pcm data without closing the writer
+	Flush() error
+}
+
+type EncoderOptions struct {
+	NumChannels  int
+	SampleRateHz int
+
+	// number of samples per channel in each packet. codecs that are not packet based ignore this
+	FrameSize int
+}
+
+type DecoderOptions struct {
+	NumChannels  int
+	SampleRateHz int
+
+	// called with every packet and the pcm that it decoded to, before the pcm is read. only codecs
+	// that decode self contained packets call it
+	OnPacket func(packet Packet, pcm []byte)
+}
+
+type Codec struct {
+	Name string
+
+	// nil if any sample rate is supported
+	SampleRatesHz []int
+
+	// nil if any number of channels is supported
+	NumChannels []int
+
+	// encodes 16-bit signed little endian PCM and writes the packets to w
+	NewEncoderWriter func(w PacketWriter, opts EncoderOptions) (EncoderWriter, error)
+
+	// decodes packets from r into 16-bit signed little endian PCM
+	NewDecoderReader func(r PacketReader, opts DecoderOptions) (io.Reader, error)
+
+	// encodes 16-bit signed little endian PCM into a self contained file that is written to w. if w
+	// is an [io.WriteSeeker], headers may be rewritten when the writer is closed. nil if the codec
+	// has no file format
+	NewFileEncoderWriter func(w io.Writer, opts EncoderOptions) (EncoderWriter, error)
+}
+
+func (c Codec) Supports(sampleRateHz, nChannels int) bool {
+	if c.SampleRatesHz != nil && !slices.Contains(c.SampleRatesHz, sampleRateHz) {
+		return false
+	}
+
+	if c.NumChannels != nil && !slices.Contains(c.NumChannels, nChannels) {
+		return false
+	}
+
+	return true
+}
+
+// creates an encoder after checking that the codec supports the audio format
+func (c Codec) NewEncoder(w PacketWriter, opts EncoderOptions) (EncoderWriter, error) {
+	if !c.Supports(opts.SampleRateHz, opts.NumChannels) {
+		return nil, fmt.Errorf("%w: %s does not support %dHz with %d channels", ErrUnsupportedFormat, c.Name, opts.SampleRateHz, opts.NumChannels)
+	}
+
+	return c.NewEncoderWriter(w, opts)
+}
+
+// creates a file encoder after checking that the codec supports the audio format
+func (c Codec) NewFileEncoder(w io.Writer, opts EncoderOptions) (EncoderWriter, error) {
+	if c.NewFileEncoderWriter == nil {
+		return nil, fmt.Errorf("%w: %s has no file format", ErrUnsupportedFormat, c.Name)
+	}
+
+	if !c.Supports(opts.SampleRateHz, opts.NumChannels) {
+		return nil, fmt.Errorf("%w: %s does not support %dHz with %d channels", ErrUnsupportedFormat, c.Name, opts.SampleRateHz, opts.NumChannels)
+	}
+
+	return c.NewFileEncoderWriter(w, opts)
+}
+
+// creates a decoder after checking that the codec supports the audio format
+func (c Codec) NewDecoder(r PacketReader, opts DecoderOptions) (io.Reader, error) {
+	if !c.Supports(opts.SampleRateHz, opts.NumChannels) {
+		return nil, fmt.Errorf("%w: %s does not support %dHz with %d channels", ErrUnsupportedFormat, c.Name, opts.SampleRateHz, opts.NumChannels)
+	}
+
+	return c.NewDecoderReader(r, opts)
+}
+
+// makes a codec available to [Lookup]. panics if a codec with the same name is already registered
+func Register(codec Codec) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.codecs[codec.Name]; ok {
+		panic("codec already registered: " + codec.Name)
+	}
+
+	registry.codecs[codec.Name] = codec
+}
+
+// finds a registered codec by name
+//
+// returns [ErrCodecNotFound] if there is no codec with the name
+func Lookup(name string) (Codec, error) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	codec, ok := registry.codecs[name]
+	if !ok {
+		return Codec{}, fmt.Errorf("%w: %s", ErrCodecNotFound, name)
+	}
+
+	return codec, nil
+}
+
+// returns all registered codecs sorted by name
+func Codecs() []Codec {
+	registry.Lock()
+	defer registry.Unlock()
+
+	codecs := make([]Codec, 0, len(registry.codecs))
+	for _, codec := range registry.codecs {
+		codecs = append(codecs, codec)
+	}
+
+	slices.SortFunc(codecs, func(a, b Codec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return codecs
+}
+
+// adapts a [PacketWriter] for codecs that produce a byte stream instead of packets. each write is
+// sent as a single packet
+type packetStreamWriter struct {
+	w     PacketWriter
+	clock packetClock
+
+	// size of a single sample across all channels in bytes. 0 if the stream is not pcm, in which
+	// case packets do not have a duration
+	sampleSizeBytes int
+}
+
+func newPacketStreamWriter(w PacketWriter) *packetStreamWriter {
+	return &packetStreamWriter{w: w}
+}
+
+func (s *packetStreamWriter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	nSamples := 0
+	if s.sampleSizeBytes > 0 {
+		nSamples = len(p) / s.sampleSizeBytes
+	}
+
+	packets := []Packet{s.clock.next(slices.Clone(p), nSamples)}
+	for len(packets) > 0 {
+		n, err := s.w.Write(packets)
+		packets = packets[n:]
+
+		if err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// adapts a [PacketReader] for codecs that consume a byte stream instead of packets by
+// concatenating the packets
+type packetStreamReader struct {
+	r      PacketReader
+	packet []byte
+}
+
+func newPacketStreamReader(r PacketReader) *packetStreamReader {
+	return &packetStreamReader{r: r}
+}
+
+func (s *packetStreamReader) Read(p []byte) (n int, err error) {
+	for len(s.packet) == 0 {
+		packet, err := s.r.ReadPacket()
+		if err != nil {
+			return 0, err
+		}
+
+		s.packet = packet.Data
+	}
+
+	n = copy(p, s.packet)
+	s.packet = s.packet[n:]
+
+	return n, nil
+}
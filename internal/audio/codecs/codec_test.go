@@ -0,0 +1,117 @@
+package codecs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+)
+
+type packetReader struct {
+	Frames []codecs.Packet
+}
+
+func (r *packetReader) ReadPacket() (codecs.Packet, error) {
+	if len(r.Frames) == 0 {
+		return codecs.Packet{}, io.EOF
+	}
+
+	frame := r.Frames[0]
+	r.Frames = r.Frames[1:]
+
+	return frame, nil
+}
+
+func TestLookupRegisteredCodecs(t *testing.T) {
+	for _, name := range []string{codecs.CodecName_Opus, codecs.CodecName_PCMS16LE} {
+		codec, err := codecs.Lookup(name)
+		if err != nil {
+			t.Fatalf("failed to lookup codec %s: %s", name, err.Error())
+		}
+
+		if codec.Name != name {
+			t.Fatalf("incorrect codec name. want %s, got %s", name, codec.Name)
+		}
+	}
+
+	_, err := codecs.Lookup("does-not-exist")
+	if !errors.Is(err, codecs.ErrCodecNotFound) {
+		t.Fatalf("expected ErrCodecNotFound, got %v", err)
+	}
+}
+
+func TestCodecRejectsUnsupportedFormat(t *testing.T) {
+	opus, err := codecs.Lookup(codecs.CodecName_Opus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer packetBuffer
+	_, err = opus.NewEncoder(&buffer, codecs.EncoderOptions{NumChannels: 2, SampleRateHz: 44100, FrameSize: 960})
+	if !errors.Is(err, codecs.ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestOpusCodecFileEncoderWritesOgg(t *testing.T) {
+	opus, err := codecs.Lookup(codecs.CodecName_Opus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file bytes.Buffer
+	_, err = opus.NewFileEncoder(&file, codecs.EncoderOptions{NumChannels: 2, SampleRateHz: 44100, FrameSize: 960})
+	if !errors.Is(err, codecs.ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+
+	w, err := opus.NewFileEncoder(&file, codecs.EncoderOptions{NumChannels: 2, SampleRateHz: 48000, FrameSize: 960})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write(make([]byte, 960*2*2))
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(file.Bytes(), []byte("OggS")) {
+		t.Fatalf("expected an ogg stream, got % x", file.Bytes()[:min(file.Len(), 4)])
+	}
+}
+
+func TestPCMCodecRoundTrip(t *testing.T) {
+	pcm, err := codecs.Lookup(codecs.CodecName_PCMS16LE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer packetBuffer
+	w, err := pcm.NewEncoder(&buffer, codecs.EncoderOptions{NumChannels: 2, SampleRateHz: 44100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	w.Write(want[:4])
+	w.Write(want[4:])
+	w.Close()
+
+	r, err := pcm.NewDecoder(&packetReader{Frames: buffer.Frames}, codecs.DecoderOptions{NumChannels: 2, SampleRateHz: 44100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("incorrect pcm. want %v, got %v", want, got)
+	}
+}
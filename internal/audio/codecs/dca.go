@@ -0,0 +1,347 @@
+package codecs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// DCA files store encoded packets (normally opus frames) so that they can be replayed without re-encoding.
+//
+// layout:
+//
+//	"DCA1"
+//	int32 (LE) length of json metadata
+//	json metadata
+//	frames: int16 (LE) length of frame, uint32 (LE) sequence number, uint64 (LE) timestamp, uint32 (LE) number of samples, followed by the frame itself
+//	trailer: int16 (LE) -1, uint32 (LE) number of frames, uint32 (LE) length of json tags, json tags, uint32 (LE) crc32 of all frames (including their headers) and the tags
+//
+// the timing fields in the frame headers and the trailer are not part of the original DCA format.
+// the timing fields are used to keep the position of every packet in the stream and the trailer is
+// used to detect truncated or corrupted files. the tags are in the trailer so that they can be
+// added once all of the frames have been written. version 2 files have no tags
+const (
+	dcaMagic         = "DCA1"
+	dcaVersion       = 3
+	dcaTrailerMarker = 0xFFFF // -1 as an int16
+
+	// the oldest version that can still be read
+	dcaMinVersion = 2
+
+	dcaFrameHeaderLen = 2 + 4 + 8 + 4
+	dcaMaxMetadataLen = 0xFFFF
+)
+
+var (
+	ErrDCAInvalidMagic       = errors.New("dca: invalid magic number")
+	ErrDCATruncated          = errors.New("dca: file is truncated")
+	ErrDCAChecksumMismatch   = errors.New("dca: checksum mismatch")
+	ErrDCAUnsupportedVersion = errors.New("dca: unsupported version")
+)
+
+var _ PacketWriter = (*DCAWriter)(nil)
+var _ PacketReader = (*DCAReader)(nil)
+
+type DCAMetadata struct {
+	Codec        string `json:"codec"`
+	SampleRateHz int    `json:"sampleRateHz"`
+	NumChannels  int    `json:"numChannels"`
+	FrameSize    int    `json:"frameSize"`
+}
+
+// the metadata as it is stored in the file
+type dcaFileMetadata struct {
+	DCAMetadata
+	Version int `json:"version"`
+}
+
+type DCAWriter struct {
+	w       io.Writer
+	crc     hash.Hash32
+	nFrames uint32
+	tags    map[string]string
+	closed  bool
+}
+
+func (w *DCAWriter) Write(p []Packet) (n int, err error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	for _, packet := range p {
+		if len(packet.Data) > math.MaxInt16 {
+			return n, fmt.Errorf("dca: frame of %d bytes is too large", len(packet.Data))
+		}
+
+		buf := make([]byte, 0, dcaFrameHeaderLen+len(packet.Data))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(packet.Data)))
+		buf = binary.LittleEndian.AppendUint32(buf, packet.SequenceNumber)
+		buf = binary.LittleEndian.AppendUint64(buf, packet.Timestamp)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(packet.NumSamples))
+		buf = append(buf, packet.Data...)
+
+		_, err = w.w.Write(buf)
+		if err != nil {
+			return n, err
+		}
+
+		w.crc.Write(buf)
+		w.nFrames++
+		n++
+	}
+
+	return n, nil
+}
+
+// sets the tags that are written to the trailer when the writer is closed
+func (w *DCAWriter) SetTags(tags map[string]string) {
+	w.tags = tags
+}
+
+// writes the trailer. does not close the underlying writer
+func (w *DCAWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	tagsBytes, err := json.Marshal(w.tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dca tags: %w", err)
+	}
+
+	if len(tagsBytes) > dcaMaxMetadataLen {
+		return fmt.Errorf("dca: tags of %d bytes are too large", len(tagsBytes))
+	}
+
+	trailer := make([]byte, 0, 14+len(tagsBytes))
+	trailer = binary.LittleEndian.AppendUint16(trailer, dcaTrailerMarker)
+	trailer = binary.LittleEndian.AppendUint32(trailer, w.nFrames)
+	trailer = binary.LittleEndian.AppendUint32(trailer, uint32(len(tagsBytes)))
+	trailer = append(trailer, tagsBytes...)
+
+	w.crc.Write(trailer[6:])
+	trailer = binary.LittleEndian.AppendUint32(trailer, w.crc.Sum32())
+
+	_, err = w.w.Write(trailer)
+	return err
+}
+
+func NewDCAWriter(w io.Writer, metadata DCAMetadata) (*DCAWriter, error) {
+	metadataBytes, err := json.Marshal(dcaFileMetadata{DCAMetadata: metadata, Version: dcaVersion})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dca metadata: %w", err)
+	}
+
+	header := make([]byte, 0, len(dcaMagic)+4+len(metadataBytes))
+	header = append(header, dcaMagic...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(metadataBytes)))
+	header = append(header, metadataBytes...)
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write dca header: %w", err)
+	}
+
+	return &DCAWriter{w: w, crc: crc32.NewIEEE()}, nil
+}
+
+type DCAReader struct {
+	r        *bufio.Reader
+	metadata DCAMetadata
+	version  int
+	crc      hash.Hash32
+	nFrames  uint32
+	tags     map[string]string
+	err      error
+
+	endTimestamp uint64
+}
+
+func (r *DCAReader) Metadata() DCAMetadata {
+	return r.metadata
+}
+
+func (r *DCAReader) ReadPacket() (Packet, error) {
+	if r.err != nil {
+		return Packet{}, r.err
+	}
+
+	packet, err := r.readPacket()
+	if err != nil {
+		r.err = err
+	}
+
+	return packet, err
+}
+
+func (r *DCAReader) readPacket() (Packet, error) {
+	var header [dcaFrameHeaderLen]byte
+	_, err := io.ReadFull(r.r, header[:2])
+	if err != nil {
+		return Packet{}, ErrDCATruncated
+	}
+
+	frameLen := binary.LittleEndian.Uint16(header[:2])
+
+	if frameLen == dcaTrailerMarker {
+		return Packet{}, r.readTrailer()
+	}
+
+	if frameLen > math.MaxInt16 {
+		return Packet{}, fmt.Errorf("dca: invalid frame length %d", frameLen)
+	}
+
+	_, err = io.ReadFull(r.r, header[2:])
+	if err != nil {
+		return Packet{}, ErrDCATruncated
+	}
+
+	frame := make([]byte, frameLen)
+	_, err = io.ReadFull(r.r, frame)
+	if err != nil {
+		return Packet{}, ErrDCATruncated
+	}
+
+	r.crc.Write(header[:])
+	r.crc.Write(frame)
+	r.nFrames++
+
+	nSamples := int(binary.LittleEndian.Uint32(header[14:18]))
+
+	packet := Packet{
+		Data:           frame,
+		SequenceNumber: binary.LittleEndian.Uint32(header[2:6]),
+		Timestamp:      binary.LittleEndian.Uint64(header[6:14]),
+		NumSamples:     nSamples,
+		Duration:       frameDuration(nSamples, r.metadata.SampleRateHz),
+	}
+
+	r.endTimestamp = packet.EndTimestamp()
+
+	return packet, nil
+}
+
+// checks the trailer and reads the tags from it. returns [io.EOF] if the file is intact
+func (r *DCAReader) readTrailer() error {
+	var header [4]byte
+	_, err := io.ReadFull(r.r, header[:])
+	if err != nil {
+		return ErrDCATruncated
+	}
+
+	nFrames := binary.LittleEndian.Uint32(header[:])
+
+	var tagsBytes []byte
+
+	if r.version >= 3 {
+		var tagsLen [4]byte
+		_, err = io.ReadFull(r.r, tagsLen[:])
+		if err != nil {
+			return ErrDCATruncated
+		}
+
+		n := binary.LittleEndian.Uint32(tagsLen[:])
+		if n > dcaMaxMetadataLen {
+			return fmt.Errorf("dca: tags of %d bytes are too large", n)
+		}
+
+		tagsBytes = make([]byte, n)
+		_, err = io.ReadFull(r.r, tagsBytes)
+		if err != nil {
+			return ErrDCATruncated
+		}
+
+		r.crc.Write(tagsLen[:])
+		r.crc.Write(tagsBytes)
+	}
+
+	var checksum [4]byte
+	_, err = io.ReadFull(r.r, checksum[:])
+	if err != nil {
+		return ErrDCATruncated
+	}
+
+	if nFrames != r.nFrames || binary.LittleEndian.Uint32(checksum[:]) != r.crc.Sum32() {
+		return ErrDCAChecksumMismatch
+	}
+
+	if len(tagsBytes) > 0 {
+		err = json.Unmarshal(tagsBytes, &r.tags)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal dca tags: %w", err)
+		}
+	}
+
+	return io.EOF
+}
+
+// returns the tags from the trailer, which are only known once every packet has been read, such as
+// after [DCAReader.Verify]. returns nil if the file has no tags
+func (r *DCAReader) Tags() map[string]string {
+	return r.tags
+}
+
+// the timestamp that follows the last packet that was read. after [DCAReader.Verify], this is the
+// length of the stream in samples per channel
+func (r *DCAReader) EndTimestamp() uint64 {
+	return r.endTimestamp
+}
+
+// reads every remaining frame and checks the trailer of the file
+func (r *DCAReader) Verify() error {
+	for {
+		_, err := r.ReadPacket()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+func NewDCAReader(r io.Reader) (*DCAReader, error) {
+	br := bufio.NewReader(r)
+
+	var header [len(dcaMagic) + 4]byte
+	_, err := io.ReadFull(br, header[:])
+	if err != nil {
+		return nil, ErrDCATruncated
+	}
+
+	if string(header[:len(dcaMagic)]) != dcaMagic {
+		return nil, ErrDCAInvalidMagic
+	}
+
+	metadataLen := binary.LittleEndian.Uint32(header[len(dcaMagic):])
+	if metadataLen > dcaMaxMetadataLen {
+		return nil, fmt.Errorf("dca: metadata of %d bytes is too large", metadataLen)
+	}
+
+	metadataBytes := make([]byte, metadataLen)
+	_, err = io.ReadFull(br, metadataBytes)
+	if err != nil {
+		return nil, ErrDCATruncated
+	}
+
+	var metadata dcaFileMetadata
+	err = json.Unmarshal(metadataBytes, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dca metadata: %w", err)
+	}
+
+	if metadata.Version < dcaMinVersion || metadata.Version > dcaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrDCAUnsupportedVersion, metadata.Version)
+	}
+
+	return &DCAReader{r: br, metadata: metadata.DCAMetadata, version: metadata.Version, crc: crc32.NewIEEE()}, nil
+}
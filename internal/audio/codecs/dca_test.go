@@ -0,0 +1,191 @@
+package codecs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs/testdata"
+)
+
+// assigns timing to the frames as if they were produced by an encoder with the frame size in the metadata
+func packetsFromFrames(metadata codecs.DCAMetadata, frames [][]byte) []codecs.Packet {
+	packets := make([]codecs.Packet, len(frames))
+	for i, frame := range frames {
+		packets[i] = codecs.Packet{
+			Data:           frame,
+			SequenceNumber: uint32(i),
+			Timestamp:      uint64(i * metadata.FrameSize),
+			NumSamples:     metadata.FrameSize,
+		}
+	}
+
+	return packets
+}
+
+func writeDCA(t *testing.T, metadata codecs.DCAMetadata, frames [][]byte) []byte {
+	var buf bytes.Buffer
+
+	w, err := codecs.NewDCAWriter(&buf, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write(packetsFromFrames(metadata, frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDCARoundTrip(t *testing.T) {
+	metadata := codecs.DCAMetadata{Codec: codecs.CodecName_Opus, SampleRateHz: 48000, NumChannels: 2, FrameSize: 960}
+	data := writeDCA(t, metadata, testdata.PCMS16LESampleEncodedAsOpus)
+
+	r, err := codecs.NewDCAReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Metadata() != metadata {
+		t.Fatalf("incorrect metadata. want %+v, got %+v", metadata, r.Metadata())
+	}
+
+	for idx, want := range testdata.PCMS16LESampleEncodedAsOpus {
+		packet, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("failed to read frame (idx = %d): %s", idx, err.Error())
+		}
+
+		if !slices.Equal(packet.Data, want) {
+			t.Fatalf("incorrect frame (idx = %d). want %v, got %v", idx, want, packet.Data)
+		}
+
+		if packet.SequenceNumber != uint32(idx) || packet.Timestamp != uint64(idx*metadata.FrameSize) || packet.NumSamples != metadata.FrameSize {
+			t.Fatalf("incorrect timing (idx = %d). got %+v", idx, packet)
+		}
+
+		if packet.Duration != 20*time.Millisecond {
+			t.Fatalf("incorrect duration (idx = %d). want %s, got %s", idx, 20*time.Millisecond, packet.Duration)
+		}
+	}
+
+	_, err = r.ReadPacket()
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF after the last frame, got %v", err)
+	}
+}
+
+func TestDCATags(t *testing.T) {
+	metadata := codecs.DCAMetadata{Codec: codecs.CodecName_Opus, SampleRateHz: 48000, NumChannels: 2, FrameSize: 960}
+
+	var buf bytes.Buffer
+
+	w, err := codecs.NewDCAWriter(&buf, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write(packetsFromFrames(metadata, testdata.PCMS16LESampleEncodedAsOpus[:10]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tags can be set after the frames have been written
+	w.SetTags(map[string]string{"title": "clip"})
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := codecs.NewDCAReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Tags()["title"] != "clip" {
+		t.Errorf("expected the tags to be read from the trailer, got %v", r.Tags())
+	}
+
+	// the tags are covered by the checksum
+	corrupted := bytes.Replace(buf.Bytes(), []byte("clip"), []byte("slip"), 1)
+
+	r, err = codecs.NewDCAReader(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Verify()
+	if !errors.Is(err, codecs.ErrDCAChecksumMismatch) {
+		t.Errorf("expected corrupted tags to fail with ErrDCAChecksumMismatch, got %v", err)
+	}
+}
+
+func TestDCADetectsCorruption(t *testing.T) {
+	metadata := codecs.DCAMetadata{Codec: codecs.CodecName_Opus, SampleRateHz: 48000, NumChannels: 2, FrameSize: 960}
+	data := writeDCA(t, metadata, testdata.PCMS16LESampleEncodedAsOpus[:10])
+
+	corrupted := slices.Clone(data)
+	corrupted[len(corrupted)/2] ^= 0xFF
+
+	r, err := codecs.NewDCAReader(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Verify()
+	if err == nil {
+		t.Fatal("expected corrupted file to fail verification")
+	}
+
+	r, err = codecs.NewDCAReader(bytes.NewReader(data[:len(data)-4]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Verify()
+	if !errors.Is(err, codecs.ErrDCATruncated) {
+		t.Fatalf("expected truncated file to fail with ErrDCATruncated, got %v", err)
+	}
+}
+
+func TestOpusDecoderReader(t *testing.T) {
+	metadata := codecs.DCAMetadata{Codec: codecs.CodecName_Opus, SampleRateHz: 48000, NumChannels: 2, FrameSize: 960}
+	data := writeDCA(t, metadata, testdata.PCMS16LESampleEncodedAsOpus)
+
+	dca, err := codecs.NewDCAReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := codecs.NewOpusDecoderReader(dca, metadata.NumChannels, metadata.SampleRateHz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := len(testdata.PCMS16LESampleEncodedAsOpus) * metadata.FrameSize * metadata.NumChannels * 2
+	if len(pcm) != want {
+		t.Fatalf("incorrect number of decoded bytes. want %d, got %d", want, len(pcm))
+	}
+}
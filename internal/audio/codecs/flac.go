@@ -0,0 +1,1305 @@
+package codecs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"slices"
+)
+
+const CodecName_FLAC = "flac"
+
+const (
+	flacMagic = "fLaC"
+
+	flacBlockSize         = 4096
+	flacMinBlockSize      = 16 // the smallest block that any frame but the last may hold
+	flacBitsPerSample     = 16
+	flacMaxChannels       = 8
+	flacMaxSampleRateHz   = 1<<20 - 1
+	flacMaxFixedOrder     = 4
+	flacMaxLPCOrder       = 8
+	flacQLPCoeffPrecision = 12
+	flacMaxQLPShift       = 15
+	flacMaxPartitionOrder = 8
+
+	flacMetadataBlockType_StreamInfo = 0
+	flacStreamInfoSize               = 34
+
+	flacChannelAssignment_LeftSide  = 0b1000
+	flacChannelAssignment_RightSide = 0b1001
+	flacChannelAssignment_MidSide   = 0b1010
+
+	flacSubframeType_Constant = 0b000000
+	flacSubframeType_Verbatim = 0b000001
+	flacSubframeType_Fixed    = 0b001000
+	flacSubframeType_LPC      = 0b100000
+
+	flacResidualCodingMethod_Rice  = 0
+	flacResidualCodingMethod_Rice2 = 1
+)
+
+var (
+	ErrFLACInvalidMagic      = errors.New("flac: invalid magic number")
+	ErrFLACInvalidFrame      = errors.New("flac: invalid frame")
+	ErrFLACUnsupported       = errors.New("flac: unsupported stream")
+	ErrFLACChecksumMismatch  = errors.New("flac: checksum mismatch")
+	ErrFLACMissingStreamInfo = errors.New("flac: missing STREAMINFO metadata block")
+)
+
+// sample rates that can be stored directly in a frame header
+var flacSampleRateCodes = map[int]uint64{
+	88200:  0b0001,
+	176400: 0b0010,
+	192000: 0b0011,
+	8000:   0b0100,
+	16000:  0b0101,
+	22050:  0b0110,
+	24000:  0b0111,
+	32000:  0b1000,
+	44100:  0b1001,
+	48000:  0b1010,
+	96000:  0b1011,
+}
+
+func init() {
+	Register(Codec{
+		Name:        CodecName_FLAC,
+		NumChannels: []int{1, 2, 3, 4, 5, 6, 7, 8},
+		NewEncoderWriter: func(w PacketWriter, opts EncoderOptions) (EncoderWriter, error) {
+			return NewFLACEncoderWriter(newPacketStreamWriter(w), opts.NumChannels, opts.SampleRateHz)
+		},
+		NewDecoderReader: func(r PacketReader, opts DecoderOptions) (io.Reader, error) {
+			dec, err := NewFLACDecoderReader(newPacketStreamReader(r))
+			if err != nil {
+				return nil, err
+			}
+
+			if dec.NumChannels() != opts.NumChannels || dec.SampleRateHz() != opts.SampleRateHz {
+				return nil, fmt.Errorf("%w: stream has %dHz with %d channels", ErrUnsupportedFormat, dec.SampleRateHz(), dec.NumChannels())
+			}
+
+			return dec, nil
+		},
+		NewFileEncoderWriter: func(w io.Writer, opts EncoderOptions) (EncoderWriter, error) {
+			return NewFLACEncoderWriter(w, opts.NumChannels, opts.SampleRateHz)
+		},
+	})
+}
+
+type FLACStreamInfo struct {
+	MinBlockSize  int
+	MaxBlockSize  int
+	MinFrameSize  int // 0 if unknown
+	MaxFrameSize  int // 0 if unknown
+	SampleRateHz  int
+	NumChannels   int
+	BitsPerSample int
+	NumSamples    uint64   // per channel. 0 if unknown
+	MD5           [16]byte // md5 of the unencoded audio. all zeros if unknown
+}
+
+func (info FLACStreamInfo) appendTo(p []byte) []byte {
+	var w bitWriter
+	w.writeBits(uint64(info.MinBlockSize), 16)
+	w.writeBits(uint64(info.MaxBlockSize), 16)
+	w.writeBits(uint64(info.MinFrameSize), 24)
+	w.writeBits(uint64(info.MaxFrameSize), 24)
+	w.writeBits(uint64(info.SampleRateHz), 20)
+	w.writeBits(uint64(info.NumChannels-1), 3)
+	w.writeBits(uint64(info.BitsPerSample-1), 5)
+	w.writeBits(info.NumSamples, 36)
+
+	return append(append(p, w.buf...), info.MD5[:]...)
+}
+
+func parseFLACStreamInfo(p []byte) (info FLACStreamInfo, err error) {
+	r := bitReader{r: bytes.NewReader(p)}
+
+	fields := []*int{&info.MinBlockSize, &info.MaxBlockSize, &info.MinFrameSize, &info.MaxFrameSize, &info.SampleRateHz, &info.NumChannels, &info.BitsPerSample}
+	sizes := []uint{16, 16, 24, 24, 20, 3, 5}
+
+	for i, field := range fields {
+		v, err := r.readBits(sizes[i])
+		if err != nil {
+			return info, err
+		}
+
+		*field = int(v)
+	}
+
+	info.NumChannels++
+	info.BitsPerSample++
+
+	info.NumSamples, err = r.readBits(36)
+	if err != nil {
+		return info, err
+	}
+
+	copy(info.MD5[:], p[18:])
+
+	return info, nil
+}
+
+// encodes 16-bit signed little endian PCM into a FLAC stream
+type flacEncoderWriter struct {
+	w io.Writer
+
+	// position of the STREAMINFO metadata block if w is an io.WriteSeeker
+	streamInfoOffset int64
+	seeker           io.WriteSeeker
+
+	info          FLACStreamInfo
+	lastBlockSize int
+	md5           hash.Hash
+
+	pcm    []byte
+	closed bool
+}
+
+func (e *flacEncoderWriter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	e.pcm = append(e.pcm, p...)
+
+	blockSizeBytes := flacBlockSize * e.info.NumChannels * 2
+	for len(e.pcm) >= blockSizeBytes {
+		err := e.encodeFrame(e.pcm[:blockSizeBytes])
+		e.pcm = e.pcm[blockSizeBytes:]
+
+		if err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// encodes the buffered samples into a shorter frame. the stream uses a variable block size, so the
+// writer can continue to be used after flushing. a frame is only written once at least 16 samples
+// are buffered, since that is the smallest block that is allowed before the last frame. fewer
+// samples are carried over to the next frame
+func (e *flacEncoderWriter) Flush() error {
+	if e.closed {
+		return io.ErrClosedPipe
+	}
+
+	return e.flush(flacMinBlockSize)
+}
+
+// encodes the buffered samples into a frame if there are at least minSamples of them
+func (e *flacEncoderWriter) flush(minSamples int) error {
+	sampleSizeBytes := e.info.NumChannels * 2
+	n := len(e.pcm) / sampleSizeBytes * sampleSizeBytes
+
+	if n == 0 || n/sampleSizeBytes < minSamples {
+		return nil
+	}
+
+	err := e.encodeFrame(e.pcm[:n])
+	e.pcm = e.pcm[n:]
+
+	return err
+}
+
+// encodes all buffered samples into a final frame, which may hold fewer than 16 samples. if the
+// underlying writer is an [io.WriteSeeker], the STREAMINFO metadata block is rewritten with the
+// total number of samples, block sizes, frame sizes and the md5 of the audio. does not close the
+// underlying writer
+func (e *flacEncoderWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	err := e.flush(1)
+	e.closed = true
+
+	if err != nil {
+		return err
+	}
+
+	if e.seeker == nil {
+		return nil
+	}
+
+	info := e.info
+	copy(info.MD5[:], e.md5.Sum(nil))
+
+	if info.MinBlockSize == 0 {
+		// there was at most one frame, which is excluded from the minimum. the minimum can never be
+		// smaller than 16 samples, even if the only frame is
+		info.MinBlockSize = max(e.lastBlockSize, flacMinBlockSize)
+		info.MaxBlockSize = max(info.MaxBlockSize, info.MinBlockSize)
+	}
+
+	end, err := e.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to get end of flac stream: %w", err)
+	}
+
+	_, err = e.seeker.Seek(e.streamInfoOffset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to seek to flac STREAMINFO: %w", err)
+	}
+
+	_, err = e.seeker.Write(info.appendTo(nil))
+	if err != nil {
+		return fmt.Errorf("failed to rewrite flac STREAMINFO: %w", err)
+	}
+
+	_, err = e.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+func (e *flacEncoderWriter) encodeFrame(pcm []byte) error {
+	nChannels := e.info.NumChannels
+	interleaved := BytesToS16LE(pcm)
+	blockSize := len(interleaved) / nChannels
+
+	channels := make([][]int32, nChannels)
+	for c := range channels {
+		channels[c] = make([]int32, blockSize)
+		for i := range blockSize {
+			channels[c][i] = int32(interleaved[i*nChannels+c])
+		}
+	}
+
+	assignment := uint64(nChannels - 1)
+	subframes := make([]flacSubframe, nChannels)
+	for c := range channels {
+		subframes[c] = planFLACSubframe(channels[c], flacBitsPerSample)
+	}
+
+	if nChannels == 2 {
+		assignment, subframes = planFLACStereo(channels[0], channels[1], subframes[0], subframes[1])
+	}
+
+	var w bitWriter
+
+	// header
+	w.writeBits(0b11111111111110, 14) // sync code
+	w.writeBits(0, 1)                 // reserved
+	w.writeBits(1, 1)                 // variable block size
+	w.writeBits(0b0111, 4)            // block size is stored as a 16 bit value at the end of the header
+
+	sampleRateCode, ok := flacSampleRateCodes[e.info.SampleRateHz]
+	if !ok {
+		sampleRateCode = 0b0000 // get the sample rate from STREAMINFO
+	}
+
+	w.writeBits(sampleRateCode, 4)
+	w.writeBits(assignment, 4)
+	w.writeBits(0b100, 3) // 16 bits per sample
+	w.writeBits(0, 1)     // reserved
+	w.buf = appendFLACUTF8(w.buf, e.info.NumSamples)
+	w.writeBits(uint64(blockSize-1), 16)
+	w.writeBits(uint64(crc8(0, w.buf)), 8)
+
+	for _, subframe := range subframes {
+		subframe.write(&w)
+	}
+
+	w.align()
+	w.writeBits(uint64(crc16(0, w.buf)), 16)
+
+	_, err := e.w.Write(w.buf)
+	if err != nil {
+		return err
+	}
+
+	e.md5.Write(pcm)
+	e.info.NumSamples += uint64(blockSize)
+
+	// the last block is allowed to be smaller than the minimum block size, so only count the
+	// previous block now that it is known not to be the last
+	if e.lastBlockSize != 0 && (e.info.MinBlockSize == 0 || e.lastBlockSize < e.info.MinBlockSize) {
+		e.info.MinBlockSize = e.lastBlockSize
+	}
+
+	e.lastBlockSize = blockSize
+	e.info.MaxBlockSize = max(e.info.MaxBlockSize, blockSize)
+
+	if e.info.MinFrameSize == 0 || len(w.buf) < e.info.MinFrameSize {
+		e.info.MinFrameSize = len(w.buf)
+	}
+
+	e.info.MaxFrameSize = max(e.info.MaxFrameSize, len(w.buf))
+
+	return nil
+}
+
+// encodes 16-bit signed little endian PCM into a FLAC stream that is written to w. the stream can
+// be written to a file by using the writer as the output of an audio graph WriterNode.
+//
+// the total number of samples and the md5 of the audio are only stored in the stream if w is an
+// [io.WriteSeeker], otherwise they are marked as unknown
+func NewFLACEncoderWriter(w io.Writer, nChannels, sampleRateHz int) (EncoderWriter, error) {
+	if nChannels < 1 || nChannels > flacMaxChannels {
+		return nil, fmt.Errorf("%w: flac supports 1 to %d channels, got %d", ErrUnsupportedFormat, flacMaxChannels, nChannels)
+	}
+
+	if sampleRateHz <= 0 || sampleRateHz > flacMaxSampleRateHz {
+		return nil, fmt.Errorf("%w: invalid flac sample rate %dHz", ErrUnsupportedFormat, sampleRateHz)
+	}
+
+	e := &flacEncoderWriter{
+		w: w,
+		info: FLACStreamInfo{
+			SampleRateHz:  sampleRateHz,
+			NumChannels:   nChannels,
+			BitsPerSample: flacBitsPerSample,
+		},
+		md5: md5.New(),
+		pcm: make([]byte, 0, flacBlockSize*nChannels*2),
+	}
+
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			e.seeker = seeker
+			e.streamInfoOffset = start + int64(len(flacMagic)) + 4
+		}
+	}
+
+	header := make([]byte, 0, len(flacMagic)+4+flacStreamInfoSize)
+	header = append(header, flacMagic...)
+	header = append(header, 1<<7|flacMetadataBlockType_StreamInfo) // last metadata block
+	header = append(header, 0, 0, flacStreamInfoSize)
+
+	// the block sizes are not known until the stream is finished, so the header gives the range of
+	// block sizes that the encoder can use
+	info := e.info
+	info.MinBlockSize, info.MaxBlockSize = flacMinBlockSize, flacBlockSize
+	header = info.appendTo(header)
+
+	_, err := w.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write flac header: %w", err)
+	}
+
+	return e, nil
+}
+
+// encodes a number using the extended utf-8 coding that flac uses for frame and sample numbers
+func appendFLACUTF8(p []byte, v uint64) []byte {
+	if v < 0x80 {
+		return append(p, byte(v))
+	}
+
+	nBytes := 2
+	for nBytes < 7 && v >= 1<<(5*nBytes+1) {
+		nBytes++
+	}
+
+	// the first byte starts with one 1 bit for every byte in the sequence
+	first := byte(0xFF<<(8-nBytes)) | byte(v>>(6*(nBytes-1)))
+	if nBytes == 7 {
+		first = 0xFE
+	}
+
+	p = append(p, first)
+	for i := nBytes - 2; i >= 0; i-- {
+		p = append(p, 0x80|byte(v>>(6*i))&0x3F)
+	}
+
+	return p
+}
+
+type flacSubframe struct {
+	subframeType int
+	bps          uint
+	samples      []int32
+
+	// fixed and lpc
+	order          int
+	residual       []int64
+	partitionOrder int
+	riceParams     []int
+	codingMethod   uint64
+
+	// lpc
+	qlpCoeffs []int32
+	qlpShift  int
+
+	bits int
+}
+
+func (s flacSubframe) write(w *bitWriter) {
+	order := s.order
+	if s.subframeType == flacSubframeType_LPC {
+		// lpc subframes store the order minus one
+		order--
+	}
+
+	w.writeBits(0, 1) // zero padding
+	w.writeBits(uint64(s.subframeType|order), 6)
+	w.writeBits(0, 1) // no wasted bits
+
+	switch s.subframeType {
+	case flacSubframeType_Constant:
+		w.writeSigned(int64(s.samples[0]), s.bps)
+	case flacSubframeType_Verbatim:
+		for _, sample := range s.samples {
+			w.writeSigned(int64(sample), s.bps)
+		}
+	case flacSubframeType_Fixed, flacSubframeType_LPC:
+		for _, sample := range s.samples[:s.order] {
+			w.writeSigned(int64(sample), s.bps)
+		}
+
+		if s.subframeType == flacSubframeType_LPC {
+			w.writeBits(flacQLPCoeffPrecision-1, 4)
+			w.writeSigned(int64(s.qlpShift), 5)
+
+			for _, coeff := range s.qlpCoeffs {
+				w.writeSigned(int64(coeff), flacQLPCoeffPrecision)
+			}
+		}
+
+		s.writeResidual(w)
+	}
+}
+
+func (s flacSubframe) writeResidual(w *bitWriter) {
+	paramBits := uint(4)
+	if s.codingMethod == flacResidualCodingMethod_Rice2 {
+		paramBits = 5
+	}
+
+	w.writeBits(s.codingMethod, 2)
+	w.writeBits(uint64(s.partitionOrder), 4)
+
+	nPartitionSamples := len(s.samples) >> s.partitionOrder
+	residual := s.residual
+
+	for p, k := range s.riceParams {
+		n := nPartitionSamples
+		if p == 0 {
+			n -= s.order
+		}
+
+		w.writeBits(uint64(k), paramBits)
+
+		for _, r := range residual[:n] {
+			u := zigzag(r)
+			w.writeUnary(u >> k)
+			w.writeBits(u, uint(k))
+		}
+
+		residual = residual[n:]
+	}
+}
+
+// picks the way of encoding the samples that uses the fewest bits
+func planFLACSubframe(samples []int32, bps uint) flacSubframe {
+	best := flacSubframe{
+		subframeType: flacSubframeType_Verbatim,
+		bps:          bps,
+		samples:      samples,
+		bits:         8 + int(bps)*len(samples),
+	}
+
+	if !slices.ContainsFunc(samples, func(s int32) bool { return s != samples[0] }) {
+		return flacSubframe{subframeType: flacSubframeType_Constant, bps: bps, samples: samples, bits: 8 + int(bps)}
+	}
+
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		subframe := flacSubframe{subframeType: flacSubframeType_Fixed, bps: bps, samples: samples, order: order}
+		subframe.residual = fixedResidual(samples, order)
+
+		if subframe.planResidual(8 + order*int(bps)) {
+			if subframe.bits < best.bits {
+				best = subframe
+			}
+		}
+	}
+
+	for _, lpc := range computeLPCCoefficients(samples, flacMaxLPCOrder) {
+		order := len(lpc)
+		if order >= len(samples) {
+			continue
+		}
+
+		qlpCoeffs, shift, ok := quantizeLPCCoefficients(lpc, flacQLPCoeffPrecision)
+		if !ok {
+			continue
+		}
+
+		subframe := flacSubframe{subframeType: flacSubframeType_LPC, bps: bps, samples: samples, order: order, qlpCoeffs: qlpCoeffs, qlpShift: shift}
+		subframe.residual = lpcResidual(samples, qlpCoeffs, shift)
+
+		header := 8 + order*int(bps) + 4 + 5 + order*flacQLPCoeffPrecision
+
+		if subframe.planResidual(header) && subframe.bits < best.bits {
+			best = subframe
+		}
+	}
+
+	return best
+}
+
+// chooses the partition order and rice parameters for the residual. returns false if the
+// residual cannot be rice coded
+func (s *flacSubframe) planResidual(headerBits int) bool {
+	blockSize := len(s.samples)
+
+	bestBits := math.MaxInt
+	var bestParams []int
+	bestOrder := 0
+
+	for partitionOrder := 0; partitionOrder <= flacMaxPartitionOrder; partitionOrder++ {
+		nPartitions := 1 << partitionOrder
+		if blockSize%nPartitions != 0 || blockSize/nPartitions <= s.order {
+			break
+		}
+
+		nPartitionSamples := blockSize / nPartitions
+		params := make([]int, nPartitions)
+		bits := 0
+		residual := s.residual
+
+		for p := range nPartitions {
+			n := nPartitionSamples
+			if p == 0 {
+				n -= s.order
+			}
+
+			var sum uint64
+			for _, r := range residual[:n] {
+				sum += zigzag(r)
+			}
+
+			k := riceParameter(sum, n)
+			params[p] = k
+			bits += n*(k+1) + int(sum>>k)
+			residual = residual[n:]
+		}
+
+		if bits < bestBits {
+			bestBits, bestParams, bestOrder = bits, params, partitionOrder
+		}
+	}
+
+	if bestParams == nil {
+		return false
+	}
+
+	s.partitionOrder = bestOrder
+	s.riceParams = bestParams
+	s.codingMethod = flacResidualCodingMethod_Rice
+
+	paramBits := 4
+	if slices.Max(bestParams) >= 0b1111 {
+		s.codingMethod = flacResidualCodingMethod_Rice2
+		paramBits = 5
+	}
+
+	// compute the exact size now that the parameters are known
+	bits := 2 + 4 + len(bestParams)*paramBits
+	residual := s.residual
+	nPartitionSamples := blockSize >> bestOrder
+
+	for p, k := range bestParams {
+		n := nPartitionSamples
+		if p == 0 {
+			n -= s.order
+		}
+
+		for _, r := range residual[:n] {
+			bits += int(zigzag(r)>>k) + 1 + k
+		}
+
+		residual = residual[n:]
+	}
+
+	s.bits = headerBits + bits
+	return true
+}
+
+// picks the stereo decorrelation that uses the fewest bits
+func planFLACStereo(left, right []int32, leftSubframe, rightSubframe flacSubframe) (uint64, []flacSubframe) {
+	mid := make([]int32, len(left))
+	side := make([]int32, len(left))
+
+	for i := range left {
+		mid[i] = (left[i] + right[i]) >> 1
+		side[i] = left[i] - right[i]
+	}
+
+	midSubframe := planFLACSubframe(mid, flacBitsPerSample)
+	sideSubframe := planFLACSubframe(side, flacBitsPerSample+1)
+
+	assignment := uint64(1) // independent
+	subframes := []flacSubframe{leftSubframe, rightSubframe}
+	bits := leftSubframe.bits + rightSubframe.bits
+
+	if b := leftSubframe.bits + sideSubframe.bits; b < bits {
+		assignment, subframes, bits = flacChannelAssignment_LeftSide, []flacSubframe{leftSubframe, sideSubframe}, b
+	}
+
+	if b := sideSubframe.bits + rightSubframe.bits; b < bits {
+		assignment, subframes, bits = flacChannelAssignment_RightSide, []flacSubframe{sideSubframe, rightSubframe}, b
+	}
+
+	if b := midSubframe.bits + sideSubframe.bits; b < bits {
+		assignment, subframes = flacChannelAssignment_MidSide, []flacSubframe{midSubframe, sideSubframe}
+	}
+
+	return assignment, subframes
+}
+
+func fixedResidual(samples []int32, order int) []int64 {
+	residual := make([]int64, 0, len(samples)-order)
+
+	for i := order; i < len(samples); i++ {
+		x := func(j int) int64 { return int64(samples[i-j]) }
+
+		switch order {
+		case 0:
+			residual = append(residual, x(0))
+		case 1:
+			residual = append(residual, x(0)-x(1))
+		case 2:
+			residual = append(residual, x(0)-2*x(1)+x(2))
+		case 3:
+			residual = append(residual, x(0)-3*x(1)+3*x(2)-x(3))
+		case 4:
+			residual = append(residual, x(0)-4*x(1)+6*x(2)-4*x(3)+x(4))
+		}
+	}
+
+	return residual
+}
+
+func lpcResidual(samples []int32, qlpCoeffs []int32, shift int) []int64 {
+	order := len(qlpCoeffs)
+	residual := make([]int64, 0, len(samples)-order)
+
+	for i := order; i < len(samples); i++ {
+		var prediction int64
+		for j, coeff := range qlpCoeffs {
+			prediction += int64(coeff) * int64(samples[i-j-1])
+		}
+
+		residual = append(residual, int64(samples[i])-prediction>>shift)
+	}
+
+	return residual
+}
+
+// computes the linear prediction coefficients for every order from 1 to maxOrder using a welch
+// window and the levinson-durbin recursion. result[i] holds the coefficients for order i+1
+func computeLPCCoefficients(samples []int32, maxOrder int) [][]float64 {
+	n := len(samples)
+	if n <= maxOrder {
+		return nil
+	}
+
+	windowed := make([]float64, n)
+	half := float64(n-1) / 2
+	for i, sample := range samples {
+		x := (float64(i) - half) / (half + 1)
+		windowed[i] = float64(sample) * (1 - x*x)
+	}
+
+	autoc := make([]float64, maxOrder+1)
+	for lag := range autoc {
+		for i := lag; i < n; i++ {
+			autoc[lag] += windowed[i] * windowed[i-lag]
+		}
+	}
+
+	if autoc[0] == 0 {
+		return nil
+	}
+
+	result := make([][]float64, 0, maxOrder)
+	lpc := make([]float64, maxOrder)
+	err := autoc[0]
+
+	for i := range maxOrder {
+		r := -autoc[i+1]
+		for j := range i {
+			r -= lpc[j] * autoc[i-j]
+		}
+		r /= err
+
+		lpc[i] = r
+
+		j := 0
+		for ; j < i>>1; j++ {
+			tmp := lpc[j]
+			lpc[j] += r * lpc[i-1-j]
+			lpc[i-1-j] += r * tmp
+		}
+
+		if i&1 != 0 {
+			lpc[j] += lpc[j] * r
+		}
+
+		err *= 1 - r*r
+
+		coeffs := make([]float64, i+1)
+		for j := range coeffs {
+			coeffs[j] = -lpc[j]
+		}
+		result = append(result, coeffs)
+
+		if err <= 0 {
+			break
+		}
+	}
+
+	return result
+}
+
+// quantizes lpc coefficients to integers with the given precision. returns false if the
+// coefficients cannot be represented
+func quantizeLPCCoefficients(lpc []float64, precision uint) (qlp []int32, shift int, ok bool) {
+	qmax := int32(1)<<(precision-1) - 1
+	qmin := -qmax - 1
+
+	var cmax float64
+	for _, c := range lpc {
+		cmax = max(cmax, math.Abs(c))
+	}
+
+	if cmax <= 0 || math.IsNaN(cmax) || math.IsInf(cmax, 0) {
+		return nil, 0, false
+	}
+
+	_, log2cmax := math.Frexp(cmax)
+	shift = int(precision) - 1 - log2cmax
+
+	switch {
+	case shift > flacMaxQLPShift:
+		shift = flacMaxQLPShift
+	case shift < 0:
+		// negative shifts are not allowed by flac
+		return nil, 0, false
+	}
+
+	qlp = make([]int32, len(lpc))
+
+	var errAcc float64
+	for i, c := range lpc {
+		errAcc += c * float64(int64(1)<<shift)
+		q := int32(math.Round(errAcc))
+		q = min(max(q, qmin), qmax)
+		qlp[i] = q
+		errAcc -= float64(q)
+	}
+
+	return qlp, shift, true
+}
+
+// picks a rice parameter for a partition from the sum of its zigzag encoded residuals
+func riceParameter(sum uint64, n int) int {
+	k := 0
+	for k < 30 && uint64(n)<<(k+1) <= sum {
+		k++
+	}
+
+	return k
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// decodes a FLAC stream with 16 bits per sample into 16-bit signed little endian PCM
+type FLACDecoderReader struct {
+	r    *bufio.Reader
+	bits bitReader
+	info FLACStreamInfo
+	md5  hash.Hash
+
+	pcm []byte
+	err error
+}
+
+func (d *FLACDecoderReader) NumChannels() int {
+	return d.info.NumChannels
+}
+
+func (d *FLACDecoderReader) SampleRateHz() int {
+	return d.info.SampleRateHz
+}
+
+func (d *FLACDecoderReader) StreamInfo() FLACStreamInfo {
+	return d.info
+}
+
+func (d *FLACDecoderReader) Read(p []byte) (n int, err error) {
+	for len(d.pcm) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		d.err = d.decodeFrame()
+	}
+
+	n = copy(p, d.pcm)
+	d.pcm = d.pcm[n:]
+
+	return n, nil
+}
+
+func (d *FLACDecoderReader) decodeFrame() error {
+	if _, err := d.r.Peek(1); err == io.EOF {
+		var zero [16]byte
+		if d.info.MD5 != zero && !bytes.Equal(d.md5.Sum(nil), d.info.MD5[:]) {
+			return ErrFLACChecksumMismatch
+		}
+
+		return io.EOF
+	}
+
+	r := &d.bits
+	r.resetCRC()
+
+	sync, err := r.readBits(14)
+	if err != nil {
+		return err
+	}
+
+	if sync != 0b11111111111110 {
+		return fmt.Errorf("%w: invalid sync code", ErrFLACInvalidFrame)
+	}
+
+	header, err := r.readBits(18) // reserved, blocking strategy, block size, sample rate, channels, sample size, reserved
+	if err != nil {
+		return err
+	}
+
+	blockSizeCode := header >> 12 & 0b1111
+	sampleRateCode := header >> 8 & 0b1111
+	assignment := header >> 4 & 0b1111
+	sampleSizeCode := header >> 1 & 0b111
+
+	if sampleSizeCode != 0b000 && sampleSizeCode != 0b100 {
+		return fmt.Errorf("%w: only 16 bits per sample is supported", ErrFLACUnsupported)
+	}
+
+	// frame or sample number
+	first, err := r.readBits(8)
+	if err != nil {
+		return err
+	}
+
+	for mask := uint64(0x80); mask > 1 && first&mask != 0 && first&(mask>>1) != 0; mask >>= 1 {
+		_, err := r.readBits(8)
+		if err != nil {
+			return err
+		}
+	}
+
+	var blockSize int
+	switch {
+	case blockSizeCode == 0b0001:
+		blockSize = 192
+	case blockSizeCode >= 0b0010 && blockSizeCode <= 0b0101:
+		blockSize = 576 << (blockSizeCode - 2)
+	case blockSizeCode == 0b0110:
+		v, err := r.readBits(8)
+		if err != nil {
+			return err
+		}
+		blockSize = int(v) + 1
+	case blockSizeCode == 0b0111:
+		v, err := r.readBits(16)
+		if err != nil {
+			return err
+		}
+		blockSize = int(v) + 1
+	case blockSizeCode >= 0b1000:
+		blockSize = 256 << (blockSizeCode - 8)
+	default:
+		return fmt.Errorf("%w: invalid block size", ErrFLACInvalidFrame)
+	}
+
+	switch sampleRateCode {
+	case 0b1100:
+		_, err = r.readBits(8)
+	case 0b1101, 0b1110:
+		_, err = r.readBits(16)
+	case 0b1111:
+		err = fmt.Errorf("%w: invalid sample rate", ErrFLACInvalidFrame)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	headerCRC := r.crc8
+	expectedHeaderCRC, err := r.readBits(8)
+	if err != nil {
+		return err
+	}
+
+	if byte(expectedHeaderCRC) != headerCRC {
+		return fmt.Errorf("%w: frame header", ErrFLACChecksumMismatch)
+	}
+
+	nChannels := int(assignment) + 1
+	if assignment >= flacChannelAssignment_LeftSide {
+		nChannels = 2
+	}
+
+	if assignment > flacChannelAssignment_MidSide || nChannels != d.info.NumChannels {
+		return fmt.Errorf("%w: invalid channel assignment", ErrFLACInvalidFrame)
+	}
+
+	channels := make([][]int64, nChannels)
+	for c := range channels {
+		bps := uint(flacBitsPerSample)
+
+		isSide := (assignment == flacChannelAssignment_LeftSide && c == 1) ||
+			(assignment == flacChannelAssignment_RightSide && c == 0) ||
+			(assignment == flacChannelAssignment_MidSide && c == 1)
+
+		if isSide {
+			bps++
+		}
+
+		channels[c], err = d.decodeSubframe(blockSize, bps)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.align()
+
+	frameCRC := r.crc16
+	expectedFrameCRC, err := r.readBits(16)
+	if err != nil {
+		return err
+	}
+
+	if uint16(expectedFrameCRC) != frameCRC {
+		return fmt.Errorf("%w: frame", ErrFLACChecksumMismatch)
+	}
+
+	switch assignment {
+	case flacChannelAssignment_LeftSide:
+		for i := range blockSize {
+			channels[1][i] = channels[0][i] - channels[1][i]
+		}
+	case flacChannelAssignment_RightSide:
+		for i := range blockSize {
+			channels[0][i] += channels[1][i]
+		}
+	case flacChannelAssignment_MidSide:
+		for i := range blockSize {
+			mid := channels[0][i]<<1 | channels[1][i]&1
+			side := channels[1][i]
+			channels[0][i] = (mid + side) >> 1
+			channels[1][i] = (mid - side) >> 1
+		}
+	}
+
+	pcm := make([]byte, 0, blockSize*nChannels*2)
+	for i := range blockSize {
+		for c := range channels {
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(channels[c][i])))
+		}
+	}
+
+	d.md5.Write(pcm)
+	d.pcm = pcm
+
+	return nil
+}
+
+func (d *FLACDecoderReader) decodeSubframe(blockSize int, bps uint) ([]int64, error) {
+	r := &d.bits
+
+	header, err := r.readBits(8)
+	if err != nil {
+		return nil, err
+	}
+
+	if header&0x80 != 0 {
+		return nil, fmt.Errorf("%w: invalid subframe padding", ErrFLACInvalidFrame)
+	}
+
+	subframeType := int(header >> 1 & 0b111111)
+
+	wasted := uint(0)
+	if header&1 != 0 {
+		k, err := r.readUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		wasted = uint(k) + 1
+		bps -= wasted
+	}
+
+	samples := make([]int64, blockSize)
+
+	readWarmup := func(order int) error {
+		for i := range order {
+			samples[i], err = r.readSigned(bps)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	switch {
+	case subframeType == flacSubframeType_Constant:
+		v, err := r.readSigned(bps)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range samples {
+			samples[i] = v
+		}
+	case subframeType == flacSubframeType_Verbatim:
+		err = readWarmup(blockSize)
+		if err != nil {
+			return nil, err
+		}
+	case subframeType&0b111000 == flacSubframeType_Fixed && subframeType&0b111 <= flacMaxFixedOrder:
+		order := subframeType & 0b111
+
+		err = readWarmup(order)
+		if err == nil {
+			err = d.decodeResidual(samples, order)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := order; i < blockSize; i++ {
+			x := func(j int) int64 { return samples[i-j] }
+
+			switch order {
+			case 1:
+				samples[i] += x(1)
+			case 2:
+				samples[i] += 2*x(1) - x(2)
+			case 3:
+				samples[i] += 3*x(1) - 3*x(2) + x(3)
+			case 4:
+				samples[i] += 4*x(1) - 6*x(2) + 4*x(3) - x(4)
+			}
+		}
+	case subframeType&0b100000 == flacSubframeType_LPC:
+		order := subframeType&0b11111 + 1
+
+		err = readWarmup(order)
+		if err != nil {
+			return nil, err
+		}
+
+		precision, err := r.readBits(4)
+		if err != nil {
+			return nil, err
+		}
+
+		if precision == 0b1111 {
+			return nil, fmt.Errorf("%w: invalid lpc precision", ErrFLACInvalidFrame)
+		}
+
+		shift, err := r.readSigned(5)
+		if err != nil {
+			return nil, err
+		}
+
+		if shift < 0 {
+			return nil, fmt.Errorf("%w: negative lpc shift", ErrFLACUnsupported)
+		}
+
+		coeffs := make([]int64, order)
+		for i := range coeffs {
+			coeffs[i], err = r.readSigned(uint(precision) + 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = d.decodeResidual(samples, order)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := order; i < blockSize; i++ {
+			var prediction int64
+			for j, coeff := range coeffs {
+				prediction += coeff * samples[i-j-1]
+			}
+
+			samples[i] += prediction >> shift
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid subframe type %06b", ErrFLACInvalidFrame, subframeType)
+	}
+
+	if wasted > 0 {
+		for i := range samples {
+			samples[i] <<= wasted
+		}
+	}
+
+	return samples, nil
+}
+
+// reads the residual into samples[order:]
+func (d *FLACDecoderReader) decodeResidual(samples []int64, order int) error {
+	r := &d.bits
+
+	method, err := r.readBits(2)
+	if err != nil {
+		return err
+	}
+
+	var paramBits uint
+	switch method {
+	case flacResidualCodingMethod_Rice:
+		paramBits = 4
+	case flacResidualCodingMethod_Rice2:
+		paramBits = 5
+	default:
+		return fmt.Errorf("%w: invalid residual coding method", ErrFLACInvalidFrame)
+	}
+
+	escape := uint64(1)<<paramBits - 1
+
+	partitionOrder, err := r.readBits(4)
+	if err != nil {
+		return err
+	}
+
+	nPartitionSamples := len(samples) >> partitionOrder
+	if nPartitionSamples<<partitionOrder != len(samples) || nPartitionSamples < order {
+		return fmt.Errorf("%w: invalid partition order", ErrFLACInvalidFrame)
+	}
+
+	i := order
+	for p := range 1 << partitionOrder {
+		n := nPartitionSamples
+		if p == 0 {
+			n -= order
+		}
+
+		k, err := r.readBits(paramBits)
+		if err != nil {
+			return err
+		}
+
+		if k == escape {
+			nBits, err := r.readBits(5)
+			if err != nil {
+				return err
+			}
+
+			for range n {
+				samples[i], err = r.readSigned(uint(nBits))
+				if err != nil {
+					return err
+				}
+				i++
+			}
+
+			continue
+		}
+
+		for range n {
+			q, err := r.readUnary()
+			if err != nil {
+				return err
+			}
+
+			low, err := r.readBits(uint(k))
+			if err != nil {
+				return err
+			}
+
+			samples[i] = unzigzag(q<<k | low)
+			i++
+		}
+	}
+
+	return nil
+}
+
+// decodes a flac stream from r. the stream must have 16 bits per sample
+func NewFLACDecoderReader(r io.Reader) (*FLACDecoderReader, error) {
+	br := bufio.NewReader(r)
+
+	var magic [len(flacMagic)]byte
+	_, err := io.ReadFull(br, magic[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flac magic: %w", err)
+	}
+
+	if string(magic[:]) != flacMagic {
+		return nil, ErrFLACInvalidMagic
+	}
+
+	d := &FLACDecoderReader{r: br, bits: bitReader{r: br}, md5: md5.New()}
+	foundStreamInfo := false
+
+	for {
+		var header [4]byte
+		_, err := io.ReadFull(br, header[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read flac metadata block header: %w", err)
+		}
+
+		isLast := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		block := make([]byte, length)
+		_, err = io.ReadFull(br, block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read flac metadata block: %w", err)
+		}
+
+		if blockType == flacMetadataBlockType_StreamInfo {
+			if length < flacStreamInfoSize {
+				return nil, fmt.Errorf("%w: STREAMINFO is too short", ErrFLACInvalidFrame)
+			}
+
+			d.info, err = parseFLACStreamInfo(block)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse flac STREAMINFO: %w", err)
+			}
+
+			foundStreamInfo = true
+		}
+
+		if isLast {
+			break
+		}
+	}
+
+	if !foundStreamInfo {
+		return nil, ErrFLACMissingStreamInfo
+	}
+
+	if d.info.BitsPerSample != flacBitsPerSample {
+		return nil, fmt.Errorf("%w: only 16 bits per sample is supported, got %d", ErrFLACUnsupported, d.info.BitsPerSample)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,311 @@
+package codecs_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+)
+
+func TestFLACEncoderWriterRoundTrip(t *testing.T) {
+	pcm, err := os.ReadFile("./testdata/sample.pcms16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// incomplete samples at the end of the stream are dropped by the encoder
+	pcm = pcm[:len(pcm)/4*4]
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "sample.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w, err := codecs.NewFLACEncoderWriter(f, 2, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.Copy(w, bytes.NewReader(pcm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Size() >= int64(len(pcm)) {
+		t.Fatalf("flac stream is not smaller than the pcm. pcm is %d bytes, flac is %d bytes", len(pcm), stat.Size())
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := codecs.NewFLACDecoderReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := r.StreamInfo()
+	if info.NumSamples != uint64(len(pcm)/4) {
+		t.Errorf("incorrect number of samples in STREAMINFO. want %d, got %d", len(pcm)/4, info.NumSamples)
+	}
+
+	if info.MD5 != md5.Sum(pcm) {
+		t.Errorf("incorrect md5 in STREAMINFO. want %x, got %x", md5.Sum(pcm), info.MD5)
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decoded, pcm) {
+		t.Fatalf("decoded pcm does not match. want %d bytes, got %d bytes", len(pcm), len(decoded))
+	}
+}
+
+func TestFLACEncoderWriterFlush(t *testing.T) {
+	signals := map[string]func(i int) int16{
+		"silence": func(i int) int16 { return 0 },
+		"sine":    func(i int) int16 { return int16(10000 * math.Sin(float64(i)/20)) },
+		"noise":   func(i int) int16 { return int16(i*7919 ^ i>>3) },
+	}
+
+	for name, signal := range signals {
+		t.Run(name, func(t *testing.T) {
+			var stream bytes.Buffer
+			w, err := codecs.NewFLACEncoderWriter(&stream, 1, 44100)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pcm []byte
+			for _, nSamples := range []int{1, 17, 5000} {
+				samples := make([]int16, nSamples)
+				for i := range samples {
+					samples[i] = signal(len(pcm)/2 + i)
+				}
+
+				chunk := codecs.S16LEToBytes(samples)
+				pcm = append(pcm, chunk...)
+
+				_, err = w.Write(chunk)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = w.Flush()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := codecs.NewFLACDecoderReader(&stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(decoded, pcm) {
+				t.Fatalf("decoded pcm does not match. want %d bytes, got %d bytes", len(pcm), len(decoded))
+			}
+		})
+	}
+}
+
+func TestFLACEncoderWriterFlushBlockSizes(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "flushed.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w, err := codecs.NewFLACEncoderWriter(f, 1, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first sample is too few for a frame of its own, so it is carried over to the next frame
+	for _, nSamples := range []int{1, 17, 5000} {
+		_, err = w.Write(make([]byte, nSamples*2))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = w.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := codecs.NewFLACDecoderReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the frames hold 18, 4096 and 904 samples. the last frame is excluded from the minimum
+	info := r.StreamInfo()
+	if info.MinBlockSize != 18 || info.MaxBlockSize != 4096 || info.NumSamples != 5018 {
+		t.Errorf("expected blocks of 18 to 4096 samples and 5018 samples in total, got %d to %d and %d", info.MinBlockSize, info.MaxBlockSize, info.NumSamples)
+	}
+}
+
+// reference.flac was encoded by ffmpeg 2.6. testdata/reference.flac.LICENSE describes where it is
+// from and how it was changed. its md5 is unknown, but the crc of every frame was computed by ffmpeg
+func TestFLACDecoderReaderReference(t *testing.T) {
+	f, err := os.Open("./testdata/reference.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	r, err := codecs.NewFLACDecoderReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := r.StreamInfo()
+	if info.NumChannels != 1 || info.SampleRateHz != 8000 || info.MinBlockSize != 576 {
+		t.Errorf("expected 8000Hz mono with 576 sample blocks, got %+v", info)
+	}
+
+	if want := int(info.NumSamples) * info.NumChannels * 2; len(pcm) != want {
+		t.Fatalf("expected %d bytes of pcm, got %d", want, len(pcm))
+	}
+
+	// the audio that the reference encoder produced survives being encoded again
+	var stream bytes.Buffer
+
+	w, err := codecs.NewFLACEncoderWriter(&stream, info.NumChannels, info.SampleRateHz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write(pcm)
+	if err == nil {
+		err = w.Close()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = codecs.NewFLACDecoderReader(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decoded, pcm) {
+		t.Fatalf("decoded pcm does not match. want %d bytes, got %d bytes", len(pcm), len(decoded))
+	}
+}
+
+// ffmpeg decodes what the encoder writes. skipped unless ffmpeg is installed
+func TestFLACEncoderWriterDecodedByFFmpeg(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+
+	pcm, err := os.ReadFile("./testdata/sample.pcms16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm = pcm[:len(pcm)/4*4]
+
+	path := filepath.Join(t.TempDir(), "sample.flac")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w, err := codecs.NewFLACEncoderWriter(f, 2, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flushing part way through blocks gives frames of every size
+	for chunk := range slices.Chunk(pcm, 30001*4) {
+		_, err = w.Write(chunk)
+		if err == nil {
+			err = w.Flush()
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(ffmpeg, "-hide_banner", "-loglevel", "error", "-xerror", "-i", path, "-f", "s16le", "pipe:1")
+	cmd.Stderr = &stderr
+
+	decoded, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("ffmpeg failed to decode the stream: %v: %s", err, stderr.String())
+	}
+
+	if !bytes.Equal(decoded, pcm) {
+		t.Fatalf("pcm decoded by ffmpeg does not match. want %d bytes, got %d bytes", len(pcm), len(decoded))
+	}
+}
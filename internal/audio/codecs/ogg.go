@@ -0,0 +1,207 @@
+package codecs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+)
+
+const (
+	oggCapturePattern = "OggS"
+
+	oggHeaderType_BOS = 0x02
+	oggHeaderType_EOS = 0x04
+
+	oggMaxSegments    = 255
+	oggSegmentSize    = 255
+	oggPageHeaderSize = 27
+
+	// granule positions of ogg opus streams are always counted at 48 kHz
+	oggOpusGranuleRateHz = 48000
+
+	// samples at 48 kHz that the decoder has to discard from the start of the stream. this is the
+	// lookahead of the libopus encoder
+	oggOpusPreSkip = 312
+
+	oggOpusVendor = "fredboard"
+)
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range 256 {
+		c := uint32(i) << 24
+		for range 8 {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+		table[i] = c
+	}
+
+	return table
+}()
+
+// crc-32 with polynomial 0x04C11DB7, without reflection or a final xor
+func oggCRC(p []byte) uint32 {
+	var crc uint32
+	for _, b := range p {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+
+	return crc
+}
+
+// writes opus packets into an ogg container as described in RFC 7845. each call to Write ends
+// with a complete page, so everything that has been written can be played even if the stream is
+// never closed
+type OggOpusWriter struct {
+	w            io.Writer
+	sampleRateHz int
+
+	serial         uint32
+	sequenceNumber uint32
+	granule        uint64
+
+	segments []byte
+	data     []byte
+	closed   bool
+}
+
+func (w *OggOpusWriter) Write(p []Packet) (n int, err error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	for _, packet := range p {
+		nSegments := len(packet.Data)/oggSegmentSize + 1
+		if nSegments > oggMaxSegments {
+			return n, fmt.Errorf("opus packet of %d bytes is too large for an ogg page", len(packet.Data))
+		}
+
+		if len(w.segments)+nSegments > oggMaxSegments {
+			err = w.writePage(0)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		for range nSegments - 1 {
+			w.segments = append(w.segments, oggSegmentSize)
+		}
+		w.segments = append(w.segments, byte(len(packet.Data)%oggSegmentSize))
+		w.data = append(w.data, packet.Data...)
+
+		w.granule = packet.EndTimestamp()*oggOpusGranuleRateHz/uint64(w.sampleRateHz) + oggOpusPreSkip
+		n++
+	}
+
+	if len(w.segments) > 0 {
+		err = w.writePage(0)
+	}
+
+	return n, err
+}
+
+// writes the final page of the stream. does not close the underlying writer
+func (w *OggOpusWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.writePage(oggHeaderType_EOS)
+}
+
+func (w *OggOpusWriter) writePage(headerType byte) error {
+	page := make([]byte, 0, oggPageHeaderSize+len(w.segments)+len(w.data))
+	page = append(page, oggCapturePattern...)
+	page = append(page, 0) // version
+	page = append(page, headerType)
+	page = binary.LittleEndian.AppendUint64(page, w.granule)
+	page = binary.LittleEndian.AppendUint32(page, w.serial)
+	page = binary.LittleEndian.AppendUint32(page, w.sequenceNumber)
+	page = binary.LittleEndian.AppendUint32(page, 0) // crc, which is calculated with this set to 0
+	page = append(page, byte(len(w.segments)))
+	page = append(page, w.segments...)
+	page = append(page, w.data...)
+
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+
+	w.segments = w.segments[:0]
+	w.data = w.data[:0]
+	w.sequenceNumber++
+
+	_, err := w.w.Write(page)
+	return err
+}
+
+// each header packet is on its own page with a granule position of 0
+func (w *OggOpusWriter) writeHeaderPacket(packet []byte, headerType byte) error {
+	w.segments = append(w.segments, byte(len(packet)))
+	w.data = append(w.data, packet...)
+
+	return w.writePage(headerType)
+}
+
+// writes the identification and comment headers. the opus packets must be encoded at sampleRateHz
+func NewOggOpusWriter(w io.Writer, nChannels, sampleRateHz int) (*OggOpusWriter, error) {
+	if nChannels < 1 || nChannels > 2 || sampleRateHz <= 0 {
+		return nil, ErrUnsupportedFormat
+	}
+
+	ow := &OggOpusWriter{w: w, sampleRateHz: sampleRateHz, serial: rand.Uint32()}
+
+	head := []byte("OpusHead")
+	head = append(head, 1) // version
+	head = append(head, byte(nChannels))
+	head = binary.LittleEndian.AppendUint16(head, oggOpusPreSkip)
+	head = binary.LittleEndian.AppendUint32(head, uint32(sampleRateHz))
+	head = binary.LittleEndian.AppendUint16(head, 0) // output gain
+	head = append(head, 0)                           // channel mapping family for mono and stereo
+
+	tags := []byte("OpusTags")
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(oggOpusVendor)))
+	tags = append(tags, oggOpusVendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0) // number of user comments
+
+	err := ow.writeHeaderPacket(head, oggHeaderType_BOS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write ogg opus identification header: %w", err)
+	}
+
+	err = ow.writeHeaderPacket(tags, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write ogg opus comment header: %w", err)
+	}
+
+	return ow, nil
+}
+
+// encodes 16-bit signed little endian PCM into an ogg opus stream
+type oggOpusEncoderWriter struct {
+	EncoderWriter
+	ogg *OggOpusWriter
+}
+
+// flushes the encoder and ends the ogg stream. does not close the underlying writer
+func (e *oggOpusEncoderWriter) Close() error {
+	return errors.Join(e.EncoderWriter.Close(), e.ogg.Close())
+}
+
+func NewOggOpusEncoderWriter(w io.Writer, nChannels, sampleRateHz, frameSize int) (EncoderWriter, error) {
+	ogg, err := NewOggOpusWriter(w, nChannels, sampleRateHz)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := NewOpusEncoderWriter(ogg, nChannels, sampleRateHz, frameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oggOpusEncoderWriter{EncoderWriter: enc, ogg: ogg}, nil
+}
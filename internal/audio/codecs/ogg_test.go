@@ -0,0 +1,169 @@
+package codecs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs/testdata"
+)
+
+type oggPage struct {
+	headerType     byte
+	granule        uint64
+	serial         uint32
+	sequenceNumber uint32
+	packets        [][]byte
+}
+
+// crc-32 as used by ogg, calculated bit by bit so that it does not share any code with the writer
+func oggPageCRC(p []byte) uint32 {
+	var crc uint32
+	for _, b := range p {
+		crc ^= uint32(b) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// parses ogg pages where every packet ends on the page that it starts on
+func parseOggPages(t *testing.T, stream []byte) []oggPage {
+	pages := make([]oggPage, 0)
+
+	for len(stream) > 0 {
+		if len(stream) < 27 || string(stream[:4]) != "OggS" {
+			t.Fatalf("invalid ogg page header at page %d", len(pages))
+		}
+
+		nSegments := int(stream[26])
+		segments := stream[27 : 27+nSegments]
+
+		dataSize := 0
+		for _, s := range segments {
+			dataSize += int(s)
+		}
+
+		pageSize := 27 + nSegments + dataSize
+		page := slices.Clone(stream[:pageSize])
+		stream = stream[pageSize:]
+
+		crc := binary.LittleEndian.Uint32(page[22:])
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		if oggPageCRC(page) != crc {
+			t.Fatalf("incorrect crc of page %d", len(pages))
+		}
+
+		parsed := oggPage{
+			headerType:     page[5],
+			granule:        binary.LittleEndian.Uint64(page[6:]),
+			serial:         binary.LittleEndian.Uint32(page[14:]),
+			sequenceNumber: binary.LittleEndian.Uint32(page[18:]),
+		}
+
+		data := page[27+nSegments:]
+		packet := make([]byte, 0)
+
+		for _, s := range segments {
+			packet = append(packet, data[:s]...)
+			data = data[s:]
+
+			if s < 255 {
+				parsed.packets = append(parsed.packets, packet)
+				packet = make([]byte, 0)
+			}
+		}
+
+		pages = append(pages, parsed)
+	}
+
+	return pages
+}
+
+func TestOggOpusEncoderWriter(t *testing.T) {
+	pcm, err := os.ReadFile("./testdata/sample.pcms16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+
+	w, err := codecs.NewOggOpusEncoderWriter(&stream, 2, 48000, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.Copy(w, bytes.NewReader(pcm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := parseOggPages(t, stream.Bytes())
+	if len(pages) < 3 {
+		t.Fatalf("expected at least 3 pages, got %d", len(pages))
+	}
+
+	if pages[0].headerType != 0x02 || len(pages[0].packets) != 1 || !bytes.HasPrefix(pages[0].packets[0], []byte("OpusHead")) {
+		t.Errorf("first page is not a beginning of stream page with the identification header")
+	}
+
+	if len(pages[1].packets) != 1 || !bytes.HasPrefix(pages[1].packets[0], []byte("OpusTags")) {
+		t.Errorf("second page does not contain the comment header")
+	}
+
+	if pages[len(pages)-1].headerType != 0x04 {
+		t.Errorf("last page is not an end of stream page")
+	}
+
+	packets := make([][]byte, 0)
+	var prevGranule uint64
+
+	for idx, page := range pages {
+		if page.serial != pages[0].serial {
+			t.Errorf("page %d has serial %d, expected %d", idx, page.serial, pages[0].serial)
+		}
+
+		if page.sequenceNumber != uint32(idx) {
+			t.Errorf("page %d has sequence number %d", idx, page.sequenceNumber)
+		}
+
+		if page.granule < prevGranule {
+			t.Errorf("granule position of page %d decreased from %d to %d", idx, prevGranule, page.granule)
+		}
+		prevGranule = page.granule
+
+		if idx >= 2 {
+			packets = append(packets, page.packets...)
+		}
+	}
+
+	if len(packets) != len(testdata.PCMS16LESampleEncodedAsOpus) {
+		t.Fatalf("incorrect number of packets. want %d, got %d", len(testdata.PCMS16LESampleEncodedAsOpus), len(packets))
+	}
+
+	for idx, packet := range packets {
+		if !slices.Equal(packet, testdata.PCMS16LESampleEncodedAsOpus[idx]) {
+			t.Fatalf("incorrect packet (idx = %d)", idx)
+		}
+	}
+
+	nSamples := uint64(len(pcm) / 4)
+	if prevGranule < nSamples {
+		t.Errorf("final granule position %d is before the end of the %d samples", prevGranule, nSamples)
+	}
+}
@@ -8,13 +8,39 @@ import (
 	"layeh.com/gopus"
 )
 
-type OpusWriter interface {
-	Write(p [][]byte) (n int /* number of frames consumed */, err error)
+// durations that an opus frame is allowed to have, in ascending order
+var validOpusFrameDurations = []time.Duration{
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	40 * time.Millisecond,
+	60 * time.Millisecond,
+}
+
+const CodecName_Opus = "opus"
+
+func init() {
+	Register(Codec{
+		Name:          CodecName_Opus,
+		SampleRatesHz: []int{8000, 12000, 16000, 24000, 48000},
+		NumChannels:   []int{1, 2},
+		NewEncoderWriter: func(w PacketWriter, opts EncoderOptions) (EncoderWriter, error) {
+			return NewOpusEncoderWriter(w, opts.NumChannels, opts.SampleRateHz, opts.FrameSize)
+		},
+		NewDecoderReader: func(r PacketReader, opts DecoderOptions) (io.Reader, error) {
+			return newOpusDecoderReader(r, opts.NumChannels, opts.SampleRateHz, opts.OnPacket)
+		},
+		NewFileEncoderWriter: func(w io.Writer, opts EncoderOptions) (EncoderWriter, error) {
+			return NewOggOpusEncoderWriter(w, opts.NumChannels, opts.SampleRateHz, opts.FrameSize)
+		},
+	})
 }
 
 type opusEncoderWriter struct {
-	w   OpusWriter
-	enc *gopus.Encoder
+	w     PacketWriter
+	enc   *gopus.Encoder
+	clock packetClock
 
 	nChannels    int
 	sampleRateHz int
@@ -25,7 +51,7 @@ type opusEncoderWriter struct {
 
 func (e *opusEncoderWriter) Write(p []byte) (n int, err error) {
 	e.pcm = append(e.pcm, p...)
-	frames := make([][]byte, 0)
+	frames := make([]Packet, 0)
 
 	frameSizeBytes := e.nChannels * e.frameSize * 2 // last *2 is because each sample is an int16 and thus 2 bytes
 	for len(e.pcm) >= frameSizeBytes {
@@ -37,56 +63,70 @@ func (e *opusEncoderWriter) Write(p []byte) (n int, err error) {
 			return len(p), err
 		}
 
-		frames = append(frames, opus)
+		frames = append(frames, e.clock.next(opus, e.frameSize))
 	}
 
-	for len(frames) > 0 {
-		n, err := e.w.Write(frames)
-		frames = frames[n:]
-
-		if err != nil {
-			return len(p), err
-		}
+	err = e.writeFrames(frames)
+	if err != nil {
+		return len(p), err
 	}
 
 	return len(p), nil
 }
 
-// flush remaining pcm data in into a final padded opus frame
-func (e *opusEncoderWriter) Close() error {
+// encode remaining pcm data into a final opus frame. the frame is padded with silence up to
+// the shortest valid opus frame duration that can hold all of the remaining samples.
+//
+// the writer can continue to be used after flushing, which allows the same encoder to be
+// reused between tracks
+func (e *opusEncoderWriter) Flush() error {
 	pcm := BytesToS16LE(e.pcm)
-	frameSizeMs := float32(len(pcm)) / float32(e.nChannels) * 1000 / float32(e.sampleRateHz)
+	e.pcm = e.pcm[:0]
 
-	var targetMs float32
-	for _, validMs := range []float32{2.5, 5, 10, 20, 40, 60} {
-		if frameSizeMs <= validMs {
-			targetMs = validMs
-			break
-		}
+	if len(pcm) == 0 {
+		return nil
 	}
 
-	targetFrameSize := int(targetMs * float32(e.sampleRateHz) / 1000 * float32(e.nChannels))
-	paddedPCM := make([]int16, targetFrameSize)
-	copy(paddedPCM, pcm)
+	nSamplesPerChannel := (len(pcm) + e.nChannels - 1) / e.nChannels
 
-	frameSizeBytes := e.nChannels * e.frameSize * 2 // last *2 is because each sample is an int16 and thus 2 bytes
-	opus, err := e.enc.Encode(pcm, e.frameSize, frameSizeBytes)
-	e.pcm = e.pcm[:0]
+	frameSize, ok := paddedFrameSize(nSamplesPerChannel, e.sampleRateHz)
+	if !ok {
+		return fmt.Errorf("failed to flush opus encoder: %d buffered samples per channel exceeds the maximum opus frame duration", nSamplesPerChannel)
+	}
 
+	paddedPCM := make([]int16, frameSize*e.nChannels)
+	copy(paddedPCM, pcm)
+
+	frameSizeBytes := e.nChannels * frameSize * 2 // last *2 is because each sample is an int16 and thus 2 bytes
+	opus, err := e.enc.Encode(paddedPCM, frameSize, frameSizeBytes)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.w.Write([][]byte{opus})
-	if err != nil {
-		return err
+	// the padding is part of the stream, so it counts towards the timestamp of the next frame
+	return e.writeFrames([]Packet{e.clock.next(opus, frameSize)})
+}
+
+// flush remaining pcm data in into a final padded opus frame
+func (e *opusEncoderWriter) Close() error {
+	return e.Flush()
+}
+
+func (e *opusEncoderWriter) writeFrames(frames []Packet) error {
+	for len(frames) > 0 {
+		n, err := e.w.Write(frames)
+		frames = frames[n:]
+
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 // encodes opus from 16-bit signed little endian PCM
-func NewOpusEncoderWriter(w OpusWriter, nChannels, sampleRateHz, frameSize int) (io.WriteCloser, error) {
+func NewOpusEncoderWriter(w PacketWriter, nChannels, sampleRateHz, frameSize int) (EncoderWriter, error) {
 	enc, err := gopus.NewEncoder(sampleRateHz, nChannels, gopus.Audio)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create opus encoder: %w", err)
@@ -95,6 +135,7 @@ func NewOpusEncoderWriter(w OpusWriter, nChannels, sampleRateHz, frameSize int)
 	return &opusEncoderWriter{
 		w:            w,
 		enc:          enc,
+		clock:        packetClock{sampleRateHz: sampleRateHz},
 		nChannels:    nChannels,
 		sampleRateHz: sampleRateHz,
 		frameSize:    frameSize,
@@ -102,6 +143,94 @@ func NewOpusEncoderWriter(w OpusWriter, nChannels, sampleRateHz, frameSize int)
 	}, nil
 }
 
-func frameDuration(frameSize, sampleRateHz int) time.Duration {
-	return time.Duration((float64(frameSize) / float64(sampleRateHz)) * float64(time.Second))
+// finds the smallest valid opus frame size (in samples per channel) that can hold nSamples
+func paddedFrameSize(nSamples, sampleRateHz int) (frameSize int, ok bool) {
+	for _, d := range validOpusFrameDurations {
+		frameSize = int(int64(d) * int64(sampleRateHz) / int64(time.Second))
+		if nSamples <= frameSize {
+			return frameSize, true
+		}
+	}
+
+	return 0, false
+}
+
+type opusDecoderReader struct {
+	r   PacketReader
+	dec *gopus.Decoder
+
+	nChannels    int
+	sampleRateHz int
+	maxFrameSize int
+
+	// may be nil
+	onPacket func(packet Packet, pcm []byte)
+
+	// the packet that was decoded most recently. used to detect gaps in the stream
+	prev    Packet
+	hasPrev bool
+
+	pcm []byte
+	err error
+}
+
+func (d *opusDecoderReader) Read(p []byte) (n int, err error) {
+	for len(d.pcm) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		packet, err := d.r.ReadPacket()
+		if err != nil {
+			d.err = err
+			continue
+		}
+
+		pcm, err := d.dec.Decode(packet.Data, d.maxFrameSize, false)
+		if err != nil {
+			d.err = fmt.Errorf("failed to decode opus frame: %w", err)
+			continue
+		}
+
+		// fill missing samples with silence so that the output stays aligned with the timestamps
+		var gap uint64
+		if d.hasPrev && packet.NumSamples > 0 && packet.Timestamp > d.prev.EndTimestamp() {
+			// limit the silence to one second in case the timestamps are corrupted
+			gap = min(packet.Timestamp-d.prev.EndTimestamp(), uint64(d.sampleRateHz))
+		}
+
+		d.prev, d.hasPrev = packet, true
+		d.pcm = append(make([]byte, gap*uint64(d.nChannels)*2), S16LEToBytes(pcm)...)
+
+		if d.onPacket != nil {
+			d.onPacket(packet, d.pcm[len(d.pcm)-len(pcm)*2:])
+		}
+	}
+
+	n = copy(p, d.pcm)
+	d.pcm = d.pcm[n:]
+
+	return n, nil
+}
+
+// decodes opus into 16-bit signed little endian PCM
+func NewOpusDecoderReader(r PacketReader, nChannels, sampleRateHz int) (io.Reader, error) {
+	return newOpusDecoderReader(r, nChannels, sampleRateHz, nil)
+}
+
+func newOpusDecoderReader(r PacketReader, nChannels, sampleRateHz int, onPacket func(packet Packet, pcm []byte)) (*opusDecoderReader, error) {
+	dec, err := gopus.NewDecoder(sampleRateHz, nChannels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+	}
+
+	return &opusDecoderReader{
+		r:            r,
+		dec:          dec,
+		nChannels:    nChannels,
+		sampleRateHz: sampleRateHz,
+		maxFrameSize: sampleRateHz * 120 / 1000, // 120ms is the longest duration that a single opus packet can hold
+		onPacket:     onPacket,
+		pcm:          make([]byte, 0),
+	}, nil
 }
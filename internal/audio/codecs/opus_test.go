@@ -5,18 +5,20 @@ import (
 	"os"
 	"slices"
 	"testing"
+	"time"
 
 	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
 	"accidentallycoded.com/fredboard/v3/internal/audio/codecs/testdata"
+	"layeh.com/gopus"
 )
 
-type opusBuffer struct {
-	Frames [][]byte
+type packetBuffer struct {
+	Frames []codecs.Packet
 }
 
-func (b *opusBuffer) Write(p [][]byte) (n int /* number of frames consumed */, err error) {
+func (b *packetBuffer) Write(p []codecs.Packet) (n int /* number of frames consumed */, err error) {
 	if b.Frames == nil {
-		b.Frames = make([][]byte, 0)
+		b.Frames = make([]codecs.Packet, 0)
 	}
 
 	b.Frames = append(b.Frames, p...)
@@ -31,7 +33,7 @@ func TestOpusEncoderWriter(t *testing.T) {
 
 	defer f.Close()
 
-	var buffer opusBuffer
+	var buffer packetBuffer
 	w, err := codecs.NewOpusEncoderWriter(&buffer, 2, 48000, 960)
 	if err != nil {
 		t.Fatal(err)
@@ -49,8 +51,151 @@ func TestOpusEncoderWriter(t *testing.T) {
 	}
 
 	for idx, frame := range buffer.Frames {
-		if !slices.Equal(frame, testdata.PCMS16LESampleEncodedAsOpus[idx]) {
-			t.Fatalf("incorrect frame (idx = %d). want %v, got %v", idx, testdata.PCMS16LESampleEncodedAsOpus[idx], frame)
+		if !slices.Equal(frame.Data, testdata.PCMS16LESampleEncodedAsOpus[idx]) {
+			t.Fatalf("incorrect frame (idx = %d). want %v, got %v", idx, testdata.PCMS16LESampleEncodedAsOpus[idx], frame.Data)
+		}
+	}
+}
+
+func TestOpusEncoderWriterFlushPartialFrame(t *testing.T) {
+	const (
+		nChannels    = 2
+		sampleRateHz = 48000
+		frameSize    = 2880 // 60ms so that every partial frame remains buffered until flushed
+	)
+
+	tests := []struct {
+		name              string
+		nSamples          int // per channel
+		expectedFrameSize int // per channel
+	}{
+		{name: "2.5ms", nSamples: 1, expectedFrameSize: 120},
+		{name: "5ms", nSamples: 121, expectedFrameSize: 240},
+		{name: "10ms", nSamples: 241, expectedFrameSize: 480},
+		{name: "20ms", nSamples: 481, expectedFrameSize: 960},
+		{name: "40ms", nSamples: 961, expectedFrameSize: 1920},
+		{name: "60ms", nSamples: 2879, expectedFrameSize: 2880},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer packetBuffer
+			w, err := codecs.NewOpusEncoderWriter(&buffer, nChannels, sampleRateHz, frameSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pcm := make([]int16, test.nSamples*nChannels)
+			for i := range pcm {
+				pcm[i] = int16(i * 64)
+			}
+
+			_, err = w.Write(codecs.S16LEToBytes(pcm))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(buffer.Frames) != 0 {
+				t.Fatalf("partial frame was encoded before flush. got %d frames", len(buffer.Frames))
+			}
+
+			err = w.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(buffer.Frames) != 1 {
+				t.Fatalf("incorrect number of frames. want 1, got %d", len(buffer.Frames))
+			}
+
+			dec, err := gopus.NewDecoder(sampleRateHz, nChannels)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := dec.Decode(buffer.Frames[0].Data, frameSize, false)
+			if err != nil {
+				t.Fatalf("failed to decode flushed frame: %s", err.Error())
+			}
+
+			if len(decoded)/nChannels != test.expectedFrameSize {
+				t.Fatalf("incorrect frame size. want %d, got %d", test.expectedFrameSize, len(decoded)/nChannels)
+			}
+		})
+	}
+}
+
+func TestOpusEncoderWriterFlushKeepsWriterOpen(t *testing.T) {
+	var buffer packetBuffer
+	w, err := codecs.NewOpusEncoderWriter(&buffer, 2, 48000, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partial := make([]byte, 500*2*2)
+
+	for i := range 3 {
+		_, err = w.Write(partial)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = w.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(buffer.Frames) != i+1 {
+			t.Fatalf("incorrect number of frames after flush %d. want %d, got %d", i, i+1, len(buffer.Frames))
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(buffer.Frames) != 3 {
+		t.Fatalf("closing an empty writer produced a frame. want 3 frames, got %d", len(buffer.Frames))
+	}
+}
+
+func TestOpusEncoderWriterPacketTiming(t *testing.T) {
+	var buffer packetBuffer
+	w, err := codecs.NewOpusEncoderWriter(&buffer, 2, 48000, 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two full frames followed by a partial frame that is padded to 10ms
+	_, err = w.Write(make([]byte, (960*2+400)*2*2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []codecs.Packet{
+		{SequenceNumber: 0, Timestamp: 0, NumSamples: 960, Duration: 20 * time.Millisecond},
+		{SequenceNumber: 1, Timestamp: 960, NumSamples: 960, Duration: 20 * time.Millisecond},
+		{SequenceNumber: 2, Timestamp: 1920, NumSamples: 480, Duration: 10 * time.Millisecond},
+	}
+
+	if len(buffer.Frames) != len(want) {
+		t.Fatalf("incorrect number of frames. want %d, got %d", len(want), len(buffer.Frames))
+	}
+
+	for idx, packet := range buffer.Frames {
+		if packet.SequenceNumber != want[idx].SequenceNumber || packet.Timestamp != want[idx].Timestamp ||
+			packet.NumSamples != want[idx].NumSamples || packet.Duration != want[idx].Duration {
+			t.Fatalf("incorrect timing (idx = %d). want %+v, got %+v", idx, want[idx], packet)
+		}
+
+		if idx > 0 && !buffer.Frames[idx].Follows(buffer.Frames[idx-1]) {
+			t.Fatalf("frame %d does not follow the previous frame", idx)
 		}
 	}
 }
@@ -0,0 +1,61 @@
+package codecs
+
+import "time"
+
+// an encoded packet and its position in the stream
+type Packet struct {
+	Data []byte
+
+	// increases by one for every packet that an encoder produces. wraps around on overflow
+	SequenceNumber uint32
+
+	// offset of the first sample in the packet from the start of the stream, in samples per
+	// channel. this is the same unit that RTP timestamps use
+	Timestamp uint64
+
+	// number of samples per channel that the packet decodes to. 0 for codecs that produce a byte
+	// stream instead of self contained packets
+	NumSamples int
+
+	Duration time.Duration
+}
+
+// the timestamp of the packet that should immediately follow this one
+func (p Packet) EndTimestamp() uint64 {
+	return p.Timestamp + uint64(p.NumSamples)
+}
+
+// reports whether p immediately follows prev with no missing packets or samples in between
+func (p Packet) Follows(prev Packet) bool {
+	return p.SequenceNumber == prev.SequenceNumber+1 && p.Timestamp == prev.EndTimestamp()
+}
+
+// assigns sequence numbers and timestamps to the packets produced by an encoder
+type packetClock struct {
+	sampleRateHz   int
+	sequenceNumber uint32
+	timestamp      uint64
+}
+
+func (c *packetClock) next(data []byte, nSamples int) Packet {
+	p := Packet{
+		Data:           data,
+		SequenceNumber: c.sequenceNumber,
+		Timestamp:      c.timestamp,
+		NumSamples:     nSamples,
+		Duration:       frameDuration(nSamples, c.sampleRateHz),
+	}
+
+	c.sequenceNumber++
+	c.timestamp += uint64(nSamples)
+
+	return p
+}
+
+func frameDuration(frameSize, sampleRateHz int) time.Duration {
+	if sampleRateHz == 0 {
+		return 0
+	}
+
+	return time.Duration((float64(frameSize) / float64(sampleRateHz)) * float64(time.Second))
+}
@@ -1,6 +1,30 @@
 package codecs
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"io"
+)
+
+const CodecName_PCMS16LE = "pcm_s16le"
+
+func init() {
+	Register(Codec{
+		Name: CodecName_PCMS16LE,
+		NewEncoderWriter: func(w PacketWriter, opts EncoderOptions) (EncoderWriter, error) {
+			sw := newPacketStreamWriter(w)
+			sw.clock.sampleRateHz = opts.SampleRateHz
+			sw.sampleSizeBytes = opts.NumChannels * 2
+
+			return &pcmEncoderWriter{sw}, nil
+		},
+		NewDecoderReader: func(r PacketReader, opts DecoderOptions) (io.Reader, error) {
+			return newPacketStreamReader(r), nil
+		},
+		NewFileEncoderWriter: func(w io.Writer, opts EncoderOptions) (EncoderWriter, error) {
+			return NewWAVEncoderWriter(w, opts.NumChannels, opts.SampleRateHz)
+		},
+	})
+}
 
 // convert little endian bytes to signed 16bit values
 func BytesToS16LE(bytes []byte) (s16le []int16) {
@@ -24,3 +48,16 @@ func S16LEToBytes(s16le []int16) (bytes []byte) {
 
 	return bytes
 }
+
+// writes each chunk of pcm data as its own packet without modifying it
+type pcmEncoderWriter struct {
+	*packetStreamWriter
+}
+
+func (e *pcmEncoderWriter) Flush() error {
+	return nil
+}
+
+func (e *pcmEncoderWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build ignore
+
+// creates reference.flac from testdata/flac.flac of github.com/gabriel-vasile/mimetype v1.4.3
+//
+//	go run reference_flac.go path/to/flac.flac
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+const (
+	originalSHA256 = "d6bfa639b71eeaa0a4aeb914edfbf2eec0f02373f9b5918ee4723fbacac1afc4"
+
+	// the samples that are left once the last frame has been removed
+	numSamples = 21312
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: go run reference_flac.go path/to/flac.flac")
+		os.Exit(2)
+	}
+
+	err := run(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != originalSHA256 {
+		return fmt.Errorf("%s is not the original file", path)
+	}
+
+	// every frame of the file starts with the sync code and a fixed block size
+	last := bytes.LastIndex(data, []byte{0xff, 0xf8})
+	if last < 0 {
+		return fmt.Errorf("no frames in %s", path)
+	}
+
+	data = data[:last]
+
+	// STREAMINFO is the first metadata block. the total number of samples is the low 36 bits of the
+	// 8 bytes that start with the sample rate, and the MD5 signature follows them
+	streamInfo := data[8 : 8+34]
+
+	packed := binary.BigEndian.Uint64(streamInfo[10:18])
+	packed = packed&^(1<<36-1) | numSamples
+	binary.BigEndian.PutUint64(streamInfo[10:18], packed)
+
+	// the signature of the original audio does not match what is left of it
+	clear(streamInfo[18:34])
+
+	return os.WriteFile("reference.flac", data, 0o644)
+}
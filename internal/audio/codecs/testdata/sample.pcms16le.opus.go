@@ -918,5 +918,5 @@ var PCMS16LESampleEncodedAsOpus [][]byte = [][]byte {
   {0xfc, 0x16, 0x0e, 0x1e, 0xea, 0xcf, 0x9b, 0x13, 0x67, 0x4b, 0x24, 0x78, 0x94, 0x85, 0x02, 0x9d, 0x6d, 0xfd, 0xa2, 0x89, 0x0a, 0x50, 0x68, 0x2f, 0x43, 0x15, 0xc1, 0xc3, 0x89, 0x77, 0xf0, 0x59, 0xb7, 0xa6, 0x70, 0x70, 0x7d, 0x8f, 0x16, 0xf6, 0x8d, 0xbe, 0xb8, 0x79, 0x7b, 0x06, 0x99, 0x83, 0xf3, 0xaf, 0xfd, 0xb7, 0xf9, 0xab, 0x4e, 0xf3, 0xfb, 0x8c, 0x7e, 0x41, 0x2d, 0x6e, 0x96, 0xeb, 0xd2, 0x65, 0xf2, 0x52, 0x28, 0x5b, 0x17, 0xbf, 0x18, 0xd2, 0xf2, 0xa4, 0xe4, 0xee, 0x91, 0xa4, 0x10, 0x2e, 0xba, 0x02, 0xa1, 0x7a, 0xb9, 0x22, 0xc9, 0xdf, 0x85, 0x83, 0xe3, 0x56, 0xd8, 0xf6, 0x18, 0x3c, 0x41, 0x89, 0x97, 0xbb, 0xfc, 0x88, 0x59, 0xe7, 0xbe, 0x29, 0x89, 0x2d, 0xa8, 0x4f, 0x34, 0x93, 0x02, 0xd2, 0xf1, 0xa4, 0xac, 0x6f, 0xcc, 0xec, 0x7d, 0x07, 0xa3, 0x6a, 0x74, 0xf7, 0x85, 0xfe, 0x0b, 0xd0, 0xe2, 0xd5, 0xab, 0xd3, 0x87, 0xe6, 0x76, 0x52, 0x85, 0xb1, 0xd2, 0xc0, 0xf8, 0xf9, 0x3e, 0x7a, 0x67, 0x8f, 0x8d, 0x09, 0x08, 0xc6, 0x36, 0xa9, 0x4f, 0xd3, 0xf0, 0x19, 0x91, 0xc2, 0x60, 0x2b, 0x9a, 0xff, 0x3a, 0xd8, 0x92, 0x20, 0x74, 0xd5, 0x6e, 0x48, 0x6b, 0x23, 0x6d, 0x49, 0xb1, 0xf5, 0x04, 0x15, 0x5c, 0xed, 0xf1, 0x2a, 0xd4, 0x38, 0x55, 0x0e, 0x1f, 0x6d, 0x1e, 0x08, 0xbd, 0xbf, 0x33, 0x70, 0xde, 0x4e, 0x36, 0x3e, 0x95, 0xe9, 0xb5, 0x83, 0x0c, 0x0a, 0xc4, 0x69, 0x02, 0x1b, 0x2b, 0x6a, 0x58, 0x55, 0x92, 0x4d, 0xd6, 0xa6, 0x96, 0x7c, 0xc6, 0xcb, 0x85, 0x2e, 0x7b, 0x69, 0xbb, 0x3f, 0x96, 0x75, 0x9a, 0x42, 0xc5, 0x35, 0xd3, 0xf6, 0xc9, 0xfe, 0x49, 0x1a, 0xf0, 0x0a, 0x00, 0x05, 0xbf, 0x05},
   {0xfc, 0x53, 0x8b, 0xcb, 0x59, 0x00, 0xdd, 0x97, 0x03, 0xbc, 0x4a, 0xcb, 0x26, 0x09, 0x53, 0x6f, 0x50, 0x5f, 0x0d, 0x2b, 0x00, 0xc2, 0x8c, 0xbe, 0x3a, 0x59, 0x94, 0xda, 0x9a, 0xdd, 0x67, 0x4a, 0x1a, 0x3f, 0xdd, 0x55, 0x16, 0x74, 0xb9, 0x15, 0x22, 0x0e, 0xf6, 0x41, 0x50, 0xe7, 0xa7, 0x04, 0x85, 0x59, 0x18, 0x23, 0xe0, 0xa8, 0x13, 0x9a, 0xb4, 0x12, 0x72, 0x54, 0x50, 0x19, 0x21, 0x99, 0xc2, 0x02, 0x72, 0xa4, 0x58, 0x14, 0x84, 0x28, 0x3d, 0xd2, 0xf6, 0x0b, 0x47, 0x4a, 0xc4, 0xb6, 0x63, 0x02, 0x2f, 0x83, 0xe8, 0xd2, 0x65, 0xfa, 0x24, 0xd3, 0xfe, 0x25, 0x12, 0x87, 0x95, 0x89, 0x00, 0x61, 0xaa, 0x91, 0x20, 0x79, 0xd2, 0x9c, 0x37, 0xd4, 0xd2, 0x8e, 0x26, 0x44, 0x30, 0x8b, 0x47, 0x41, 0xf2, 0xe3, 0xc1, 0xd1, 0x33, 0x1a, 0x43, 0x42, 0xcd, 0xa4, 0x50, 0x98, 0xd3, 0x8d, 0x40, 0xd7, 0xac, 0x86, 0xff, 0x11, 0x52, 0x2a, 0x30, 0xe6, 0x5d, 0x51, 0x4b, 0x26, 0xe1, 0x67, 0x87, 0x2a, 0xe7, 0x65, 0xf9, 0x57, 0x72, 0xa1, 0x15, 0x6d, 0xa5, 0xc7, 0xa2, 0xed, 0xb0, 0x12, 0x99, 0xcd, 0xa2, 0x2e, 0x74, 0x58, 0x4f, 0xbf, 0x2b, 0x52, 0x01, 0xba, 0x69, 0xaa, 0x94, 0x34, 0x0b, 0x0b, 0xa3, 0x25, 0x1c, 0xc8, 0x6d, 0x0d, 0x34, 0x3b, 0x2d, 0x55, 0xab, 0x5a, 0x5c, 0x4c, 0x16, 0x73, 0x90, 0x35, 0x1e, 0x10, 0x83, 0xf0, 0x49, 0xf5, 0xb9, 0xa3, 0x13, 0xbb, 0x3b, 0x3a, 0x2f, 0xb0, 0x96, 0xec, 0x08, 0xab, 0x79, 0xa5, 0x62, 0xab, 0xef, 0xd5, 0x36, 0xaf, 0x4a, 0x9b, 0x27, 0x00, 0x0e, 0xcb, 0x5a, 0xd3, 0x8b, 0xc0, 0x0f, 0x5b, 0xc0, 0x6f, 0x69, 0x22, 0x00, 0x03, 0xfb, 0x63, 0xa5, 0x50, 0x55, 0x50, 0x4a, 0x0f},
   {0xfc, 0x53, 0xaa, 0x86, 0x59, 0x61, 0xa3, 0x98, 0x24, 0x47, 0xce, 0xa9, 0xb9, 0x9b, 0x9f, 0x24, 0x04, 0xd2, 0x3e, 0xf3, 0x32, 0x6e, 0x13, 0x3c, 0xd3, 0x4d, 0xd8, 0x66, 0x6c, 0x1a, 0xee, 0xec, 0x89, 0x57, 0xa6, 0x58, 0x62, 0xbb, 0xe1, 0xfd, 0x1f, 0x98, 0xb2, 0x48, 0x6b, 0x7a, 0xd1, 0x9d, 0xe2, 0xd5, 0x5d, 0x74, 0x73, 0xbe, 0x10, 0x19, 0xec, 0xd4, 0x7e, 0x62, 0x87, 0x33, 0x8c, 0x2a, 0x89, 0xb2, 0xd4, 0x2d, 0xd9, 0x31, 0x21, 0x23, 0x90, 0x0b, 0x5a, 0xfc, 0xb3, 0xc1, 0x46, 0x9f, 0x9c, 0x49, 0x18, 0x80, 0xa3, 0x89, 0xb5, 0xd5, 0x67, 0x50, 0xc2, 0x2c, 0x10, 0x25, 0xa9, 0x4a, 0xc5, 0x9b, 0x63, 0xf2, 0x50, 0x6f, 0xd8, 0xe7, 0x8c, 0x42, 0xe7, 0xa7, 0xa7, 0x4a, 0xd3, 0x14, 0xa1, 0x0c, 0x16, 0xe1, 0xed, 0x64, 0x63, 0x89, 0xa4, 0x81, 0x5b, 0x2c, 0x95, 0xa2, 0xe6, 0xb1, 0xc8, 0xec, 0x28, 0xde, 0xbe, 0xbb, 0x3f, 0x88, 0x70, 0x37, 0x3b, 0xa6, 0x04, 0x50, 0xbd, 0xb9, 0x5f, 0x0e, 0x93, 0x12, 0x67, 0x97, 0x65, 0xb4, 0xd3, 0x09, 0x8c, 0xc5, 0xaf, 0xa7, 0x08, 0xee, 0xdc, 0x85, 0x2c, 0x7d, 0x57, 0xe5, 0xc9, 0x02, 0x4e, 0xc3, 0x9e, 0x37, 0xb8, 0x6c, 0xd7, 0xf0, 0x41, 0xb8, 0x55, 0x1d, 0x88, 0x51, 0x4f, 0x43, 0xc8, 0xfa, 0xf5, 0x7e, 0x19, 0x6a, 0x96, 0x73, 0xba, 0xc9, 0x40, 0x32, 0x13, 0xf1, 0xf9, 0xb3, 0xbe, 0xc9, 0x5a, 0xab, 0x1c, 0x31, 0x24, 0xb2, 0xef, 0x12, 0x84, 0x18, 0x18, 0xc6, 0xc9, 0xc6, 0xce, 0x6c, 0xf3, 0xeb, 0x02, 0x0a, 0xe7, 0x8f, 0x9f, 0x4b, 0xdd, 0xc5, 0x88, 0xc1, 0xca, 0xe8, 0xb6, 0xb6, 0x4f, 0xc5, 0x5e, 0xed, 0x9b, 0x26, 0x4d, 0xac, 0x50, 0xf0, 0xa0, 0x0f, 0x15, 0x55},
-  {0xfc, 0x7f, 0x9e, 0x93, 0x85, 0xfc, 0x16, 0x5d, 0x52, 0xb8, 0x27, 0x3d, 0xc9, 0x87, 0x82, 0x49, 0xe8, 0x05, 0x48, 0xc0, 0x84, 0x74, 0x3f, 0x99, 0x26, 0x18, 0xbb, 0xa2, 0x6e, 0x6c, 0x2c, 0xc3, 0x5b, 0x42, 0x3c, 0x86, 0x47, 0xd2, 0x17, 0x97, 0xf6, 0x50, 0x57, 0x4a, 0x68, 0x12, 0x05, 0x8f, 0xce, 0x23, 0x58, 0x02, 0xc1, 0x8a, 0x6a, 0x6d, 0xa0, 0x56, 0xf5, 0x01, 0xda, 0xf2, 0x80, 0xe9, 0x38, 0xae, 0x6f, 0xe8, 0xe8, 0x44, 0xbf, 0x64, 0x70, 0x32, 0x88, 0xb7, 0x4a, 0xa9, 0xba, 0x61, 0x91, 0xf6, 0xb1, 0x3b, 0x67, 0x03, 0x5f, 0xa2, 0x5b, 0x3e, 0x5e, 0x68, 0x39, 0x02, 0xd0, 0x5c, 0xba, 0x8d, 0xca, 0x53, 0xfb, 0xc6, 0x32, 0x71, 0x17, 0x91, 0x4f, 0x23, 0x2f, 0xda, 0x52, 0xe9, 0x5d, 0x71, 0xfd, 0x3d, 0x7c, 0xa7, 0x7c, 0x0f, 0xf6, 0x95, 0x51, 0x7d, 0xe8, 0xbf, 0xc7, 0x6e, 0x15, 0xec, 0xf5, 0xf4, 0x9c, 0x1b, 0x7d, 0x8d, 0x7e, 0x79, 0x10, 0x1d, 0xe7, 0x95, 0xca, 0x83, 0x59, 0x73, 0x20, 0x82, 0x20, 0x09, 0x68, 0x81, 0xe1, 0xed, 0xfd, 0x00, 0x0e, 0x74, 0x4c, 0x3d, 0x86, 0x1e, 0xf8, 0xbe, 0x35, 0x80, 0xce, 0x6c, 0x7d, 0x31, 0x7f, 0x8f, 0x11, 0x37, 0x5a, 0x40, 0x63, 0xcf, 0x74, 0x15, 0x2e, 0xc0, 0xce, 0xb7, 0x08, 0xd0, 0x51, 0xbe, 0x7b, 0x57, 0x52, 0x85, 0x97, 0xe6, 0x84, 0x00, 0xf4, 0xf6, 0xcb, 0x4d, 0x2b, 0x83, 0x62, 0x7d, 0x5e, 0xe9, 0x8f, 0xf1, 0xc1, 0x97, 0x33, 0x82, 0x7d, 0xbf, 0xfc, 0x0e, 0x69, 0xa9, 0x67, 0x40, 0xed, 0xc1, 0xba, 0x57, 0x93, 0x8e, 0x8e, 0xf0, 0xb9, 0x3d, 0x93, 0x83, 0xd1, 0x63, 0x90, 0xcf, 0xa0, 0x77, 0xee, 0x84, 0x48, 0xcb, 0x99, 0xf4, 0xe5, 0x17, 0x57, 0xe4, 0x66, 0x89, 0xde, 0xea, 0x9d, 0xe5, 0xb5, 0x34, 0x11, 0x54, 0xce, 0x22, 0xc8, 0xf1, 0xf9, 0xd1, 0x89, 0x31, 0x34, 0xd7, 0x28, 0x60, 0x77, 0x20, 0x69, 0x5a, 0xa4, 0x20, 0x90, 0x1a, 0x27, 0xf4, 0xc9, 0xb5, 0x1f, 0x07, 0x16, 0xab, 0x6a, 0xf0, 0x5e},
+  {0xfc, 0x41, 0x7a, 0x22, 0x39, 0x3d, 0x1f, 0x25, 0x2f, 0x02, 0x24, 0x75, 0xc3, 0x3e, 0x5b, 0x0c, 0x11, 0x72, 0x03, 0x0b, 0x97, 0x2c, 0x4b, 0x34, 0xfc, 0x38, 0xcc, 0xd5, 0x6b, 0x85, 0x9a, 0x4c, 0x12, 0x03, 0x6e, 0x3b, 0x34, 0x92, 0x4a, 0xd1, 0x7c, 0x1e, 0x82, 0x5e, 0x27, 0x13, 0x15, 0xa0, 0x81, 0x4d, 0xcd, 0xeb, 0xea, 0x54, 0x7a, 0x0a, 0x4f, 0x1a, 0xe8, 0x6a, 0x66, 0x6e, 0x2c, 0x6a, 0x59, 0xc4, 0x16, 0xd2, 0x88, 0xeb, 0xad, 0x76, 0x2a, 0x8b, 0xc1, 0x33, 0x87, 0xbb, 0x16, 0xf9, 0xd3, 0xbb, 0xec, 0x7b, 0x8a, 0x14, 0x9a, 0xea, 0x1a, 0xf8, 0xe4, 0xd1, 0x51, 0x96, 0x5d, 0x0a, 0xba, 0xea, 0x85, 0x65, 0xa5, 0x11, 0xd9, 0x33, 0x20, 0xbe, 0xbb, 0xe0, 0xea, 0xb3, 0xb7, 0xf7, 0xf5, 0x54, 0xe4, 0x1a, 0xd6, 0x1e, 0xf9, 0x1c, 0x6f, 0x33, 0xde, 0x41, 0x7f, 0x91, 0x93, 0xf5, 0xe5, 0x56, 0x7f, 0xaa, 0xea, 0x25, 0x11, 0x33, 0x9b, 0x95, 0x1d, 0x1e, 0xec, 0x52, 0xa4, 0xde, 0x83, 0xdc, 0x17, 0x95, 0x81, 0x35, 0xd9, 0xb3, 0xc6, 0x9b, 0x8a, 0xf4, 0x6b, 0x40, 0xa8, 0x83, 0x30, 0x0f, 0x9f, 0x78, 0xc2, 0x98, 0x54, 0xa3, 0xbd, 0x9e, 0x00, 0xd9, 0x8f, 0x9b, 0x6a, 0x69, 0xbb, 0xcb, 0x61, 0x14, 0x67, 0x5c, 0xbf, 0x6e, 0xc9, 0xb7, 0xd9, 0xba, 0x8c, 0x78, 0x13, 0xbd, 0x7d, 0x32, 0xa8, 0xd4, 0x06, 0xd3, 0xdd, 0x92, 0x1d, 0x9e, 0x87, 0x2a, 0x44, 0x10, 0xa0, 0x11, 0x7a, 0xa0, 0x15, 0x26, 0xd5, 0xd6, 0x27, 0xc1, 0xa6, 0x04, 0x6b, 0x80, 0xe5, 0xa4, 0xe8, 0xa6, 0xb5, 0xb5, 0xf8, 0x42, 0x81, 0xca, 0xd6, 0x31, 0xa0, 0xc6, 0x91, 0xdc, 0x8f, 0x6f, 0xf6, 0x93, 0xfb, 0x65, 0x0f, 0xb0, 0x55, 0xfa, 0x40, 0xaf},
 }
@@ -0,0 +1,140 @@
+package codecs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	wavHeaderSize = 44
+
+	// offsets of the sizes in the header that are only known once the stream is closed
+	wavRIFFSizeOffset = 4
+	wavDataSizeOffset = 40
+
+	wavFormat_PCM = 1
+)
+
+// writes 16-bit signed little endian PCM into a WAV file
+type wavEncoderWriter struct {
+	w      io.Writer
+	seeker io.WriteSeeker
+
+	// position of the header if w is an io.WriteSeeker
+	headerOffset int64
+
+	nDataBytes int64
+	closed     bool
+}
+
+func (e *wavEncoderWriter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err = e.w.Write(p)
+	e.nDataBytes += int64(n)
+
+	return n, err
+}
+
+// wav is not packet based, so there is never anything buffered
+func (e *wavEncoderWriter) Flush() error {
+	return nil
+}
+
+// if the underlying writer is an [io.WriteSeeker], the header is rewritten with the length of the
+// audio. does not close the underlying writer
+func (e *wavEncoderWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+
+	if e.seeker == nil {
+		return nil
+	}
+
+	end, err := e.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to get end of wav stream: %w", err)
+	}
+
+	dataSize := uint32(min(e.nDataBytes, math.MaxUint32-(wavHeaderSize-8)))
+
+	var size [4]byte
+
+	binary.LittleEndian.PutUint32(size[:], dataSize+wavHeaderSize-8)
+	_, err = e.writeAt(size[:], e.headerOffset+wavRIFFSizeOffset)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite wav header: %w", err)
+	}
+
+	binary.LittleEndian.PutUint32(size[:], dataSize)
+	_, err = e.writeAt(size[:], e.headerOffset+wavDataSizeOffset)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite wav header: %w", err)
+	}
+
+	_, err = e.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+func (e *wavEncoderWriter) writeAt(p []byte, offset int64) (n int, err error) {
+	_, err = e.seeker.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.seeker.Write(p)
+}
+
+// the sizes are set to their maximum values, which most readers treat as a stream of unknown length
+func appendWAVHeader(p []byte, nChannels, sampleRateHz int) []byte {
+	blockAlign := nChannels * 2
+
+	p = append(p, "RIFF"...)
+	p = binary.LittleEndian.AppendUint32(p, math.MaxUint32)
+	p = append(p, "WAVE"...)
+
+	p = append(p, "fmt "...)
+	p = binary.LittleEndian.AppendUint32(p, 16)
+	p = binary.LittleEndian.AppendUint16(p, wavFormat_PCM)
+	p = binary.LittleEndian.AppendUint16(p, uint16(nChannels))
+	p = binary.LittleEndian.AppendUint32(p, uint32(sampleRateHz))
+	p = binary.LittleEndian.AppendUint32(p, uint32(sampleRateHz*blockAlign))
+	p = binary.LittleEndian.AppendUint16(p, uint16(blockAlign))
+	p = binary.LittleEndian.AppendUint16(p, 16)
+
+	p = append(p, "data"...)
+	p = binary.LittleEndian.AppendUint32(p, math.MaxUint32)
+
+	return p
+}
+
+// if w is an [io.WriteSeeker], the sizes in the header are filled in when the writer is closed
+func NewWAVEncoderWriter(w io.Writer, nChannels, sampleRateHz int) (EncoderWriter, error) {
+	if nChannels <= 0 || nChannels > math.MaxUint16 || sampleRateHz <= 0 {
+		return nil, ErrUnsupportedFormat
+	}
+
+	e := &wavEncoderWriter{w: w}
+
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			e.seeker = seeker
+			e.headerOffset = offset
+		}
+	}
+
+	_, err := w.Write(appendWAVHeader(nil, nChannels, sampleRateHz))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write wav header: %w", err)
+	}
+
+	return e, nil
+}
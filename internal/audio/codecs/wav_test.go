@@ -0,0 +1,61 @@
+package codecs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+)
+
+func TestWAVEncoderWriter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w, err := codecs.NewWAVEncoderWriter(f, 2, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm := codecs.S16LEToBytes([]int16{1, -1, 2, -2, 3, -3})
+
+	_, err = w.Write(pcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wav, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(wav) != 44+len(pcm) {
+		t.Fatalf("expected %d bytes, got %d", 44+len(pcm), len(wav))
+	}
+
+	if string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" || string(wav[36:40]) != "data" {
+		t.Errorf("invalid wav header %v", wav[:44])
+	}
+
+	if size := binary.LittleEndian.Uint32(wav[4:]); size != uint32(36+len(pcm)) {
+		t.Errorf("expected riff size %d, got %d", 36+len(pcm), size)
+	}
+
+	if size := binary.LittleEndian.Uint32(wav[40:]); size != uint32(len(pcm)) {
+		t.Errorf("expected data size %d, got %d", len(pcm), size)
+	}
+
+	if !bytes.Equal(wav[44:], pcm) {
+		t.Errorf("pcm was not written after the header")
+	}
+}
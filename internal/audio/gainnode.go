@@ -67,6 +67,15 @@ func (node *GainNode) Tick(ctx context.Context, ins []io.Reader, outs []io.Write
 	}
 }
 
+// changes the gain. must not be called while the node is ticking
+func (node *GainNode) SetFactor(factor float32) {
+	node.factor = factor
+}
+
+func (node *GainNode) Factor() float32 {
+	return node.factor
+}
+
 func (node *GainNode) Err() error {
 	return node.err
 }
@@ -53,8 +53,9 @@ func (node *MixerNode) Tick(ctx context.Context, ins []io.Reader, outs []io.Writ
 		if len(stream) > len(mixedStream) {
 			telemetry.Logger.DebugContext(ctx, "mixedStream buffer is too small. growing buffer", "oldcap", "newcap")
 
+			// grow can allocate more than was asked for, which would pad the output with silence
 			mixedStream = slices.Grow(mixedStream, len(stream)-len(mixedStream))
-			mixedStream = mixedStream[:cap(mixedStream)]
+			mixedStream = mixedStream[:len(stream)]
 		}
 
 		for sampleIdx, sample := range stream {
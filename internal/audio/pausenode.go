@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var _ Node = (*PauseNode)(nil)
+
+// wraps a node so that it can be paused. while paused, the wrapped node is not ticked, so it stops
+// consuming its inputs, and silence is written to every output instead. pausing and resuming take
+// effect on the next tick, so no samples are skipped or repeated
+type PauseNode struct {
+	logger *logging.Logger
+	err    error
+
+	node        Node
+	silenceSize int64
+	paused      atomic.Bool
+}
+
+func (node *PauseNode) Tick(ctx context.Context, ins []io.Reader, outs []io.Writer) {
+	ctx, span := telemetry.Tracer.Start(ctx, "PauseNode.Tick")
+	defer span.End()
+
+	node.err = nil
+
+	if !node.paused.Load() {
+		node.node.Tick(ctx, ins, outs)
+		node.err = node.node.Err()
+
+		return
+	}
+
+	silence := make([]byte, node.silenceSize)
+	errs := make([]error, 0)
+
+	for outIdx, out := range outs {
+		n, err := out.Write(silence)
+		telemetry.Logger.DebugContext(ctx, "PauseNode wrote silence to output", "output", outIdx, "n", n, "error", err)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to write silence to output %d: %w", outIdx, err))
+		}
+	}
+
+	node.err = errors.Join(errs...)
+}
+
+func (node *PauseNode) Err() error {
+	return node.err
+}
+
+func (node *PauseNode) Pause() {
+	node.paused.Store(true)
+}
+
+func (node *PauseNode) Resume() {
+	node.paused.Store(false)
+}
+
+func (node *PauseNode) IsPaused() bool {
+	return node.paused.Load()
+}
+
+// silenceSize is the number of bytes of silence that are written to each output per tick while paused
+func NewPauseNode(logger *logging.Logger, node Node, silenceSize int64) *PauseNode {
+	return &PauseNode{logger: logger, node: node, silenceSize: silenceSize}
+}
@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestPauseNode(t *testing.T) {
+	tracer, logger := telemetry.Tracer, telemetry.Logger
+	telemetry.Tracer = noop.NewTracerProvider().Tracer("test")
+	telemetry.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Cleanup(func() {
+		telemetry.Tracer, telemetry.Logger = tracer, logger
+	})
+
+	const tickSize = 4
+
+	// every sample is different so that a skipped or repeated sample shows up in the output
+	pcm := make([]byte, 3*tickSize)
+	for i := range pcm {
+		pcm[i] = byte(i + 1)
+	}
+
+	node := NewPauseNode(logging.NewLogger(), NewReaderNode(logging.NewLogger(), bytes.NewReader(pcm), tickSize), tickSize)
+
+	tick := func() []byte {
+		var out bytes.Buffer
+
+		node.Tick(context.Background(), nil, []io.Writer{&out})
+		if err := node.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		return out.Bytes()
+	}
+
+	if got := tick(); !bytes.Equal(got, pcm[:tickSize]) {
+		t.Errorf("expected the first tick to be played, got %v", got)
+	}
+
+	node.Pause()
+
+	for range 2 {
+		if got := tick(); !bytes.Equal(got, make([]byte, tickSize)) {
+			t.Errorf("expected silence while paused, got %v", got)
+		}
+	}
+
+	node.Resume()
+
+	// the samples that were not played while paused are played once resumed
+	if got := append(tick(), tick()...); !bytes.Equal(got, pcm[tickSize:]) {
+		t.Errorf("expected playback to resume from where it was paused, got %v", got)
+	}
+}
@@ -39,6 +39,11 @@ func (node *ReaderNode) Tick(ctx context.Context, ins []io.Reader, outs []io.Wri
 	telemetry.Logger.DebugContext(ctx, "ReaderNode copied data from reader to output", "n", n, "error", node.err)
 }
 
+// replaces the reader that the node reads from. must not be called while the node is ticking
+func (node *ReaderNode) SetReader(r io.Reader) {
+	node.r = r
+}
+
 func (node *ReaderNode) Err() error {
 	return node.err
 }
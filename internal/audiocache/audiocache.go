@@ -0,0 +1,372 @@
+package audiocache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+const (
+	entryFileExt     = ".dca"
+	tempEntryFileExt = ".tmp"
+)
+
+var ErrCacheMiss = errors.New("audio cache miss")
+
+type entry struct {
+	sizeBytes int64
+	lastUsed  time.Time
+}
+
+// an on-disk cache of encoded opus frames that evicts the least recently used entries once
+// the total size of all entries exceeds the maximum size
+type Cache struct {
+	sync.Mutex
+
+	logger       *logging.Logger
+	dir          string
+	maxSizeBytes int64
+	sizeBytes    int64
+	entries      map[string]*entry
+}
+
+// creates a key for an entry from all of the parameters that affect the encoded audio
+func Key(parts ...any) string {
+	h := sha256.New()
+
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x00", part)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// opens the entry for reading. the integrity of the entry is checked before it is returned and
+// corrupted entries are removed from the cache
+//
+// returns [ErrCacheMiss] if the entry does not exist
+func (c *Cache) Open(key string) (*Reader, error) {
+	c.Lock()
+	e, ok := c.entries[key]
+	c.Unlock()
+
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	// the whole file is read to verify it, so the cache is not locked in the meantime. an entry that
+	// is replaced or evicted while it is open stays readable until it is closed
+	r, err := c.openVerified(key)
+
+	c.Lock()
+	defer c.Unlock()
+
+	// only the entry that was verified is removed, not one that replaced it
+	current := c.entries[key] == e
+
+	if err != nil {
+		if current {
+			c.logger.Warn("removing corrupted audio cache entry", "key", key, "error", err)
+			c.removeLocked(key)
+		}
+
+		return nil, errors.Join(ErrCacheMiss, err)
+	}
+
+	if !current {
+		return r, nil
+	}
+
+	e.lastUsed = time.Now()
+
+	err = os.Chtimes(c.entryPath(key), time.Time{}, e.lastUsed)
+	if err != nil {
+		c.logger.Warn("failed to update modification time of audio cache entry", "key", key, "error", err)
+	}
+
+	return r, nil
+}
+
+func (c *Cache) openVerified(key string) (*Reader, error) {
+	f, err := os.Open(c.entryPath(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio cache entry: %w", err)
+	}
+
+	var numSamples uint64
+	var tags map[string]string
+
+	dca, err := codecs.NewDCAReader(f)
+	if err == nil {
+		err = dca.Verify()
+		numSamples = dca.EndTimestamp()
+		tags = dca.Tags()
+	}
+
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err == nil {
+		dca, err = codecs.NewDCAReader(f)
+	}
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Reader{DCAReader: dca, f: f, numSamples: numSamples, tags: tags}, nil
+}
+
+// creates a new entry. the entry is not visible to readers until [Writer.Commit] is called
+func (c *Cache) Create(key string, metadata codecs.DCAMetadata) (*Writer, error) {
+	f, err := os.CreateTemp(c.dir, key+"-*"+tempEntryFileExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary audio cache entry: %w", err)
+	}
+
+	bw := bufio.NewWriter(f)
+
+	dca, err := codecs.NewDCAWriter(bw, metadata)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return nil, err
+	}
+
+	return &Writer{cache: c, key: key, f: f, bw: bw, dca: dca}, nil
+}
+
+// removes an entry from the cache if it exists
+func (c *Cache) Remove(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.removeLocked(key)
+}
+
+// returns the total size of all entries in bytes
+func (c *Cache) SizeBytes() int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.sizeBytes
+}
+
+func (c *Cache) removeLocked(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+
+	err := os.Remove(c.entryPath(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.logger.Warn("failed to remove audio cache entry", "key", key, "error", err)
+	}
+
+	c.sizeBytes -= e.sizeBytes
+	delete(c.entries, key)
+}
+
+func (c *Cache) insert(key, tempPath string, sizeBytes int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if sizeBytes > c.maxSizeBytes {
+		os.Remove(tempPath)
+		return fmt.Errorf("audio cache entry of %d bytes exceeds the maximum cache size of %d bytes", sizeBytes, c.maxSizeBytes)
+	}
+
+	c.removeLocked(key)
+
+	err := os.Rename(tempPath, c.entryPath(key))
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to move audio cache entry into place: %w", err)
+	}
+
+	c.entries[key] = &entry{sizeBytes: sizeBytes, lastUsed: time.Now()}
+	c.sizeBytes += sizeBytes
+
+	c.evictLocked()
+
+	return nil
+}
+
+// removes the least recently used entries until the cache is within its size limit
+func (c *Cache) evictLocked() {
+	for c.sizeBytes > c.maxSizeBytes {
+		var oldestKey string
+		var oldest *entry
+
+		for key, e := range c.entries {
+			if oldest == nil || e.lastUsed.Before(oldest.lastUsed) {
+				oldestKey, oldest = key, e
+			}
+		}
+
+		if oldest == nil {
+			return
+		}
+
+		c.logger.Debug("evicting audio cache entry", "key", oldestKey, "sizeBytes", oldest.sizeBytes)
+		c.removeLocked(oldestKey)
+	}
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, key+entryFileExt)
+}
+
+type Reader struct {
+	*codecs.DCAReader
+	f *os.File
+
+	numSamples uint64
+	tags       map[string]string
+}
+
+// returns the length of the entry in samples per channel
+func (r *Reader) NumSamples() uint64 {
+	return r.numSamples
+}
+
+// returns the tags that the entry was committed with. unlike [codecs.DCAReader.Tags], they are
+// known before any packets have been read
+func (r *Reader) Tags() map[string]string {
+	return r.tags
+}
+
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
+
+type Writer struct {
+	sync.Mutex
+
+	cache *Cache
+	key   string
+	f     *os.File
+	bw    *bufio.Writer
+	dca   *codecs.DCAWriter
+	done  bool
+}
+
+// implements [codecs.PacketWriter]
+func (w *Writer) Write(p []codecs.Packet) (n int, err error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.done {
+		return 0, os.ErrClosed
+	}
+
+	return w.dca.Write(p)
+}
+
+// sets the tags that are stored with the entry when it is committed
+func (w *Writer) SetTags(tags map[string]string) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.dca.SetTags(tags)
+}
+
+// finishes the entry and makes it available to readers. does nothing if the entry has already
+// been committed or aborted
+func (w *Writer) Commit() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.done {
+		return nil
+	}
+
+	w.done = true
+
+	err := errors.Join(w.dca.Close(), w.bw.Flush(), w.f.Sync())
+
+	stat, statErr := w.f.Stat()
+	err = errors.Join(err, statErr, w.f.Close())
+
+	if err != nil {
+		os.Remove(w.f.Name())
+		return fmt.Errorf("failed to finish audio cache entry: %w", err)
+	}
+
+	return w.cache.insert(w.key, w.f.Name(), stat.Size())
+}
+
+// discards the entry. does nothing if the entry has already been committed or aborted
+func (w *Writer) Abort() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.done {
+		return
+	}
+
+	w.done = true
+
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+func New(logger *logging.Logger, dir string, maxSizeBytes int64) (*Cache, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio cache directory: %w", err)
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio cache directory: %w", err)
+	}
+
+	c := &Cache{
+		logger:       logger,
+		dir:          dir,
+		maxSizeBytes: maxSizeBytes,
+		entries:      make(map[string]*entry),
+	}
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+
+		switch {
+		case dirEntry.IsDir():
+			continue
+		case strings.HasSuffix(name, tempEntryFileExt):
+			// left behind by an entry that was never committed
+			os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, entryFileExt):
+			info, err := dirEntry.Info()
+			if err != nil {
+				logger.Warn("failed to stat audio cache entry", "name", name, "error", err)
+				continue
+			}
+
+			c.entries[strings.TrimSuffix(name, entryFileExt)] = &entry{sizeBytes: info.Size(), lastUsed: info.ModTime()}
+			c.sizeBytes += info.Size()
+		}
+	}
+
+	c.Lock()
+	c.evictLocked()
+	c.Unlock()
+
+	return c, nil
+}
@@ -0,0 +1,163 @@
+package audiocache_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/audiocache"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var metadata = codecs.DCAMetadata{Codec: codecs.CodecName_Opus, SampleRateHz: 48000, NumChannels: 2, FrameSize: 960}
+
+func writeEntry(t *testing.T, c *audiocache.Cache, key string, frames [][]byte) {
+	w, err := c.Create(key, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packets := make([]codecs.Packet, len(frames))
+	for i, frame := range frames {
+		packets[i] = codecs.Packet{Data: frame, SequenceNumber: uint32(i), Timestamp: uint64(i * metadata.FrameSize), NumSamples: metadata.FrameSize}
+	}
+
+	_, err = w.Write(packets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.SetTags(map[string]string{"key": key})
+
+	err = w.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	c, err := audiocache.New(logging.NewLogger(), t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Open("missing")
+	if !errors.Is(err, audiocache.ErrCacheMiss) {
+		t.Fatalf("expected ErrCacheMiss, got %v", err)
+	}
+
+	frames := [][]byte{{1, 2, 3}, {4, 5, 6}}
+	writeEntry(t, c, "key", frames)
+
+	r, err := c.Open("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.NumSamples() != uint64(len(frames)*metadata.FrameSize) {
+		t.Fatalf("incorrect number of samples. want %d, got %d", len(frames)*metadata.FrameSize, r.NumSamples())
+	}
+
+	if r.Tags()["key"] != "key" {
+		t.Fatalf("expected the tags of the entry to be known before reading it, got %v", r.Tags())
+	}
+
+	for idx, want := range frames {
+		packet, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(packet.Data, want) {
+			t.Fatalf("incorrect frame (idx = %d). want %v, got %v", idx, want, packet.Data)
+		}
+	}
+}
+
+func TestCacheAbortedEntryIsNotVisible(t *testing.T) {
+	c, err := audiocache.New(logging.NewLogger(), t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := c.Create("key", metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Abort()
+
+	_, err = c.Open("key")
+	if !errors.Is(err, audiocache.ErrCacheMiss) {
+		t.Fatalf("expected ErrCacheMiss, got %v", err)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	frame := make([]byte, 1000)
+
+	// large enough for two entries but not three
+	c, err := audiocache.New(logging.NewLogger(), t.TempDir(), 2500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeEntry(t, c, "a", [][]byte{frame})
+	writeEntry(t, c, "b", [][]byte{frame})
+
+	// use "a" so that "b" is the least recently used
+	r, err := c.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	writeEntry(t, c, "c", [][]byte{frame})
+
+	if _, err := c.Open("b"); !errors.Is(err, audiocache.ErrCacheMiss) {
+		t.Fatalf("expected least recently used entry to be evicted, got %v", err)
+	}
+
+	for _, key := range []string{"a", "c"} {
+		r, err := c.Open(key)
+		if err != nil {
+			t.Fatalf("expected entry %s to still be cached, got %v", key, err)
+		}
+		r.Close()
+	}
+}
+
+func TestCacheRemovesCorruptedEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := audiocache.New(logging.NewLogger(), dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeEntry(t, c, "key", [][]byte{make([]byte, 100)})
+
+	path := filepath.Join(dir, "key.dca")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, data[:len(data)-1], 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Open("key")
+	if !errors.Is(err, audiocache.ErrCacheMiss) {
+		t.Fatalf("expected ErrCacheMiss for corrupted entry, got %v", err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected corrupted entry to be deleted, got %v", err)
+	}
+}
@@ -2,17 +2,26 @@ package audiosession
 
 import (
 	"context"
+	"errors"
+	"io"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/config"
 	"accidentallycoded.com/fredboard/v3/internal/events"
-	"accidentallycoded.com/fredboard/v3/internal/syncext"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 )
 
-var allSessions = syncext.NewSyncData(make([]*Session, 0))
+var (
+	ErrSeekNotSupported = errors.New("input does not support seeking")
+	ErrSeekOutOfRange   = errors.New("seek position is past the end of the input")
+	ErrSessionDestroyed = errors.New("audio session has been destroyed")
+)
 
 type inputState byte
 
@@ -22,6 +31,24 @@ const (
 	inputState_Stopped
 )
 
+// describes what an input is playing
+type InputMetadata struct {
+	Title string
+
+	// where the audio comes from, such as a url or a path. empty if the audio is not from anywhere
+	SourceURL    string
+	ThumbnailURL string
+
+	// the total length of the input, if it is known
+	Duration optional.Optional[time.Duration]
+
+	// the discord user that asked for the input to be played. empty if it was not requested by a user
+	RequesterID string
+
+	// when the input was queued, or when it was created if it was not queued
+	EnqueuedAt time.Time
+}
+
 type Input interface {
 	Session() *Session
 
@@ -31,15 +58,30 @@ type Input interface {
 	// returns the current state of the input and its playback
 	State() inputState
 
-	// pauses playback
+	// pauses playback. the input outputs silence and stops reading from its source until it is resumed
 	Pause()
 
-	// resumes paused playback
+	// resumes paused playback from where it was paused
 	Resume()
 
 	// stops playback (cannot be resumed)
 	Stop()
 
+	// moves playback to position. returns [ErrSeekNotSupported] if the input cannot seek
+	Seek(position time.Duration) error
+
+	// returns how far into the input playback is
+	Position() time.Duration
+
+	// returns the total length of the input, if it is known
+	Duration() optional.Optional[time.Duration]
+
+	// returns the error that stopped the input, if it failed
+	Err() error
+
+	// returns what the input is playing. some of it may only be filled in once the input has started
+	Metadata() InputMetadata
+
 	// returns an event emitter that will broadcast when the input is stopped
 	OnStoppedEvent() *events.EventEmitter[struct{}]
 
@@ -48,41 +90,222 @@ type Input interface {
 }
 
 type BaseInput struct {
+	sync.Mutex
+
 	session        *Session
-	subgraph       audio.Node
+	subgraph       *audio.PauseNode
 	state          inputState
+	duration       optional.Optional[time.Duration]
 	onStoppedEvent *events.EventEmitter[struct{}]
+	err            error
+
+	// the duration is kept in duration instead
+	metadata InputMetadata
+
+	// the input that embeds this, which is set once it is added to the session. events describe it
+	self Input
+
+	// set once the first audio has been read
+	started atomic.Bool
+
+	// number of bytes of pcm that have been played, including the position that was seeked to
+	positionBytes atomic.Int64
+
+	// wraps the subgraph if the input was scheduled to start part way through a tick. only set by
+	// delayStart before the input is added to its session, so it is read without the lock
+	delay *delayNode
 }
 
-func (i BaseInput) Session() *Session {
+func (i *BaseInput) Session() *Session {
 	return i.session
 }
 
-func (i BaseInput) Subgraph() audio.Node {
+// the node that is connected to the mixer of the session. it does not change once the input has
+// been added to the session
+func (i *BaseInput) Subgraph() audio.Node {
+	if i.delay != nil {
+		return i.delay
+	}
+
 	return i.subgraph
 }
 
-func (i BaseInput) State() inputState {
+func (i *BaseInput) State() inputState {
+	i.Lock()
+	defer i.Unlock()
+
 	return i.state
 }
 
 func (i *BaseInput) Pause() {
+	i.Lock()
+
+	if i.state != inputState_Running {
+		i.Unlock()
+		return
+	}
+
 	i.state = inputState_Paused
+	i.subgraph.Pause()
+	i.Unlock()
+
+	i.publish(func(h InputEventHeader) SessionEvent { return InputEvent_Paused{h} })
 }
 
 func (i *BaseInput) Resume() {
+	i.Lock()
+
+	if i.state != inputState_Paused {
+		i.Unlock()
+		return
+	}
+
 	i.state = inputState_Running
+	i.subgraph.Resume()
+	i.Unlock()
+
+	i.publish(func(h InputEventHeader) SessionEvent { return InputEvent_Resumed{h} })
 }
 
+// stops the input. the stopped event is only broadcast the first time that the input is stopped
 func (i *BaseInput) Stop() {
+	i.Lock()
+
+	if i.state == inputState_Stopped {
+		i.Unlock()
+		return
+	}
+
 	i.state = inputState_Stopped
+	i.Unlock()
+
 	i.onStoppedEvent.Broadcast(struct{}{})
 }
 
+// stops the input because of err and reports the error on the session's event bus. the error is
+// still reported if the input has already stopped, since the end of its audio is usually noticed
+// before the failure of the process that produced it. only the first error is kept
+func (i *BaseInput) fail(err error) {
+	i.Lock()
+
+	if i.err != nil {
+		i.Unlock()
+		return
+	}
+
+	i.err = err
+	i.Unlock()
+
+	i.publish(func(h InputEventHeader) SessionEvent { return InputEvent_Errored{InputEventHeader: h, Err: err} })
+	i.Stop()
+}
+
+// stops the input once its pcm has run out. an error other than [io.EOF] fails the input. pcm is
+// read from within a tick while the session is locked, so the input is stopped asynchronously
+func (i *BaseInput) endAsync(err error) {
+	if err != nil && err != io.EOF {
+		go i.fail(err)
+	} else {
+		go i.Stop()
+	}
+}
+
+// reports that the input has run out of audio and is waiting for its source, or that it has caught up
+func (i *BaseInput) setBuffering(buffering bool) {
+	i.publish(func(h InputEventHeader) SessionEvent {
+		return InputEvent_Buffering{InputEventHeader: h, IsBuffering: buffering}
+	})
+}
+
+func (i *BaseInput) Err() error {
+	i.Lock()
+	defer i.Unlock()
+
+	return i.err
+}
+
+func (i *BaseInput) Seek(position time.Duration) error {
+	return ErrSeekNotSupported
+}
+
+func (i *BaseInput) Position() time.Duration {
+	return pcmBytesToDuration(i.positionBytes.Load())
+}
+
+func (i *BaseInput) Duration() optional.Optional[time.Duration] {
+	i.Lock()
+	defer i.Unlock()
+
+	return i.duration
+}
+
+func (i *BaseInput) Metadata() InputMetadata {
+	i.Lock()
+	defer i.Unlock()
+
+	metadata := i.metadata
+	metadata.Duration = i.duration
+
+	return metadata
+}
+
+func (i *BaseInput) updateMetadata(update func(metadata *InputMetadata)) {
+	i.Lock()
+	defer i.Unlock()
+
+	update(&i.metadata)
+}
+
+func (i *BaseInput) setDuration(duration time.Duration) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.duration = optional.Make(duration)
+}
+
+func (i *BaseInput) setPosition(position time.Duration) {
+	i.positionBytes.Store(durationToPCMBytes(position))
+}
+
+// wraps the pcm source of the input so that the position advances as the source is read
+func (i *BaseInput) trackPosition(r io.Reader) io.Reader {
+	return &positionReader{r: r, onRead: i.advance}
+}
+
+// advances the position by n bytes of pcm that were played
+func (i *BaseInput) advance(n int) {
+	i.positionBytes.Add(int64(n))
+
+	if n > 0 && i.started.CompareAndSwap(false, true) {
+		i.publish(func(h InputEventHeader) SessionEvent { return InputEvent_Started{h} })
+	}
+}
+
+// publishes the event returned by newEvent. does nothing until the input has been added to its session
+func (i *BaseInput) publish(newEvent func(h InputEventHeader) SessionEvent) {
+	if i.self == nil {
+		return
+	}
+
+	i.session.Events.publish(newEvent(newInputEventHeader(i.self)))
+}
+
 func (i *BaseInput) OnStoppedEvent() *events.EventEmitter[struct{}] {
 	return i.onStoppedEvent
 }
 
+// calls f once the input has stopped, or straight away if it already has. an input can stop from
+// its own goroutines before anything waiting for it is set up, which a delegate alone would miss
+func (i *BaseInput) whenStopped(f func()) {
+	var once sync.Once
+
+	i.onStoppedEvent.AddDelegate(func(struct{}) { once.Do(f) })
+
+	if i.State() == inputState_Stopped {
+		once.Do(f)
+	}
+}
+
 func (i *BaseInput) asBase() *BaseInput {
 	return i
 }
@@ -91,12 +314,15 @@ func (i *BaseInput) Equals(rhs Input) bool {
 	return i == rhs.asBase()
 }
 
+// the subgraph is wrapped so that it can be paused
 func NewBaseInput(session *Session, subgraph audio.Node) *BaseInput {
 	return &BaseInput{
 		session:        session,
-		subgraph:       subgraph,
+		subgraph:       audio.NewPauseNode(session.logger, subgraph, tickSizeBytes()),
 		state:          inputState_Running,
+		duration:       optional.None[time.Duration](),
 		onStoppedEvent: events.NewEventEmitter[struct{}](),
+		metadata:       InputMetadata{EnqueuedAt: time.Now()},
 	}
 }
 
@@ -104,6 +330,9 @@ type Output interface {
 	Session() *Session
 	Subgraph() audio.Node
 
+	// flushes and releases the resources of the output. called once the output has been removed from its session
+	Close() error
+
 	Equals(rhs Output) bool
 	asBase() *BaseOutput
 }
@@ -121,6 +350,10 @@ func (o BaseOutput) Subgraph() audio.Node {
 	return o.subgraph
 }
 
+func (o *BaseOutput) Close() error {
+	return nil
+}
+
 func (o *BaseOutput) asBase() *BaseOutput {
 	return o
 }
@@ -129,6 +362,18 @@ func (i *BaseOutput) Equals(rhs Output) bool {
 	return i == rhs.asBase()
 }
 
+// implemented by outputs that only follow what the players of a session are playing, like http
+// streams and recordings. they do not keep a session alive once its last player leaves
+type followerOutput interface {
+	Output
+	followsPlayers()
+}
+
+func isPlayerOutput(o Output) bool {
+	_, ok := o.(followerOutput)
+	return !ok
+}
+
 func NewBaseOutput(session *Session, subgraph audio.Node) *BaseOutput {
 	return &BaseOutput{
 		session:  session,
@@ -136,6 +381,10 @@ func NewBaseOutput(session *Session, subgraph audio.Node) *BaseOutput {
 	}
 }
 
+type SessionEvent_OnInputAdded struct {
+	InputAdded Input
+}
+
 type SessionEvent_OnInputRemoved struct {
 	InputRemoved     Input
 	NInputsRemaining int
@@ -156,36 +405,92 @@ const (
 type Session struct {
 	sync.Mutex
 
+	id         string
 	logger     *logging.Logger
 	inputs     []Input
 	outputs    []Output
 	rootMixer  *audio.MixerNode
+	volumeNode *audio.GainNode
+	rootTee    *audio.TeeNode
+	clock      *playbackClock
 	audioGraph *audio.Graph
 	state      SessionState
+	queue      *Queue
+	destroyed  bool
+
+	// lets outputs send cached opus packets without encoding them again. reset every tick
+	passthrough passthroughTick
+
+	// inputs that are waiting to start, and the silence that keeps the session ticking until they do
+	schedules       []*ScheduledInput
+	schedulePadding audio.Node
+	schedulePadded  bool
+
+	// closed once the session has been destroyed
+	destroyedChan chan struct{}
+
+	// tracks the tick loop and any child processes of the inputs so that shutdown can wait for them
+	running sync.WaitGroup
+
+	// serializes saving and removing the snapshot of the session
+	snapshotMu sync.Mutex
 
+	// lets other guilds join the session. empty until the session is shared
+	shareToken string
+
+	OnInputAdded    *events.EventEmitter[SessionEvent_OnInputAdded]
 	OnInputRemoved  *events.EventEmitter[SessionEvent_OnInputRemoved]
 	OnOutputRemoved *events.EventEmitter[SessionEvent_OnOutputRemoved]
+	OnDestroyed     *events.EventEmitter[struct{}]
+
+	// every playback event of the session, in order
+	Events *SessionEventBus
+}
+
+// the id of the owner of the session, such as a discord guild id
+func (s *Session) ID() string {
+	return s.id
 }
 
 func (s *Session) AddInput(input Input) {
 	s.Lock()
-	defer s.Unlock()
+	s.addInputLocked(input)
+	s.Unlock()
+
+	s.onInputAdded(input)
+}
+
+func (s *Session) addInputLocked(input Input) {
+	input.asBase().self = input
 
 	s.audioGraph.AddNode(input.Subgraph())
 	s.audioGraph.CreateConnection(input.Subgraph(), s.rootMixer)
 	s.inputs = append(s.inputs, input)
 }
 
+// must be called without the session locked
+func (s *Session) onInputAdded(input Input) {
+	s.OnInputAdded.Broadcast(SessionEvent_OnInputAdded{InputAdded: input})
+	s.Events.publish(InputEvent_Added{newInputEventHeader(input)})
+}
+
+// does nothing if the input is not in the session
 func (s *Session) RemoveInput(input Input) {
-	func() {
-		s.Lock()
-		defer s.Unlock()
+	s.Lock()
 
-		s.inputs = slices.DeleteFunc(s.inputs, func(i Input) bool { return i.Equals(input) })
-		s.audioGraph.RemoveNode(input.Subgraph())
-	}()
+	if !slices.ContainsFunc(s.inputs, func(i Input) bool { return i.Equals(input) }) {
+		s.Unlock()
+		return
+	}
+
+	s.inputs = slices.DeleteFunc(s.inputs, func(i Input) bool { return i.Equals(input) })
+	s.audioGraph.RemoveNode(input.Subgraph())
+	nInputsRemaining := len(s.inputs)
 
-	s.OnInputRemoved.Broadcast(SessionEvent_OnInputRemoved{InputRemoved: input, NInputsRemaining: len(s.inputs)})
+	s.Unlock()
+
+	s.OnInputRemoved.Broadcast(SessionEvent_OnInputRemoved{InputRemoved: input, NInputsRemaining: nInputsRemaining})
+	s.Events.publish(InputEvent_Finished{InputEventHeader: newInputEventHeader(input), NInputsRemaining: nInputsRemaining})
 }
 
 func (s *Session) Inputs() []Input {
@@ -202,21 +507,80 @@ func (s *Session) AddOutput(output Output) {
 	s.Lock()
 	defer s.Unlock()
 
+	s.addOutputLocked(output)
+}
+
+// adds output unless the session has been destroyed, which has already removed its outputs
+func (s *Session) tryAddOutput(output Output) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.destroyed {
+		return ErrSessionDestroyed
+	}
+
+	s.addOutputLocked(output)
+
+	return nil
+}
+
+func (s *Session) addOutputLocked(output Output) {
 	s.audioGraph.AddNode(output.Subgraph())
-	s.audioGraph.CreateConnection(s.rootMixer, output.Subgraph())
+	s.audioGraph.CreateConnection(s.rootTee, output.Subgraph())
 	s.outputs = append(s.outputs, output)
+
+	s.Events.publish(OutputEvent_Added{SessionEventHeader: newSessionEventHeader(s), Output: output})
 }
 
+// does nothing if the output is not in the session. the session is destroyed once its last player
+// is removed. http streams and recordings are not players, they only follow the session while
+// something else is playing it
 func (s *Session) RemoveOutput(output Output) {
-	func() {
-		s.Lock()
-		defer s.Unlock()
+	s.Lock()
 
-		s.outputs = slices.DeleteFunc(s.outputs, func(o Output) bool { return o.Equals(output) })
-		s.audioGraph.RemoveNode(output.Subgraph())
-	}()
+	if !slices.ContainsFunc(s.outputs, func(o Output) bool { return o.Equals(output) }) {
+		s.Unlock()
+		return
+	}
+
+	s.outputs = slices.DeleteFunc(s.outputs, func(o Output) bool { return o.Equals(output) })
+	s.audioGraph.RemoveNode(output.Subgraph())
+	nOutputsRemaining := len(s.outputs)
+
+	nPlayersRemaining := 0
+	for _, o := range s.outputs {
+		if isPlayerOutput(o) {
+			nPlayersRemaining++
+		}
+	}
+
+	s.Unlock()
+
+	// the output is no longer in the graph, so it is safe to close without waiting for a tick to finish
+	err := output.Close()
+	if err != nil {
+		s.logger.Warn("failed to close audio session output", "id", s.id, "error", err)
+	}
+
+	s.OnOutputRemoved.Broadcast(SessionEvent_OnOutputRemoved{OutputRemoved: output, NOutputsRemaining: nOutputsRemaining})
+	s.Events.publish(OutputEvent_Removed{SessionEventHeader: newSessionEventHeader(s), Output: output, NOutputsRemaining: nOutputsRemaining})
+
+	if nPlayersRemaining == 0 {
+		s.logger.Debug("destroying audio session because its last output was removed", "id", s.id)
+		s.Destroy()
+	}
+}
 
-	s.OnOutputRemoved.Broadcast(SessionEvent_OnOutputRemoved{OutputRemoved: output, NOutputsRemaining: len(s.outputs)})
+// removes output once the last input of the session finishes, which is how the bot leaves a voice
+// channel once there is nothing left to play
+func (s *Session) RemoveOutputWhenInputsRunOut(output Output) {
+	s.Events.Subscribe(func(event SessionEvent) {
+		// events are delivered asynchronously, so another input may have been added since
+		if finished, ok := event.(InputEvent_Finished); ok && finished.NInputsRemaining == 0 && len(s.Inputs()) == 0 {
+			s.logger.Debug("removing output due to all inputs to the audio session being removed", "id", s.id, "output", output)
+			s.RemoveOutput(output)
+		}
+	})
 }
 
 func (s *Session) Outputs() []Output {
@@ -229,7 +593,30 @@ func (s *Session) Outputs() []Output {
 	return outputs
 }
 
+// sets the volume of everything that the session plays, where 1 leaves the audio unchanged
+func (s *Session) SetVolume(volume float64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.volumeNode.SetFactor(float32(volume))
+}
+
+func (s *Session) Volume() float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return float64(s.volumeNode.Factor())
+}
+
+// returns the queue of tracks that are played one after another
+func (s *Session) Queue() *Queue {
+	return s.queue
+}
+
 func (s *Session) State() SessionState {
+	s.Lock()
+	defer s.Unlock()
+
 	return s.state
 }
 
@@ -239,60 +626,170 @@ func (s *Session) StartTicking() {
 
 	processTick := func() /*continue*/ bool {
 		s.Lock()
-		defer s.Unlock()
 
-		if len(s.inputs) == 0 {
+		if (len(s.inputs) == 0 && len(s.schedules) == 0) || s.destroyed {
+			s.Unlock()
 			return false
 		}
 
+		tickStart := s.clock.sampleIndex()
+		activated := s.activateSchedulesLocked()
+		s.beginPassthroughTickLocked()
 		s.audioGraph.Tick(ctx)
+		s.passthrough = passthroughTick{}
+
+		s.Unlock()
+
+		s.publishScheduledStarts(activated, tickStart)
+
 		return true
 	}
 
 	s.Lock()
-	if s.state == SessionState_Ticking {
+	if s.state == SessionState_Ticking || s.destroyed {
 		s.Unlock()
 		return
 	}
 
 	s.state = SessionState_Ticking
+	s.clock.reset()
+	s.running.Add(1)
+	s.Events.publish(SessionEvent_TickingStarted{newSessionEventHeader(s)})
 	s.Unlock()
 
-	for {
-		if !processTick() {
-			s.Lock()
-			s.state = SessionState_NotTicking
-			s.Unlock()
+	defer s.running.Done()
 
-			break
-		}
+	lead := playbackLead()
+
+	for processTick() {
+		s.clock.wait(lead)
 	}
+
+	s.Lock()
+	s.state = SessionState_NotTicking
+	s.Events.publish(SessionEvent_TickingStopped{newSessionEventHeader(s)})
+	s.Unlock()
 }
 
-func New(logger *logging.Logger) *Session {
+// stops all inputs, removes all outputs and removes the session from the registry. does nothing if
+// the session has already been destroyed
+func (s *Session) Destroy() {
+	s.Lock()
+
+	if s.destroyed {
+		s.Unlock()
+		return
+	}
+
+	s.destroyed = true
+	close(s.destroyedChan)
+	inputs := slices.Clone(s.inputs)
+	outputs := slices.Clone(s.outputs)
+
+	s.Unlock()
+
+	unregister(s)
+
+	// sessions that are destroyed by a shutdown are restored on the next start
+	if !shuttingDown.Load() {
+		s.removeSnapshot()
+	}
+
+	// clear the queue first so that stopping the current track does not start the next one
+	s.queue.Clear()
+	s.cancelSchedules()
+
+	for _, output := range outputs {
+		s.RemoveOutput(output)
+	}
+
+	for _, input := range inputs {
+		input.Stop()
+		s.RemoveInput(input)
+	}
+
+	s.OnDestroyed.Broadcast(struct{}{})
+}
+
+// counts a goroutine that [Session.Wait] waits for. fails once the session has been destroyed,
+// since Wait may already be waiting and would miss a goroutine that is started now
+func (s *Session) addRunning() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.destroyed {
+		return ErrSessionDestroyed
+	}
+
+	s.running.Add(1)
+
+	return nil
+}
+
+// waits for the tick loop and any child processes of the inputs to finish, then for the events
+// that they published to be delivered. only returns once the session has been destroyed and all of
+// its work has stopped
+func (s *Session) Wait() {
+	<-s.destroyedChan
+	s.running.Wait()
+	s.Events.wait()
+}
+
+func (s *Session) IsDestroyed() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.destroyed
+}
+
+func newSession(logger *logging.Logger, id string) *Session {
 	rootMixer := audio.NewMixerNode(logger)
+	volumeNode := audio.NewGainNode(logger, 1)
+
+	// the mixer can only have one output, so every output is connected to the tee instead
+	rootTee := audio.NewTeeNode(logger)
+
+	// the clock is always connected to the tee, so the session plays in real time even without outputs
+	clock := &playbackClock{}
+	clockNode := audio.NewWriterNode(logger, clock)
 
 	audioGraph := audio.NewGraph(logger)
 	audioGraph.AddNode(rootMixer)
+	audioGraph.AddNode(volumeNode)
+	audioGraph.AddNode(rootTee)
+	audioGraph.AddNode(clockNode)
+	audioGraph.CreateConnection(rootMixer, volumeNode)
+	audioGraph.CreateConnection(volumeNode, rootTee)
+	audioGraph.CreateConnection(rootTee, clockNode)
 
 	audioSession := Session{
+		id:         id,
 		logger:     logger,
 		inputs:     make([]Input, 0),
 		outputs:    make([]Output, 0),
 		rootMixer:  rootMixer,
-		audioGraph: audio.NewGraph(logger),
+		volumeNode: volumeNode,
+		rootTee:    rootTee,
+		clock:      clock,
+		audioGraph: audioGraph,
 		state:      SessionState_NotTicking,
 
+		destroyedChan: make(chan struct{}),
+
+		OnInputAdded:    events.NewEventEmitter[SessionEvent_OnInputAdded](),
 		OnInputRemoved:  events.NewEventEmitter[SessionEvent_OnInputRemoved](),
 		OnOutputRemoved: events.NewEventEmitter[SessionEvent_OnOutputRemoved](),
+		OnDestroyed:     events.NewEventEmitter[struct{}](),
 	}
 
-	func() {
-		allSessions.Lock()
-		defer allSessions.Unlock()
+	audioSession.schedulePadding = newSchedulePadding(&audioSession)
+	audioSession.Events = newSessionEventBus()
+	audioSession.queue = newQueue(&audioSession)
 
-		allSessions.Data = append(allSessions.Data, &audioSession)
-	}()
+	if config.Get().Snapshot.Directory.IsSet() {
+		audioSession.running.Add(1)
+		go audioSession.saveSnapshotsPeriodically()
+	}
 
 	return &audioSession
 }
@@ -0,0 +1,302 @@
+package audiosession
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/audiocache"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var (
+	audioCacheOnce sync.Once
+	audioCache     *audiocache.Cache
+)
+
+// gets the shared audio cache. returns nil if caching is disabled or the cache could not be created
+func getAudioCache(logger *logging.Logger) *audiocache.Cache {
+	audioCacheOnce.Do(func() {
+		cfg := config.Get().Cache
+		if !cfg.Directory.IsSet() {
+			return
+		}
+
+		c, err := audiocache.New(logger, cfg.Directory.Get(), cfg.MaxSizeBytes)
+		if err != nil {
+			logger.Warn("failed to create audio cache. caching is disabled", "error", err)
+			return
+		}
+
+		audioCache = c
+	})
+
+	return audioCache
+}
+
+// the parameters of the encoded audio that is stored in the cache
+func audioCacheMetadata() codecs.DCAMetadata {
+	return codecs.DCAMetadata{
+		Codec:        config.Get().Audio.CacheCodec,
+		SampleRateHz: config.Get().Audio.SampleRateHz,
+		NumChannels:  config.Get().Audio.NumChannels,
+		FrameSize:    opusFrameSize,
+	}
+}
+
+// opens a cache entry and decodes it to 16-bit signed little endian PCM, starting at offset. the
+// opus packets are tracked as they are decoded so that they can be passed through
+//
+// returns [audiocache.ErrCacheMiss] if the entry does not exist
+func openCachedPCM(cache *audiocache.Cache, key string, offset time.Duration) (*cachedPCMReader, error) {
+	entry, err := cache.Open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := audioCacheMetadata()
+	if entry.Metadata().SampleRateHz != metadata.SampleRateHz || entry.Metadata().NumChannels != metadata.NumChannels {
+		entry.Close()
+		cache.Remove(key)
+
+		return nil, errors.Join(audiocache.ErrCacheMiss, fmt.Errorf("cached audio parameters %+v do not match %+v", entry.Metadata(), metadata))
+	}
+
+	// entries are decoded with the codec that they were encoded with, which may not be the
+	// currently configured codec
+	codec, err := codecs.Lookup(entry.Metadata().Codec)
+	if err != nil {
+		entry.Close()
+		cache.Remove(key)
+
+		return nil, errors.Join(audiocache.ErrCacheMiss, err)
+	}
+
+	offsetSamples := uint64(durationToSamples(offset))
+	if offsetSamples > entry.NumSamples() {
+		entry.Close()
+		return nil, ErrSeekOutOfRange
+	}
+
+	packets, err := skipPackets(entry, offsetSamples)
+	if err != nil {
+		entry.Close()
+		return nil, fmt.Errorf("failed to seek cached audio: %w", err)
+	}
+
+	track := newOpusPacketTrack(packets.firstTimestamp, offset)
+
+	decoder, err := codec.NewDecoder(packets, codecs.DecoderOptions{
+		NumChannels:  metadata.NumChannels,
+		SampleRateHz: metadata.SampleRateHz,
+		OnPacket:     track.add,
+	})
+
+	if err != nil {
+		entry.Close()
+		return nil, err
+	}
+
+	// the first packet may start before the offset
+	var nSkippedSamples uint64
+	if offsetSamples > packets.firstTimestamp {
+		nSkippedSamples = offsetSamples - packets.firstTimestamp
+	}
+
+	_, err = io.CopyN(io.Discard, decoder, int64(nSkippedSamples)*pcmSampleSizeBytes())
+	if err != nil && err != io.EOF {
+		entry.Close()
+		return nil, fmt.Errorf("failed to seek cached audio: %w", err)
+	}
+
+	return &cachedPCMReader{Reader: decoder, entry: entry, packets: track}, nil
+}
+
+type cachedPCMReader struct {
+	io.Reader
+	entry   *audiocache.Reader
+	packets *opusPacketTrack
+}
+
+// returns the length of the cached audio in samples per channel
+func (r *cachedPCMReader) NumSamples() uint64 {
+	return r.entry.NumSamples()
+}
+
+// returns the tags that the audio was cached with
+func (r *cachedPCMReader) Tags() map[string]string {
+	return r.entry.Tags()
+}
+
+func (r *cachedPCMReader) Close() error {
+	return r.entry.Close()
+}
+
+// a packet reader that starts at the packet that contains a timestamp
+type skippedPacketReader struct {
+	r       codecs.PacketReader
+	pending optional.Optional[codecs.Packet]
+
+	// timestamp of the first packet that was not skipped
+	firstTimestamp uint64
+}
+
+func (r *skippedPacketReader) ReadPacket() (codecs.Packet, error) {
+	if r.pending.IsSet() {
+		packet := r.pending.Get()
+		r.pending.Unset()
+
+		return packet, nil
+	}
+
+	return r.r.ReadPacket()
+}
+
+// skips the packets that end before timestamp. packets without timing information are never
+// skipped since they belong to codecs that cannot be decoded from the middle of the stream
+func skipPackets(r codecs.PacketReader, timestamp uint64) (*skippedPacketReader, error) {
+	for {
+		packet, err := r.ReadPacket()
+
+		switch {
+		case err == io.EOF:
+			return &skippedPacketReader{r: r, firstTimestamp: timestamp}, nil
+		case err != nil:
+			return nil, err
+		case packet.NumSamples == 0 || packet.EndTimestamp() > timestamp:
+			return &skippedPacketReader{r: r, pending: optional.Make(packet), firstTimestamp: packet.Timestamp}, nil
+		}
+	}
+}
+
+// passes through pcm data while also encoding it into a cache entry. the entry is only committed
+// once the reader has reached EOF and the process that produced the pcm has exited successfully,
+// since a process that fails can still end its output cleanly
+type cachingReader struct {
+	sync.Mutex
+
+	logger *logging.Logger
+
+	r     io.Reader
+	enc   codecs.EncoderWriter
+	entry *audiocache.Writer
+
+	// returns the tags that the entry is committed with
+	tags func() map[string]string
+
+	eof      bool
+	exitedOK bool
+	done     bool
+}
+
+func (r *cachingReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.done {
+		return n, err
+	}
+
+	if n > 0 {
+		_, encErr := r.enc.Write(p[:n])
+		if encErr != nil {
+			r.logger.Warn("failed to encode audio for audio cache", "error", encErr)
+			r.abortLocked()
+
+			return n, err
+		}
+	}
+
+	switch {
+	case err == io.EOF:
+		r.eof = true
+
+		flushErr := r.enc.Flush()
+		if flushErr != nil {
+			r.logger.Warn("failed to encode audio for audio cache", "error", flushErr)
+			r.abortLocked()
+
+			return n, err
+		}
+
+		r.commitIfCompleteLocked()
+	case err != nil:
+		r.abortLocked()
+	}
+
+	return n, err
+}
+
+// called once the process that produced the pcm has exited. the entry is discarded if it failed
+func (r *cachingReader) Finish(ok bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if !ok {
+		r.abortLocked()
+		return
+	}
+
+	r.exitedOK = true
+	r.commitIfCompleteLocked()
+}
+
+// discards the entry unless all of the pcm has already been read, in which case the entry is left
+// for [cachingReader.Finish] to commit or discard
+func (r *cachingReader) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.eof {
+		r.abortLocked()
+	}
+}
+
+func (r *cachingReader) commitIfCompleteLocked() {
+	if r.done || !r.eof || !r.exitedOK {
+		return
+	}
+
+	r.done = true
+	r.entry.SetTags(r.tags())
+
+	err := r.entry.Commit()
+	if err != nil {
+		r.logger.Warn("failed to commit audio cache entry", "error", err)
+	}
+}
+
+func (r *cachingReader) abortLocked() {
+	r.done = true
+	r.entry.Abort()
+}
+
+func newCachingReader(logger *logging.Logger, r io.Reader, cache *audiocache.Cache, key string, tags func() map[string]string) (*cachingReader, error) {
+	metadata := audioCacheMetadata()
+
+	entry, err := cache.Create(key, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := codecs.Lookup(metadata.Codec)
+	if err != nil {
+		entry.Abort()
+		return nil, err
+	}
+
+	enc, err := codec.NewEncoder(entry, codecs.EncoderOptions{NumChannels: metadata.NumChannels, SampleRateHz: metadata.SampleRateHz, FrameSize: metadata.FrameSize})
+	if err != nil {
+		entry.Abort()
+		return nil, err
+	}
+
+	return &cachingReader{logger: logger, r: r, enc: enc, entry: entry, tags: tags}, nil
+}
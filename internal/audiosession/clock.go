@@ -0,0 +1,84 @@
+package audiosession
+
+import (
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+// keeps the session playing in real time. outputs never hold up the tick, so without the clock the
+// session would play its inputs as fast as they can be read
+type playbackClock struct {
+	mu      sync.Mutex
+	start   time.Time
+	nPlayed int64 // bytes of pcm that have been played since start
+
+	// bytes of pcm that have been played since the session was created, which is never reset
+	nTotal int64
+
+	// the sample index that was due at start
+	startIndex int64
+}
+
+// counts the pcm that is played by the session. called from within a tick
+func (c *playbackClock) Write(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nPlayed += int64(len(p))
+	c.nTotal += int64(len(p))
+
+	return len(p), nil
+}
+
+func (c *playbackClock) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.start = time.Now()
+	c.nPlayed = 0
+	c.startIndex = c.nTotal / pcmSampleSizeBytes()
+}
+
+// returns the index of the next sample that the session will play
+func (c *playbackClock) sampleIndex() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.nTotal / pcmSampleSizeBytes()
+}
+
+// returns the index of the sample that is due at t. the clock must have been reset since the
+// session last started ticking
+func (c *playbackClock) sampleIndexAt(t time.Time) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.startIndex + durationToSamples(t.Sub(c.start))
+}
+
+// sleeps until the session is no more than lead ahead of real time. a session that has fallen
+// behind carries on from where it is instead of rushing to catch up
+func (c *playbackClock) wait(lead time.Duration) {
+	c.mu.Lock()
+
+	elapsed := time.Since(c.start)
+	played := pcmBytesToDuration(c.nPlayed)
+
+	if played < elapsed {
+		c.start = time.Now().Add(-played)
+	}
+
+	c.mu.Unlock()
+
+	if ahead := played - elapsed; ahead > lead {
+		time.Sleep(ahead - lead)
+	}
+}
+
+// how far ahead of real time the session plays. half of the output buffer is left for the outputs
+// to fall behind before they start dropping audio
+func playbackLead() time.Duration {
+	return time.Duration(config.Get().Audio.OutputBufferMs) * time.Millisecond / 2
+}
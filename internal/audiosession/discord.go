@@ -3,47 +3,271 @@ package audiosession
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"accidentallycoded.com/fredboard/v3/internal/audio"
 	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
 	"accidentallycoded.com/fredboard/v3/internal/config"
-	"accidentallycoded.com/fredboard/v3/internal/ioext"
+	"accidentallycoded.com/fredboard/v3/internal/events"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 	"github.com/bwmarrin/discordgo"
 )
 
-var ErrOutputNotFound = errors.New("audio session output not found")
+// TODO: move to config file
+const opusFrameSize = 960
+
+// the length of an opus frame at 48kHz, which is the only sample rate that discord accepts
+const opusFrameDuration = opusFrameSize * time.Second / 48000
+
+// how long to wait for the last opus frames to be sent to discord when an output is closed
+const discordFlushTimeout = time.Second
+
+// how often a voice connection checks whether it has been idle for too long
+const discordIdleCheckInterval = time.Second
+
+var (
+	ErrOutputNotFound = errors.New("audio session output not found")
+	ErrOutputClosed   = errors.New("audio session output is closed")
+)
 
 type DiscordVoiceConnOutput struct {
 	*BaseOutput
 	Conn *discordgo.VoiceConnection
+
+	queue      *outputQueue
+	encoder    codecs.EncoderWriter
+	sender     *discordOpusWriter
+	disconnect func() error
+
+	// unix nanoseconds of when audio was last played or an input was added
+	lastActive atomic.Int64
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	eventsHandle events.DelegateHandle
+}
+
+// returns how much audio was not sent to discord because the voice connection fell behind
+func (o *DiscordVoiceConnOutput) Dropped() time.Duration {
+	return o.queue.dropped()
+}
+
+// returns how many opus frames have been sent to discord, dropped and sent late
+func (o *DiscordVoiceConnOutput) SendStats() DiscordSendStats {
+	return o.sender.stats()
+}
+
+// flushes the opus encoder so that the end of the audio is sent to discord, then leaves the voice
+// channel
+func (o *DiscordVoiceConnOutput) Close() error {
+	err := o.release()
+
+	disconnectErr := o.disconnect()
+	if disconnectErr != nil {
+		disconnectErr = fmt.Errorf("failed to disconnect discord voice connection: %w", disconnectErr)
+	}
+
+	return errors.Join(err, disconnectErr)
+}
+
+// flushes what is still queued and stops sending to discord without leaving the voice channel
+func (o *DiscordVoiceConnOutput) release() error {
+	o.session.Events.Unsubscribe(o.eventsHandle)
+	o.closeOnce.Do(func() { close(o.closed) })
+
+	o.queue.close()
+
+	queueErr := o.queue.waitTimeout(discordFlushTimeout)
+	if queueErr != nil {
+		// unblocks the queue if discord stopped receiving. the encoder must not be closed while the
+		// queue is still writing to it
+		o.sender.Close()
+		o.queue.wait()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- o.encoder.Close() }()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-time.After(discordFlushTimeout):
+		err = errors.New("timed out while flushing opus encoder")
+	}
+
+	// gives discord time to send the frames that are still queued
+	deadline := time.Now().Add(discordFlushTimeout)
+	for o.sender.nQueued() > 0 && time.Now().Before(deadline) {
+		time.Sleep(opusFrameDuration)
+	}
+
+	// unblocks the encoder if discord stopped receiving
+	o.sender.Close()
+
+	return errors.Join(queueErr, err)
 }
 
 func (o *DiscordVoiceConnOutput) Subgraph() audio.Node {
 	return o.subgraph
 }
 
+// returns how long the voice connection has gone without playing anything
+func (o *DiscordVoiceConnOutput) IdleFor() time.Duration {
+	return time.Since(time.Unix(0, o.lastActive.Load()))
+}
+
+func (o *DiscordVoiceConnOutput) markActive() {
+	o.lastActive.Store(time.Now().UnixNano())
+}
+
+// removes the output, which leaves the voice channel, once it has been idle for timeout
+func (o *DiscordVoiceConnOutput) leaveWhenIdle(timeout time.Duration) {
+	defer o.session.running.Done()
+
+	ticker := time.NewTicker(discordIdleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.closed:
+			return
+		case <-ticker.C:
+		}
+
+		if idleFor := o.IdleFor(); idleFor >= timeout {
+			o.session.logger.Info("leaving idle discord voice channel", "id", o.session.id, "guildId", o.Conn.GuildID, "channelId", o.Conn.ChannelID, "idleFor", idleFor)
+			o.session.RemoveOutput(o)
+
+			return
+		}
+	}
+}
+
+// cached audio is sent to discord as the opus packets that it was cached as
 func (s *Session) AddDiscordVoiceConnOutput(conn *discordgo.VoiceConnection) (*DiscordVoiceConnOutput, error) {
-	opusSendWriter := ioext.NewChannelWriter(conn.OpusSend)
-	opusEncoderWriter, err := codecs.NewOpusEncoderWriter(opusSendWriter, config.Get().Audio.NumChannels, config.Get().Audio.SampleRateHz, 960) // TODO: move 960 to config file
+	return s.addDiscordVoiceConnOutput(conn, conn.OpusSend, conn.Speaking, conn.Disconnect)
+}
+
+// sends opus frames to send instead of to conn, which lets tests add an output without connecting
+// to discord
+func (s *Session) addDiscordVoiceConnOutput(conn *discordgo.VoiceConnection, send chan<- []byte, setSpeaking func(speaking bool) error, disconnect func() error) (*DiscordVoiceConnOutput, error) {
+	// speaking is set automatically while frames are being sent
+	opusSendWriter, err := s.newDiscordOpusWriter(send, setSpeaking)
 	if err != nil {
+		return nil, err
+	}
+
+	opusEncoderWriter, err := newOpusPassthroughWriter(opusSendWriter)
+	if err != nil {
+		opusSendWriter.Close()
 		return nil, fmt.Errorf("failed to create opus encoder writer: %w", err)
 	}
 
-	opusSendNode := audio.NewWriterNode(s.logger, opusEncoderWriter)
-	output := &DiscordVoiceConnOutput{BaseOutput: NewBaseOutput(s, opusSendNode), Conn: conn}
-	s.AddOutput(output)
+	output := &DiscordVoiceConnOutput{Conn: conn, encoder: opusEncoderWriter, sender: opusSendWriter, disconnect: disconnect, closed: make(chan struct{})}
+	output.markActive()
+
+	// nothing is encoded during silence, so discord is told that the bot stopped speaking
+	gate := &silenceGate{
+		w:         opusEncoderWriter,
+		threshold: time.Duration(config.Get().Discord.SilenceMs) * time.Millisecond,
+		onAudio:   output.markActive,
+	}
+
+	// every voice connection has its own encoder and queue so that one that falls behind does not
+	// hold up the others
+	output.queue, err = s.newOutputQueue("discord:"+conn.GuildID+":"+conn.ChannelID, gate)
+	if err != nil {
+		opusEncoderWriter.Close()
+		opusSendWriter.Close()
+		return nil, err
+	}
+
+	output.BaseOutput = NewBaseOutput(s, audio.NewWriterNode(s.logger, output.queue))
+
+	// a new input is activity even if it has not played anything yet. the event bus is used since
+	// the output can be closed from its own goroutine once it is idle
+	output.eventsHandle = s.Events.Subscribe(func(event SessionEvent) {
+		if _, ok := event.(InputEvent_Added); ok {
+			output.markActive()
+		}
+	})
+
+	// the session may have been destroyed while the output was being created. conn is left for the
+	// caller to disconnect
+	err = s.tryAddOutput(output)
+	if err != nil {
+		output.release()
+		return nil, err
+	}
+
+	// a destroyed session has already removed the output, so there is nothing to leave
+	if idleTimeout := time.Duration(config.Get().Discord.IdleTimeoutMs) * time.Millisecond; idleTimeout > 0 && s.addRunning() == nil {
+		go output.leaveWhenIdle(idleTimeout)
+	}
 
 	return output, nil
 }
 
-func FindDiscordVoiceConnOutput(conn *discordgo.VoiceConnection) (*DiscordVoiceConnOutput, error) {
-	allSessions.Lock()
-	defer allSessions.Unlock()
+// plays the session that is owned by id in conn, creating the session if it does not exist. the
+// session can outlive the voice connection of its owner, such as while another guild listens to it
+// through a share token, in which case conn joins it again
+//
+// created reports whether the session was created for conn, in which case it is destroyed again if
+// conn cannot be added. conn is not disconnected if it cannot be added
+func JoinOwnSession(logger *logging.Logger, id string, conn *discordgo.VoiceConnection) (s *Session, output *DiscordVoiceConnOutput, created bool, err error) {
+	return joinOwnSession(logger, id, func(s *Session) (*DiscordVoiceConnOutput, error) { return s.AddDiscordVoiceConnOutput(conn) })
+}
+
+func joinOwnSession(logger *logging.Logger, id string, addOutput func(s *Session) (*DiscordVoiceConnOutput, error)) (*Session, *DiscordVoiceConnOutput, bool, error) {
+	for {
+		s, exists, err := GetOrCreate(logger, id)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		output, err := addOutput(s)
+
+		// the session may have been destroyed since it was found, such as by its last voice
+		// connection leaving, in which case a new session is created
+		if exists && errors.Is(err, ErrSessionDestroyed) {
+			continue
+		}
+
+		if err != nil {
+			if !exists {
+				s.Destroy()
+			}
+
+			return nil, nil, false, err
+		}
 
-	for _, s := range allSessions.Data {
-		for _, o := range s.Outputs() {
-			do, ok := o.(*DiscordVoiceConnOutput)
-			if ok && do.Conn == conn {
+		return s, output, !exists, nil
+	}
+}
+
+// returns every discord voice connection that the session plays to
+func (s *Session) DiscordVoiceConnOutputs() []*DiscordVoiceConnOutput {
+	outputs := make([]*DiscordVoiceConnOutput, 0)
+
+	for _, o := range s.Outputs() {
+		if do, ok := o.(*DiscordVoiceConnOutput); ok {
+			outputs = append(outputs, do)
+		}
+	}
+
+	return outputs
+}
+
+// finds the output of the voice connection in any session. a voice connection can be added to a
+// session that is owned by another guild
+func FindDiscordVoiceConnOutput(conn *discordgo.VoiceConnection) (*DiscordVoiceConnOutput, error) {
+	for _, s := range Sessions() {
+		for _, do := range s.DiscordVoiceConnOutputs() {
+			if do.Conn == conn {
 				return do, nil
 			}
 		}
@@ -0,0 +1,132 @@
+package audiosession
+
+import (
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// adds a voice connection that discards what it is sent instead of connecting to discord
+func addTestDiscordVoiceConnOutput(t *testing.T, s *Session, guildID string) (*DiscordVoiceConnOutput, error) {
+	t.Helper()
+
+	return addCapturingTestDiscordVoiceConnOutput(t, s, guildID, func([]byte) {})
+}
+
+// adds a voice connection that passes the opus frames that it is sent to onFrame instead of
+// connecting to discord
+func addCapturingTestDiscordVoiceConnOutput(t *testing.T, s *Session, guildID string, onFrame func(frame []byte)) (*DiscordVoiceConnOutput, error) {
+	t.Helper()
+
+	send := make(chan []byte)
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+
+	go func() {
+		for {
+			select {
+			case frame := <-send:
+				onFrame(frame)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	conn := &discordgo.VoiceConnection{GuildID: guildID, ChannelID: "channel"}
+
+	return s.addDiscordVoiceConnOutput(conn, send, func(bool) error { return nil }, func() error { return nil })
+}
+
+func TestJoinOwnSessionAfterOwnerLeft(t *testing.T) {
+	initTestConfig(t)
+
+	joinOwner := func() (*Session, *DiscordVoiceConnOutput, bool) {
+		s, output, created, err := joinOwnSession(logging.NewLogger(), "owner", func(s *Session) (*DiscordVoiceConnOutput, error) {
+			return addTestDiscordVoiceConnOutput(t, s, "owner")
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s, output, created
+	}
+
+	s, ownerOutput, created := joinOwner()
+	if !created {
+		t.Fatal("expected the session of the owner to be created")
+	}
+
+	t.Cleanup(func() {
+		s.Destroy()
+		s.Wait()
+	})
+
+	// another guild listens to the session through a share token
+	_, err := addTestDiscordVoiceConnOutput(t, s, "listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.RemoveOutput(ownerOutput)
+
+	if s.IsDestroyed() {
+		t.Fatal("expected the session to be kept while another guild is listening to it")
+	}
+
+	// the owner joins the session that the listener is still on instead of failing to create one
+	rejoined, _, created := joinOwner()
+	if rejoined != s || created {
+		t.Fatal("expected the owner to join its existing session")
+	}
+
+	if n := len(s.DiscordVoiceConnOutputs()); n != 2 {
+		t.Errorf("expected the owner and the listener to be playing the session, got %d voice connections", n)
+	}
+
+	var nCreated int
+
+	position, err := s.Queue().Enqueue(newTestTrack("a", &nCreated))
+	if err != nil || position != 0 {
+		t.Errorf("expected the owner to be able to play again, got position %d and %v", position, err)
+	}
+}
+
+func TestJoinOwnSessionAfterSessionDestroyed(t *testing.T) {
+	initTestConfig(t)
+
+	destroyed := createTestSession(t, "owner")
+
+	// the session that is found is destroyed before the voice connection is added to it, like when
+	// its last voice connection leaves at the same time
+	s, _, created, err := joinOwnSession(logging.NewLogger(), "owner", func(s *Session) (*DiscordVoiceConnOutput, error) {
+		if s == destroyed {
+			s.Destroy()
+		}
+
+		return addTestDiscordVoiceConnOutput(t, s, "owner")
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		s.Destroy()
+		s.Wait()
+	})
+
+	if s == destroyed || s.IsDestroyed() || !created {
+		t.Fatal("expected a new session to be created in place of the destroyed session")
+	}
+
+	if n := len(s.DiscordVoiceConnOutputs()); n != 1 {
+		t.Errorf("expected the voice connection to play the new session, got %d voice connections", n)
+	}
+
+	if n := len(destroyed.Outputs()); n != 0 {
+		t.Errorf("expected nothing to be added to the destroyed session, got %d outputs", n)
+	}
+}
@@ -0,0 +1,246 @@
+package audiosession
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// discord is told that the bot stopped speaking once no frames have been sent for this long
+const discordSpeakingTimeout = 5 * opusFrameDuration
+
+type DiscordSendStats struct {
+	// frames that were handed to discord
+	Sent int64
+
+	// frames that were thrown away because the send queue was full
+	Dropped int64
+
+	// frames that were handed to discord after they should have been played, such as when the
+	// encoder could not keep up
+	Late int64
+}
+
+type discordFrame struct {
+	data     []byte
+	duration time.Duration
+}
+
+// sends opus packets to a discord voice connection. packets wait in a bounded queue so that a
+// stalled voice connection does not block the encoder forever. what happens once the queue is full
+// depends on the configured [config.DiscordSendPolicy]
+//
+// discordgo generates its own rtp timestamps and assumes that every packet holds opusFrameSize
+// samples, so gaps and packets of other sizes are logged since they will cause the audio to drift
+type discordOpusWriter struct {
+	session     *Session
+	c           chan<- []byte
+	setSpeaking func(speaking bool) error
+
+	policy  config.DiscordSendPolicy
+	timeout time.Duration
+	frames  chan discordFrame
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	prev    codecs.Packet
+	hasPrev bool
+
+	nSent    atomic.Int64
+	nDropped atomic.Int64
+	nLate    atomic.Int64
+}
+
+func (w *discordOpusWriter) Close() error {
+	w.closeOnce.Do(func() { close(w.closed) })
+	return nil
+}
+
+func (w *discordOpusWriter) Write(p []codecs.Packet) (n int, err error) {
+	for _, packet := range p {
+		if w.hasPrev && !packet.Follows(w.prev) {
+			w.session.logger.Warn("gap in opus stream sent to discord",
+				"prevSequenceNumber", w.prev.SequenceNumber,
+				"sequenceNumber", packet.SequenceNumber,
+				"prevEndTimestamp", w.prev.EndTimestamp(),
+				"timestamp", packet.Timestamp)
+		}
+
+		if packet.NumSamples != opusFrameSize {
+			w.session.logger.Debug("sending opus packet with a non-standard size to discord", "numSamples", packet.NumSamples, "duration", packet.Duration)
+		}
+
+		err = w.enqueue(discordFrame{data: packet.Data, duration: packet.Duration})
+		if err != nil {
+			return n, err
+		}
+
+		w.prev, w.hasPrev = packet, true
+		n++
+	}
+
+	return n, nil
+}
+
+// returns [ErrOutputClosed] if the writer has been closed
+func (w *discordOpusWriter) enqueue(frame discordFrame) error {
+	select {
+	case <-w.closed:
+		return ErrOutputClosed
+	default:
+	}
+
+	switch w.policy {
+	case config.DiscordSendPolicy_DropNewest:
+		select {
+		case w.frames <- frame:
+		default:
+			w.drop()
+		}
+	case config.DiscordSendPolicy_Block:
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+
+		select {
+		case w.frames <- frame:
+		case <-timer.C:
+			w.drop()
+		case <-w.closed:
+			return ErrOutputClosed
+		}
+	default:
+		for {
+			select {
+			case w.frames <- frame:
+				return nil
+			default:
+			}
+
+			// the sender may have taken a frame in the meantime, in which case nothing is dropped
+			select {
+			case <-w.frames:
+				w.drop()
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *discordOpusWriter) drop() {
+	if w.nDropped.Add(1) == 1 {
+		w.session.logger.Warn("discord voice connection fell behind. dropping opus frames", "id", w.session.id, "policy", w.policy)
+	}
+
+	w.record(telemetry.DiscordDroppedFramesCounter)
+}
+
+func (w *discordOpusWriter) record(counter metric.Int64Counter) {
+	if counter == nil {
+		return
+	}
+
+	counter.Add(context.Background(), 1, metric.WithAttributes(attribute.String("session", w.session.id)))
+}
+
+// hands queued frames to discord and tells discord whether the bot is speaking. frames are due one
+// after another from when speaking starts, so a frame that is handed over after it is due is late
+func (w *discordOpusWriter) send() {
+	defer w.session.running.Done()
+
+	speaking := false
+	var due time.Time
+
+	setSpeaking := func(b bool) {
+		speaking = b
+
+		err := w.setSpeaking(b)
+		if err != nil {
+			w.session.logger.Warn("failed to set discord speaking state", "id", w.session.id, "speaking", b, "error", err)
+		}
+	}
+
+	idle := time.NewTimer(discordSpeakingTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case frame := <-w.frames:
+			if !speaking {
+				setSpeaking(true)
+				due = time.Now()
+			}
+
+			select {
+			case w.c <- frame.data:
+			case <-w.closed:
+				setSpeaking(false)
+				return
+			}
+
+			w.nSent.Add(1)
+
+			// one frame of slack is allowed since discordgo buffers the frames that it is sent
+			if now := time.Now(); now.After(due.Add(frame.duration)) {
+				w.nLate.Add(1)
+				w.record(telemetry.DiscordLateFramesCounter)
+
+				// the frames after a late frame are due from when it was sent
+				due = now
+			}
+
+			due = due.Add(frame.duration)
+			idle.Reset(discordSpeakingTimeout)
+		case <-idle.C:
+			if speaking {
+				setSpeaking(false)
+			}
+		case <-w.closed:
+			if speaking {
+				setSpeaking(false)
+			}
+
+			return
+		}
+	}
+}
+
+func (w *discordOpusWriter) nQueued() int {
+	return len(w.frames)
+}
+
+func (w *discordOpusWriter) stats() DiscordSendStats {
+	return DiscordSendStats{Sent: w.nSent.Load(), Dropped: w.nDropped.Load(), Late: w.nLate.Load()}
+}
+
+// starts sending frames to c in the background. the size of the queue and what happens once it is
+// full are taken from the config. fails with [ErrSessionDestroyed] once the session has been destroyed
+func (s *Session) newDiscordOpusWriter(c chan<- []byte, setSpeaking func(speaking bool) error) (*discordOpusWriter, error) {
+	w := &discordOpusWriter{
+		session:     s,
+		c:           c,
+		setSpeaking: setSpeaking,
+		policy:      config.Get().Discord.SendPolicy,
+		timeout:     time.Duration(config.Get().Discord.SendTimeoutMs) * time.Millisecond,
+		frames:      make(chan discordFrame, config.Get().Discord.SendQueueFrames),
+		closed:      make(chan struct{}),
+	}
+
+	err := s.addRunning()
+	if err != nil {
+		return nil, err
+	}
+
+	go w.send()
+
+	return w, nil
+}
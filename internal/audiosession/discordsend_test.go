@@ -0,0 +1,69 @@
+package audiosession
+
+import (
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+func TestDiscordOpusWriterPolicies(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+
+	// dropping the oldest frame keeps the newest frames, the other policies keep the oldest
+	tests := []struct {
+		policy config.DiscordSendPolicy
+		next   byte
+	}{
+		{config.DiscordSendPolicy_DropOldest, 2},
+		{config.DiscordSendPolicy_DropNewest, 1},
+		{config.DiscordSendPolicy_Block, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			// discord is not receiving, so the first frame is stuck in the sender and the queue fills up
+			c := make(chan []byte)
+			w := &discordOpusWriter{
+				session:     session,
+				c:           c,
+				setSpeaking: func(bool) error { return nil },
+				policy:      test.policy,
+				timeout:     time.Millisecond,
+				frames:      make(chan discordFrame, 2),
+				closed:      make(chan struct{}),
+			}
+
+			session.running.Add(1)
+			go w.send()
+			defer w.Close()
+
+			w.enqueue(discordFrame{data: []byte{0}, duration: opusFrameDuration})
+
+			deadline := time.Now().Add(5 * time.Second)
+			for w.nQueued() > 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("timed out waiting for the sender to take the first frame")
+				}
+
+				time.Sleep(time.Millisecond)
+			}
+
+			for i := byte(1); i <= 3; i++ {
+				if err := w.enqueue(discordFrame{data: []byte{i}, duration: opusFrameDuration}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if dropped := w.stats().Dropped; dropped != 1 {
+				t.Errorf("expected 1 frame to be dropped, got %d", dropped)
+			}
+
+			if next := (<-w.frames).data[0]; next != test.next {
+				t.Errorf("expected frame %d to be next, got %d", test.next, next)
+			}
+		})
+	}
+}
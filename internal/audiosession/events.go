@@ -0,0 +1,269 @@
+package audiosession
+
+import (
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/events"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+)
+
+// an event that is published on [Session.Events]. use a type switch to find out which event it is
+type SessionEvent interface {
+	// when the event happened
+	Timestamp() time.Time
+
+	sessionEvent()
+}
+
+type SessionEventHeader struct {
+	Session *Session
+	Time    time.Time
+}
+
+func (h SessionEventHeader) Timestamp() time.Time {
+	return h.Time
+}
+
+func (SessionEventHeader) sessionEvent() {}
+
+func newSessionEventHeader(session *Session) SessionEventHeader {
+	return SessionEventHeader{Session: session, Time: time.Now()}
+}
+
+// describes an input at the time of an event
+type InputInfo struct {
+	// the type of the input, such as "ytdlp" or "file"
+	Kind string
+
+	Position time.Duration
+	Metadata InputMetadata
+}
+
+func DescribeInput(input Input) InputInfo {
+	info := InputInfo{Kind: "unknown", Position: input.Position(), Metadata: input.Metadata()}
+
+	switch input.(type) {
+	case *YtdlpInput:
+		info.Kind = "ytdlp"
+	case *FileInput:
+		info.Kind = "file"
+	case *MemoryInput:
+		info.Kind = "memory"
+	case *HTTPStreamInput:
+		info.Kind = "stream"
+	}
+
+	return info
+}
+
+type InputEventHeader struct {
+	SessionEventHeader
+
+	Input Input
+	Info  InputInfo
+}
+
+func newInputEventHeader(input Input) InputEventHeader {
+	return InputEventHeader{
+		SessionEventHeader: newSessionEventHeader(input.Session()),
+		Input:              input,
+		Info:               DescribeInput(input),
+	}
+}
+
+// the input was added to the session
+type InputEvent_Added struct {
+	InputEventHeader
+}
+
+// the first audio was read from the input
+type InputEvent_Started struct {
+	InputEventHeader
+}
+
+type InputEvent_Paused struct {
+	InputEventHeader
+}
+
+type InputEvent_Resumed struct {
+	InputEventHeader
+}
+
+// the input ran out of audio and is waiting for its source, or has caught up again
+type InputEvent_Buffering struct {
+	InputEventHeader
+
+	IsBuffering bool
+}
+
+// the source of the input stopped producing audio. the input is restarted from where it stalled
+// unless it has been restarted too many times, in which case it fails with [ErrInputStalled]
+type InputEvent_Stalled struct {
+	InputEventHeader
+
+	StalledFor time.Duration
+
+	// how many times the input has stalled, including this time
+	NStalls int
+}
+
+// the source of the input reported that a new song started playing, such as the stream title of
+// an internet radio station
+type InputEvent_TitleChanged struct {
+	InputEventHeader
+
+	Title string
+}
+
+// a scheduled input was added to the session. its first sample is played at SampleIndex, which is
+// after the index that it was scheduled at if it started late
+type InputEvent_ScheduledStart struct {
+	InputEventHeader
+
+	SampleIndex int64
+	Late        time.Duration
+}
+
+// the input failed. the input is stopped if it has not stopped already
+type InputEvent_Errored struct {
+	InputEventHeader
+
+	Err error
+}
+
+// the input was removed from the session
+type InputEvent_Finished struct {
+	InputEventHeader
+
+	NInputsRemaining int
+}
+
+type OutputEvent_Added struct {
+	SessionEventHeader
+
+	Output Output
+}
+
+type OutputEvent_Removed struct {
+	SessionEventHeader
+
+	Output            Output
+	NOutputsRemaining int
+}
+
+type SessionEvent_TickingStarted struct {
+	SessionEventHeader
+}
+
+type SessionEvent_TickingStopped struct {
+	SessionEventHeader
+}
+
+// a track was added, removed, moved or started, or the loop mode or shuffle changed
+type QueueEvent_Changed struct {
+	SessionEventHeader
+
+	Current  optional.Optional[Track]
+	Tracks   []Track
+	LoopMode LoopMode
+	Shuffle  bool
+}
+
+// delivers session events to subscribers in the order that they were published. delegates are
+// called from a separate goroutine, so events can be published while the session is locked and
+// delegates are free to call back into the session
+type SessionEventBus struct {
+	mu           sync.Mutex
+	delegates    map[events.DelegateHandle]events.Delegate[SessionEvent]
+	nextHandleId events.DelegateHandle
+
+	pending     []SessionEvent
+	dispatching bool
+
+	// signalled whenever the dispatching goroutine runs out of events, so that shutdown can wait
+	// for every event to be delivered. events are published from anywhere at any time, which a
+	// wait group does not allow
+	idle *sync.Cond
+}
+
+func (b *SessionEventBus) Subscribe(delegate events.Delegate[SessionEvent]) events.DelegateHandle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextHandleId++
+	b.delegates[b.nextHandleId] = delegate
+
+	return b.nextHandleId
+}
+
+// the delegate may still receive events that were being delivered while it was unsubscribed
+func (b *SessionEventBus) Unsubscribe(handle events.DelegateHandle) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.delegates, handle)
+}
+
+func (b *SessionEventBus) publish(event SessionEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, event)
+
+	if !b.dispatching {
+		b.dispatching = true
+
+		go b.dispatch()
+	}
+}
+
+// waits until every event that has been published has been delivered, including events that are
+// published by the delegates while waiting
+func (b *SessionEventBus) wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.dispatching {
+		b.idle.Wait()
+	}
+}
+
+func (b *SessionEventBus) dispatch() {
+	for {
+		b.mu.Lock()
+
+		if len(b.pending) == 0 {
+			b.dispatching = false
+			b.idle.Broadcast()
+			b.mu.Unlock()
+
+			return
+		}
+
+		event := b.pending[0]
+		b.pending = b.pending[1:]
+
+		delegates := make([]events.Delegate[SessionEvent], 0, len(b.delegates))
+		for _, d := range b.delegates {
+			delegates = append(delegates, d)
+		}
+
+		b.mu.Unlock()
+
+		for _, d := range delegates {
+			d(event)
+		}
+	}
+}
+
+func newSessionEventBus() *SessionEventBus {
+	bus := &SessionEventBus{
+		delegates: make(map[events.DelegateHandle]events.Delegate[SessionEvent]),
+		pending:   make([]SessionEvent, 0),
+	}
+
+	bus.idle = sync.NewCond(&bus.mu)
+
+	return bus
+}
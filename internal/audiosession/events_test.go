@@ -0,0 +1,61 @@
+package audiosession
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionEventBus(t *testing.T) {
+	bus := newSessionEventBus()
+
+	received := make([]time.Time, 0)
+	handle := bus.Subscribe(func(event SessionEvent) {
+		received = append(received, event.Timestamp())
+	})
+
+	published := make([]time.Time, 0)
+	for i := range 100 {
+		timestamp := time.Unix(int64(i), 0)
+		published = append(published, timestamp)
+
+		bus.publish(SessionEvent_TickingStarted{SessionEventHeader{Time: timestamp}})
+	}
+
+	bus.wait()
+
+	if len(received) != len(published) {
+		t.Fatalf("expected %d events, got %d", len(published), len(received))
+	}
+
+	for idx := range published {
+		if !received[idx].Equal(published[idx]) {
+			t.Fatalf("event %d was delivered out of order", idx)
+		}
+	}
+
+	bus.Unsubscribe(handle)
+	bus.publish(SessionEvent_TickingStopped{SessionEventHeader{Time: time.Now()}})
+	bus.wait()
+
+	if len(received) != len(published) {
+		t.Errorf("an event was delivered after unsubscribing")
+	}
+
+	// events published by delegates are waited for too
+	var nested bool
+	bus.Subscribe(func(event SessionEvent) {
+		switch event.(type) {
+		case SessionEvent_TickingStarted:
+			bus.publish(SessionEvent_TickingStopped{SessionEventHeader{Time: time.Now()}})
+		case SessionEvent_TickingStopped:
+			nested = true
+		}
+	})
+
+	bus.publish(SessionEvent_TickingStarted{SessionEventHeader{Time: time.Now()}})
+	bus.wait()
+
+	if !nested {
+		t.Errorf("expected an event published by a delegate to be delivered before waiting returned")
+	}
+}
@@ -0,0 +1,623 @@
+package audiosession
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/exec/ffmpeg"
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var (
+	ErrMediaDirectoryNotConfigured = errors.New("media directory is not configured")
+	ErrFileNotAllowed              = errors.New("file is not inside of the media directory")
+)
+
+// plays a local audio file. flac and dca files are decoded natively if they match the configured
+// sample rate and number of channels, everything else is decoded with ffmpeg
+type FileInput struct {
+	*BaseInput
+
+	path       string
+	loop       atomic.Bool
+	readerNode *audio.ReaderNode
+
+	seekMu   sync.Mutex
+	sourceMu sync.Mutex
+	source   *fileSource
+
+	// opens a pass through the file at offset
+	openPass func(offset time.Duration) (*filePCM, error)
+}
+
+// the pcm that is being played. a new source is opened every time that the input seeks. while
+// looping, the source reopens the file each time that it reaches the end
+type fileSource struct {
+	input *FileInput
+
+	mu    sync.Mutex
+	pass  *filePCM
+	ended bool
+
+	// the next pass while looping, which is opened in the background once the current pass has been
+	// read to the end so that it has buffered some audio by the time that it starts. opening it never
+	// holds up a tick
+	next        *filePCM
+	openingNext bool
+	nextErr     error
+
+	// set once the source has been replaced by seeking. the end of a replaced source does not stop the input
+	replaced atomic.Bool
+
+	// set once the source has been closed
+	closed atomic.Bool
+}
+
+// one pass through the file
+type filePCM struct {
+	io.Reader
+	close func()
+
+	// the length of the file if it is known before it is read
+	duration optional.Optional[time.Duration]
+
+	// receives the exit error of ffmpeg. nil if the file is decoded natively
+	exited <-chan *exec.ExitError
+
+	// reads the pass ahead of playback. nil if the pass is not being played, such as by [DecodeFile]
+	buffer *jitterBuffer
+
+	// set once the pass has been closed. ffmpeg is expected to exit with an error after this
+	closed atomic.Bool
+}
+
+func (s *fileSource) Read(p []byte) (n int, err error) {
+	s.mu.Lock()
+	pass := s.pass
+	ended := s.ended
+	s.mu.Unlock()
+
+	if !ended {
+		n, err = pass.Read(p)
+		if err == nil {
+			s.openNextPass(pass)
+		}
+
+		if err != io.EOF {
+			return n, err
+		}
+
+		s.mu.Lock()
+		s.ended = true
+		s.mu.Unlock()
+
+		// the position has to include everything that was read before the end is handled
+		if n > 0 {
+			return n, nil
+		}
+	}
+
+	// the length of the file is only known once all of it has been read if it could not be
+	// determined when it was opened
+	if !s.input.Duration().IsSet() {
+		s.input.setDuration(s.input.Position())
+	}
+
+	if !s.input.IsLooping() || s.closed.Load() {
+		return 0, io.EOF
+	}
+
+	// looping may have been enabled after the pass was read to the end
+	s.openNextPass(pass)
+
+	s.mu.Lock()
+	next := s.next
+	err = s.nextErr
+
+	// play silence until the next pass has been opened, the same as while a buffer fills up
+	if next == nil && err == nil {
+		s.mu.Unlock()
+
+		n = len(p) - len(p)%int(pcmSampleSizeBytes())
+		clear(p[:n])
+
+		return n, nil
+	}
+
+	s.next = nil
+	s.mu.Unlock()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to loop file input: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.closed.Load() {
+		s.mu.Unlock()
+		next.close()
+
+		return 0, io.EOF
+	}
+
+	s.pass = next
+	s.ended = false
+	s.mu.Unlock()
+
+	pass.close()
+	s.input.setPosition(0)
+
+	return next.Read(p)
+}
+
+// starts opening the next pass in the background while looping once all of pass has been buffered.
+// a failure is reported once the end of pass is reached
+func (s *fileSource) openNextPass(pass *filePCM) {
+	if !pass.buffer.sourceEnded() || !s.input.IsLooping() || s.closed.Load() {
+		return
+	}
+
+	s.mu.Lock()
+	if s.next != nil || s.openingNext || s.nextErr != nil {
+		s.mu.Unlock()
+		return
+	}
+
+	s.openingNext = true
+	s.mu.Unlock()
+
+	// shutdown waits for the pass to be opened, which may start ffmpeg
+	s.input.session.running.Add(1)
+
+	go func() {
+		defer s.input.session.running.Done()
+
+		next, err := s.input.openPass(0)
+
+		// the source may have been closed while the pass was being opened
+		s.mu.Lock()
+		s.openingNext = false
+		s.nextErr = err
+		closed := err == nil && s.closed.Load()
+		if err == nil && !closed {
+			s.next = next
+		}
+		s.mu.Unlock()
+
+		if closed {
+			next.close()
+		}
+	}()
+}
+
+func (s *fileSource) close() {
+	s.closed.Store(true)
+
+	s.mu.Lock()
+	pass := s.pass
+	next := s.next
+	s.next = nil
+	s.mu.Unlock()
+
+	pass.close()
+
+	if next != nil {
+		next.close()
+	}
+}
+
+// plays the file again from the start every time that it ends until looping is disabled
+func (i *FileInput) SetLooping(loop bool) {
+	i.loop.Store(loop)
+}
+
+func (i *FileInput) IsLooping() bool {
+	return i.loop.Load()
+}
+
+func (i *FileInput) Path() string {
+	return i.path
+}
+
+// moves playback to position by reopening the file and discarding everything before position
+func (i *FileInput) Seek(position time.Duration) error {
+	position = max(position, 0)
+
+	duration := i.Duration()
+	if duration.IsSet() && position > duration.Get() {
+		return ErrSeekOutOfRange
+	}
+
+	i.seekMu.Lock()
+	defer i.seekMu.Unlock()
+
+	source, err := i.openSource(position)
+	if err != nil {
+		return fmt.Errorf("failed to seek file input: %w", err)
+	}
+
+	// the reader can only be swapped between ticks
+	i.session.Lock()
+
+	i.sourceMu.Lock()
+	prevSource := i.source
+	i.source = source
+	i.sourceMu.Unlock()
+
+	prevSource.replaced.Store(true)
+	i.readerNode.SetReader(source.pcm())
+	i.setPosition(position)
+
+	i.session.Unlock()
+
+	prevSource.close()
+
+	// the input may have been stopped while the new source was being opened
+	if i.State() == inputState_Stopped {
+		i.closeSource()
+	}
+
+	return nil
+}
+
+func (i *FileInput) closeSource() {
+	i.sourceMu.Lock()
+	source := i.source
+	i.sourceMu.Unlock()
+
+	source.close()
+}
+
+func (i *FileInput) openSource(offset time.Duration) (*fileSource, error) {
+	pass, err := i.openPass(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSource{input: i, pass: pass}, nil
+}
+
+// stops the input the same way that a ytdlp input is stopped once all of its audio is read
+func (s *fileSource) pcm() io.Reader {
+	return ioext.NewErrNotifyReader(s, func(err error) {
+		switch {
+		case s.replaced.Load():
+		case err != io.EOF && !s.closed.Load():
+			s.input.endAsync(fmt.Errorf("failed to read file input: %w", err))
+		default:
+			s.input.endAsync(io.EOF)
+		}
+	})
+}
+
+// opens the file at offset, decoding it natively if possible. the file is read ahead of playback so
+// that a slow disk or ffmpeg never holds up a tick
+func (i *FileInput) openPCM(offset time.Duration) (*filePCM, error) {
+	logger := i.session.logger
+
+	pcm, err := openFilePCM(logger, i.path, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if pcm.duration.IsSet() {
+		i.setDuration(pcm.duration.Get())
+	}
+
+	pcm.buffer = i.prefetch(pcm.Reader)
+	pcm.Reader = pcm.buffer
+
+	closePCM := pcm.close
+	pcm.close = func() {
+		pcm.buffer.close()
+		closePCM()
+	}
+
+	if pcm.exited == nil {
+		return pcm, nil
+	}
+
+	// shutdown waits for the process to exit
+	i.session.running.Add(1)
+
+	go func() {
+		defer i.session.running.Done()
+
+		err := <-pcm.exited
+		switch {
+		case err != nil && pcm.closed.Load():
+			logger.Debug("file transcoder was killed after the file was closed", "err", err)
+		case err != nil:
+			logger.Error("file transcoder exited with exit error", "path", i.path, "err", err)
+
+			// a successful exit stops the input once all of the pcm has been read
+			i.fail(fmt.Errorf("ffmpeg exited with an error: %w", err))
+		default:
+			logger.Debug("file transcoder exited successfully")
+		}
+	}()
+
+	return pcm, nil
+}
+
+// opens path at offset and decodes it to 16-bit signed little endian pcm. flac and dca files are
+// decoded natively if possible, everything else is decoded with ffmpeg
+func openFilePCM(logger *logging.Logger, path string, offset time.Duration) (*filePCM, error) {
+	pcm, err := openNativeFilePCM(path, offset)
+
+	switch {
+	case err == nil:
+		return pcm, nil
+	case errors.Is(err, ErrSeekOutOfRange):
+		return nil, err
+	case !errors.Is(err, codecs.ErrUnsupportedFormat):
+		logger.Warn("failed to decode file natively. decoding with ffmpeg", "path", path, "error", err)
+	}
+
+	return openTranscodedFilePCM(logger, path, offset)
+}
+
+// returns [codecs.ErrUnsupportedFormat] if the file cannot be decoded natively
+func openNativeFilePCM(path string, offset time.Duration) (*filePCM, error) {
+	var open func(f *os.File, offset time.Duration) (*filePCM, error)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		open = openFLACFilePCM
+	case ".dca":
+		open = openDCAFilePCM
+	default:
+		return nil, codecs.ErrUnsupportedFormat
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	pcm, err := open(f, offset)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return pcm, nil
+}
+
+func openFLACFilePCM(f *os.File, offset time.Duration) (*filePCM, error) {
+	decoder, err := codecs.NewFLACDecoderReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.SampleRateHz() != config.Get().Audio.SampleRateHz || decoder.NumChannels() != config.Get().Audio.NumChannels {
+		return nil, codecs.ErrUnsupportedFormat
+	}
+
+	pcm := &filePCM{Reader: decoder, close: func() { f.Close() }}
+
+	nSamples := decoder.StreamInfo().NumSamples
+	if nSamples != 0 {
+		pcm.duration = optional.Make(samplesToDuration(nSamples))
+
+		if uint64(durationToSamples(offset)) > nSamples {
+			return nil, ErrSeekOutOfRange
+		}
+	}
+
+	_, err = io.CopyN(io.Discard, decoder, durationToPCMBytes(offset))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to seek flac file: %w", err)
+	}
+
+	return pcm, nil
+}
+
+func openDCAFilePCM(f *os.File, offset time.Duration) (*filePCM, error) {
+	dca, err := codecs.NewDCAReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := dca.Metadata()
+	if metadata.SampleRateHz != config.Get().Audio.SampleRateHz || metadata.NumChannels != config.Get().Audio.NumChannels {
+		return nil, codecs.ErrUnsupportedFormat
+	}
+
+	codec, err := codecs.Lookup(metadata.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetSamples := uint64(durationToSamples(offset))
+
+	packets, err := skipPackets(dca, offsetSamples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek dca file: %w", err)
+	}
+
+	decoder, err := codec.NewDecoder(packets, codecs.DecoderOptions{NumChannels: metadata.NumChannels, SampleRateHz: metadata.SampleRateHz})
+	if err != nil {
+		return nil, err
+	}
+
+	// the first packet may start before the offset
+	var nSkippedSamples uint64
+	if offsetSamples > packets.firstTimestamp {
+		nSkippedSamples = offsetSamples - packets.firstTimestamp
+	}
+
+	_, err = io.CopyN(io.Discard, decoder, int64(nSkippedSamples)*pcmSampleSizeBytes())
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to seek dca file: %w", err)
+	}
+
+	return &filePCM{Reader: decoder, close: func() { f.Close() }}, nil
+}
+
+func openTranscodedFilePCM(logger *logging.Logger, path string, offset time.Duration) (*filePCM, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	transcoder, err, transcoderExitChan := ffmpeg.NewTranscoderWithStartOffset(
+		logger,
+		ffmpeg.Config{ExePath: config.Get().Ffmpeg.ExePath},
+		f,
+		ffmpeg.Format_PCMSigned16BitLittleEndian,
+		config.Get().Audio.SampleRateHz,
+		config.Get().Audio.NumChannels,
+		offset,
+	)
+
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create transcoder: %w", err)
+	}
+
+	pcm := &filePCM{Reader: transcoder, exited: transcoderExitChan}
+
+	pcm.close = func() {
+		pcm.closed.Store(true)
+
+		transcoder.Close()
+		f.Close()
+	}
+
+	return pcm, nil
+}
+
+// decodes all of a file to 16-bit signed little endian pcm in memory
+func DecodeFile(logger *logging.Logger, path string) ([]byte, error) {
+	pcm, err := openFilePCM(logger, path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(pcm)
+	if err != nil {
+		pcm.close()
+		return nil, fmt.Errorf("failed to decode file: %w", err)
+	}
+
+	// ffmpeg may still be writing to stderr after its output has ended, so wait for it to exit
+	// before closing it
+	if pcm.exited != nil {
+		exitErr := <-pcm.exited
+		if exitErr != nil {
+			pcm.close()
+			return nil, fmt.Errorf("ffmpeg exited with exit error: %w", exitErr)
+		}
+	}
+
+	pcm.close()
+
+	return data, nil
+}
+
+// resolves path inside of dir. relative paths are relative to dir. symlinks are resolved before
+// checking so that a link inside of dir cannot be used to play a file outside of it
+func resolveMediaPath(dir string, path string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve media directory: %w", err)
+	}
+
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve media directory: %w", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve file: %w", err)
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrFileNotAllowed
+	}
+
+	return resolved, nil
+}
+
+// add a file input that will automatically be stopped when EOF is reached unless it is looping.
+// path must be inside of the configured media directory
+func (s *Session) AddFileInput(path string) (*FileInput, error) {
+	input, err := s.newFileInput(path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AddInput(input)
+
+	return input, nil
+}
+
+// opens the file at offset without adding the input to the session
+func (s *Session) newFileInput(path string, offset time.Duration) (*FileInput, error) {
+	mediaDir := config.Get().Media.Directory
+	if !mediaDir.IsSet() {
+		return nil, ErrMediaDirectoryNotConfigured
+	}
+
+	resolved, err := resolveMediaPath(mediaDir.Get(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	readerNode := audio.NewReaderNode(s.logger, nil, tickSizeBytes())
+
+	input := &FileInput{
+		BaseInput:  NewBaseInput(s, readerNode),
+		path:       resolved,
+		readerNode: readerNode,
+	}
+
+	input.openPass = input.openPCM
+	input.metadata.Title = filepath.Base(resolved)
+	input.metadata.SourceURL = resolved
+
+	source, err := input.openSource(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	input.source = source
+	input.setPosition(offset)
+	readerNode.SetReader(source.pcm())
+
+	// kill ffmpeg if the input is stopped before all of the audio has been played
+	input.whenStopped(input.closeSource)
+
+	return input, nil
+}
+
+// creates a track that plays a file input when it reaches the front of a queue
+func NewFileTrack(path string) Track {
+	return newSeekableTrack(filepath.Base(path), func(s *Session, offset time.Duration) (Input, error) {
+		input, err := s.newFileInput(path, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return input, nil
+	}).withSource(TrackSource{Kind: TrackKind_File, URL: path})
+}
@@ -0,0 +1,229 @@
+package audiosession
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+func TestResolveMediaPath(t *testing.T) {
+	root := t.TempDir()
+	mediaDir := filepath.Join(root, "media")
+	outsideFile := filepath.Join(root, "outside.ogg")
+
+	err := os.MkdirAll(filepath.Join(mediaDir, "clips"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(mediaDir, "clips", "airhorn.ogg"), outsideFile} {
+		err = os.WriteFile(path, nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = os.Symlink(outsideFile, filepath.Join(mediaDir, "link.ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolvedMediaDir, err := filepath.EvalSymlinks(mediaDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(resolvedMediaDir, "clips", "airhorn.ogg")
+
+	for _, path := range []string{"clips/airhorn.ogg", filepath.Join(mediaDir, "clips", "airhorn.ogg"), "clips/../clips/airhorn.ogg"} {
+		resolved, err := resolveMediaPath(mediaDir, path)
+		if err != nil {
+			t.Errorf("resolveMediaPath(%q) returned error: %v", path, err)
+		} else if resolved != expected {
+			t.Errorf("resolveMediaPath(%q) = %q, expected %q", path, resolved, expected)
+		}
+	}
+
+	for _, path := range []string{"../outside.ogg", outsideFile, "link.ogg", "."} {
+		_, err := resolveMediaPath(mediaDir, path)
+		if !errors.Is(err, ErrFileNotAllowed) {
+			t.Errorf("resolveMediaPath(%q) returned %v, expected %v", path, err, ErrFileNotAllowed)
+		}
+	}
+}
+
+// writes a tenth of a second of a constant sample to a flac file in the media directory and returns
+// the size of its pcm
+func writeTestLoopFile(t *testing.T, mediaDir string) int {
+	t.Helper()
+
+	nSamples := 4800
+	samples := make([]int16, nSamples*int(config.Get().Audio.NumChannels))
+	for idx := range samples {
+		samples[idx] = 1000
+	}
+
+	var flac bytes.Buffer
+
+	enc, err := codecs.NewFLACEncoderWriter(&flac, config.Get().Audio.NumChannels, config.Get().Audio.SampleRateHz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = enc.Write(codecs.S16LEToBytes(samples))
+	if err == nil {
+		err = enc.Close()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(mediaDir, "loop.flac"), flac.Bytes(), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return len(samples) * 2
+}
+
+func TestFileInputLoop(t *testing.T) {
+	mediaDir := t.TempDir()
+
+	initTestConfigJSON(t, fmt.Sprintf(`{
+		"media": {"directory": %q},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`, mediaDir))
+
+	size := writeTestLoopFile(t, mediaDir)
+	session := newTestSession(t)
+
+	input, err := session.newFileInput("loop.flac", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input.SetLooping(true)
+
+	source := input.source.pcm()
+	played := 0
+	p := make([]byte, 0x1000)
+
+	// reads never wait for the file. they return silence until it has been buffered
+	deadline := time.Now().Add(5 * time.Second)
+	for played < 2*size+size/2 && time.Now().Before(deadline) {
+		n, err := source.Read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		played += n - bytes.Count(p[:n], []byte{0})
+		time.Sleep(time.Millisecond)
+	}
+
+	if played < 2*size+size/2 {
+		t.Fatalf("expected the file to loop, played %d bytes", played)
+	}
+
+	// the position only counts the audio of the current pass, which is part way through the third
+	if input.Position() >= pcmBytesToDuration(int64(size)) || input.Position() < pcmBytesToDuration(int64(size/2)) {
+		t.Errorf("expected the position to go back to the start every pass, got %s", input.Position())
+	}
+
+	input.Stop()
+}
+
+func TestFileInputLoopDoesNotWaitForNextPass(t *testing.T) {
+	mediaDir := t.TempDir()
+
+	initTestConfigJSON(t, fmt.Sprintf(`{
+		"media": {"directory": %q},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`, mediaDir))
+
+	size := writeTestLoopFile(t, mediaDir)
+	session := newTestSession(t)
+
+	input, err := session.newFileInput("loop.flac", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input.SetLooping(true)
+
+	// the next pass takes until it is released to open, like a slow disk or ffmpeg
+	release := make(chan struct{})
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	openPass := input.openPass
+	input.openPass = func(offset time.Duration) (*filePCM, error) {
+		<-release
+		return openPass(offset)
+	}
+
+	source := input.source.pcm()
+	played := 0
+	p := make([]byte, 0x1000)
+
+	read := func() {
+		t.Helper()
+
+		start := time.Now()
+
+		n, err := source.Read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Fatalf("expected reads to never wait for the next pass to open, took %s", d)
+		}
+
+		played += n - bytes.Count(p[:n], []byte{0})
+		time.Sleep(time.Millisecond)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for played < size && time.Now().Before(deadline) {
+		read()
+	}
+
+	if played != size {
+		t.Fatalf("expected the first pass to be played, played %d of %d bytes", played, size)
+	}
+
+	// the end of the pass plays silence while the next pass is opening
+	for range 50 {
+		read()
+	}
+
+	if played != size {
+		t.Fatalf("expected silence until the next pass has been opened, played %d bytes", played-size)
+	}
+
+	close(release)
+
+	deadline = time.Now().Add(5 * time.Second)
+	for played < size+size/2 && time.Now().Before(deadline) {
+		read()
+	}
+
+	if played < size+size/2 {
+		t.Fatalf("expected the next pass to play once it was opened, played %d bytes", played-size)
+	}
+
+	input.Stop()
+}
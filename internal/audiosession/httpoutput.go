@@ -0,0 +1,255 @@
+package audiosession
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+// how long to wait for a listener to receive the end of the stream once it is closed
+const httpStreamFlushTimeout = time.Second
+
+// guards adding http stream outputs so that a session never has more than one
+var httpStreamOutputsMu sync.Mutex
+
+var ErrTooManyListeners = errors.New("http stream has too many listeners")
+
+// streams the session as ogg opus to any number of http listeners. every listener has its own
+// encoder and queue, so a listener on a slow connection only falls behind itself
+type HTTPStreamOutput struct {
+	*BaseOutput
+
+	mu        sync.Mutex
+	listeners map[*httpStreamListener]struct{}
+	closed    bool
+
+	// counts listeners from when they are accepted, which is before they are added to listeners
+	nAccepted int
+
+	// closed once the output has been closed, which ends every stream
+	closedChan chan struct{}
+}
+
+type httpStreamListener struct {
+	queue *outputQueue
+}
+
+// writes every write straight to the client instead of waiting for the response buffer to fill
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (w *flushWriter) Write(p []byte) (n int, err error) {
+	n, err = w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, w.rc.Flush()
+}
+
+// returns the number of clients that are listening to the stream
+func (o *HTTPStreamOutput) NumListeners() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.listeners)
+}
+
+// queues pcm from the session for every listener. called from within a tick
+func (o *HTTPStreamOutput) Write(p []byte) (n int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for l := range o.listeners {
+		l.queue.Write(p)
+	}
+
+	return len(p), nil
+}
+
+func (o *HTTPStreamOutput) followsPlayers() {}
+
+// ends the stream of every listener
+func (o *HTTPStreamOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.closed {
+		o.closed = true
+		close(o.closedChan)
+	}
+
+	return nil
+}
+
+// streams the session until the client disconnects or the output is closed. the output stays in the
+// session once its last listener disconnects, so listeners never decide when the session ends
+func (o *HTTPStreamOutput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+
+	if o.closed {
+		o.mu.Unlock()
+		http.Error(w, ErrOutputClosed.Error(), http.StatusGone)
+
+		return
+	}
+
+	// the encoder writes to the response as soon as it is created, so a listener is accepted first
+	if o.nAccepted >= config.Get().Web.MaxStreamListeners {
+		o.mu.Unlock()
+
+		o.session.logger.Debug("rejecting http stream listener", "id", o.session.id, "remoteAddr", r.RemoteAddr, "error", ErrTooManyListeners)
+		http.Error(w, ErrTooManyListeners.Error(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	o.nAccepted++
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.nAccepted--
+		o.mu.Unlock()
+	}()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "audio/ogg")
+	w.Header().Set("Cache-Control", "no-store")
+
+	enc, err := codecs.NewOggOpusEncoderWriter(&flushWriter{w: w, rc: rc}, config.Get().Audio.NumChannels, config.Get().Audio.SampleRateHz, opusFrameSize)
+	if err != nil {
+		o.session.logger.Warn("failed to create ogg opus encoder for http stream listener", "id", o.session.id, "error", err)
+		return
+	}
+
+	// the session is locked while ticking, which writes to the output, so the queue is created first
+	queue, err := o.session.newOutputQueue("http:"+r.RemoteAddr, enc)
+	if err != nil {
+		o.session.logger.Debug("rejecting http stream listener", "id", o.session.id, "remoteAddr", r.RemoteAddr, "error", err)
+		return
+	}
+
+	l := &httpStreamListener{queue: queue}
+
+	o.mu.Lock()
+
+	if o.closed {
+		o.mu.Unlock()
+
+		queue.close()
+		queue.wait()
+
+		return
+	}
+
+	o.listeners[l] = struct{}{}
+
+	o.mu.Unlock()
+
+	o.session.logger.Info("http stream listener connected", "id", o.session.id, "remoteAddr", r.RemoteAddr)
+
+	select {
+	case <-r.Context().Done():
+	case <-o.closedChan:
+	}
+
+	o.mu.Lock()
+	delete(o.listeners, l)
+	o.mu.Unlock()
+
+	l.queue.close()
+
+	// the response cannot be written to once the handler returns, so a stalled client is cut off
+	err = l.queue.waitTimeout(httpStreamFlushTimeout)
+	if err != nil {
+		rc.SetWriteDeadline(time.Now())
+		l.queue.wait()
+	}
+
+	err = enc.Close()
+	if err != nil && r.Context().Err() == nil {
+		o.session.logger.Debug("failed to end http stream", "id", o.session.id, "remoteAddr", r.RemoteAddr, "error", err)
+	}
+
+	o.session.logger.Info("http stream listener disconnected", "id", o.session.id, "remoteAddr", r.RemoteAddr, "dropped", l.queue.dropped())
+}
+
+func (o *HTTPStreamOutput) isClosed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.closed
+}
+
+// returns the http stream output of the session, adding it if the session does not have one that
+// is still open
+//
+// returns [ErrSessionDestroyed] if the session has been destroyed
+func (s *Session) GetOrAddHTTPStreamOutput() (*HTTPStreamOutput, error) {
+	httpStreamOutputsMu.Lock()
+	defer httpStreamOutputsMu.Unlock()
+
+	for _, o := range s.Outputs() {
+		if ho, ok := o.(*HTTPStreamOutput); ok && !ho.isClosed() {
+			return ho, nil
+		}
+	}
+
+	output := &HTTPStreamOutput{
+		listeners:  make(map[*httpStreamListener]struct{}),
+		closedChan: make(chan struct{}),
+	}
+	output.BaseOutput = NewBaseOutput(s, audio.NewWriterNode(s.logger, output))
+
+	// a destroyed session has already closed its outputs, so one that is added now would never be closed
+	err := s.tryAddOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// serves the stream of the session whose id is in the "session" path value, such as when the
+// handler is registered as "GET /sessions/{session}/stream". the "token" query parameter must be
+// the token that the session was shared with
+func NewHTTPStreamHandler(logger *logging.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := GetShared(r.PathValue("session"), r.URL.Query().Get("token"))
+		switch {
+		// a wrong token looks the same as a missing session so that session ids cannot be probed
+		case errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrInvalidShareToken):
+			http.Error(w, ErrSessionNotFound.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			logger.Warn("failed to get audio session for http stream", "remoteAddr", r.RemoteAddr, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		output, err := s.GetOrAddHTTPStreamOutput()
+		if errors.Is(err, ErrSessionDestroyed) {
+			http.Error(w, ErrSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			logger.Warn("failed to add http stream output", "id", s.ID(), "remoteAddr", r.RemoteAddr, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		output.ServeHTTP(w, r)
+	})
+}
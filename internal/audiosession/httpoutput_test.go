@@ -0,0 +1,114 @@
+package audiosession
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+func TestHTTPStreamHandler(t *testing.T) {
+	initTestConfigJSON(t, `{
+		"web": {"streamEnabled": true, "maxStreamListeners": 1},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`)
+
+	session, err := Create(logging.NewLogger(), "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		session.Destroy()
+		session.Wait()
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /sessions/{session}/stream", NewHTTPStreamHandler(logging.NewLogger()))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	get := func(ctx context.Context, path string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp
+	}
+
+	// a session that has not been shared cannot be streamed
+	resp := get(context.Background(), "/sessions/owner/stream")
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d before sharing, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	token, err := session.Share()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp = get(context.Background(), "/sessions/owner/stream?token=wrong")
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d for the wrong token, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := get(ctx, "/sessions/owner/stream?token="+token)
+	defer listener.Body.Close()
+
+	if listener.StatusCode != http.StatusOK || listener.Header.Get("Content-Type") != "audio/ogg" {
+		t.Fatalf("expected an ogg stream, got %d %q", listener.StatusCode, listener.Header.Get("Content-Type"))
+	}
+
+	// the first listener takes the only place
+	resp = get(context.Background(), "/sessions/owner/stream?token="+token)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d once the stream is full, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	output, err := session.GetOrAddHTTPStreamOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last listener leaving does not end the session
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for output.NumListeners() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if session.IsDestroyed() || !slices.Contains(session.Outputs(), Output(output)) {
+		t.Errorf("expected the session to keep its http stream output once the last listener left")
+	}
+
+	// a closed output is replaced instead of being handed to new listeners
+	output.Close()
+
+	replaced, err := session.GetOrAddHTTPStreamOutput()
+	if err != nil || replaced == output {
+		t.Errorf("expected a new output to replace the closed one, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package audiosession
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	icyMetadataStreamTitle = "StreamTitle"
+
+	// the length byte of a metadata block is multiplied by this to get the length in bytes
+	icyMetadataBlockSize = 16
+)
+
+// strips the metadata blocks that icecast and shoutcast servers interleave with the audio every
+// metaInt bytes when the client sends "Icy-MetaData: 1"
+type icyReader struct {
+	r       io.Reader
+	metaInt int
+
+	// bytes of audio until the next metadata block
+	remaining int
+
+	onMetadata func(metadata map[string]string)
+}
+
+func (r *icyReader) Read(p []byte) (n int, err error) {
+	if r.remaining == 0 {
+		err = r.readMetadata()
+		if err != nil {
+			return 0, err
+		}
+
+		r.remaining = r.metaInt
+	}
+
+	n, err = r.r.Read(p[:min(len(p), r.remaining)])
+	r.remaining -= n
+
+	return n, err
+}
+
+func (r *icyReader) readMetadata() error {
+	var length [1]byte
+	_, err := io.ReadFull(r.r, length[:])
+	if err != nil {
+		return err
+	}
+
+	// an empty block means that the metadata has not changed
+	if length[0] == 0 {
+		return nil
+	}
+
+	block := make([]byte, int(length[0])*icyMetadataBlockSize)
+	_, err = io.ReadFull(r.r, block)
+	if err != nil {
+		return fmt.Errorf("failed to read icy metadata: %w", err)
+	}
+
+	r.onMetadata(parseICYMetadata(string(block)))
+
+	return nil
+}
+
+// parses metadata in the form of StreamTitle='Artist - Title';StreamUrl='http://example.com';
+//
+// values are not escaped, so a value only ends at a quote that is followed by a semicolon or the
+// end of the metadata
+func parseICYMetadata(s string) map[string]string {
+	metadata := make(map[string]string)
+
+	// blocks are padded to a multiple of 16 bytes with nul bytes
+	s = strings.TrimRight(s, "\x00")
+
+	for len(s) > 0 {
+		key, rest, ok := strings.Cut(s, "='")
+		if !ok {
+			break
+		}
+
+		end := strings.Index(rest, "';")
+		if end == -1 {
+			metadata[key] = strings.TrimSuffix(rest, "'")
+			break
+		}
+
+		metadata[key] = rest[:end]
+		s = rest[end+len("';"):]
+	}
+
+	return metadata
+}
+
+func newICYReader(r io.Reader, metaInt int, onMetadata func(metadata map[string]string)) *icyReader {
+	return &icyReader{r: r, metaInt: metaInt, remaining: metaInt, onMetadata: onMetadata}
+}
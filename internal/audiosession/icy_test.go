@@ -0,0 +1,59 @@
+package audiosession
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"testing"
+)
+
+func icyMetadataBlock(s string) []byte {
+	length := (len(s) + icyMetadataBlockSize - 1) / icyMetadataBlockSize
+	block := make([]byte, 1+length*icyMetadataBlockSize)
+	block[0] = byte(length)
+	copy(block[1:], s)
+
+	return block
+}
+
+func TestICYReader(t *testing.T) {
+	var stream bytes.Buffer
+	stream.WriteString("abcd")
+	stream.Write(icyMetadataBlock("StreamTitle='Artist - Song';StreamUrl='';"))
+	stream.WriteString("efgh")
+	stream.WriteByte(0) // the metadata did not change
+	stream.WriteString("ij")
+
+	titles := make([]string, 0)
+	r := newICYReader(&stream, 4, func(metadata map[string]string) {
+		titles = append(titles, metadata[icyMetadataStreamTitle])
+	})
+
+	audio, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(audio) != "abcdefghij" {
+		t.Errorf("expected audio %q, got %q", "abcdefghij", audio)
+	}
+
+	if len(titles) != 1 || titles[0] != "Artist - Song" {
+		t.Errorf("expected titles [Artist - Song], got %v", titles)
+	}
+}
+
+func TestParseICYMetadata(t *testing.T) {
+	cases := map[string]map[string]string{
+		"StreamTitle='Artist - Song';\x00\x00\x00":         {"StreamTitle": "Artist - Song"},
+		"StreamTitle='It's Fine';StreamUrl='http://x.y/';": {"StreamTitle": "It's Fine", "StreamUrl": "http://x.y/"},
+		"StreamTitle='Unterminated'":                       {"StreamTitle": "Unterminated"},
+		"":                                                 {},
+	}
+
+	for s, expected := range cases {
+		if actual := parseICYMetadata(s); !maps.Equal(actual, expected) {
+			t.Errorf("parseICYMetadata(%q) = %v, expected %v", s, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package audiosession
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry"
+)
+
+// size of the chunks that are read from the source in the background
+const jitterBufferChunkSize = 0x2000
+
+// pcm that is read ahead of playback by a background goroutine so that a slow source never blocks
+// a tick. the buffer fills up to highWater bytes. whenever it runs dry before the source has ended,
+// including before the first audio arrives, it outputs silence until it holds lowWater bytes again
+type jitterBuffer struct {
+	src io.Reader
+
+	lowWater  int
+	highWater int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	data      bytes.Buffer
+	buffering bool
+	err       error
+	closed    bool
+
+	// when the source last produced any audio, or when the buffer was created
+	lastData time.Time
+
+	// called with the number of bytes of audio that were read, not including silence
+	onRead func(n int)
+
+	// called whenever the buffer starts or stops buffering
+	onBuffering func(buffering bool)
+
+	// identifies the buffer in metrics
+	sessionID string
+}
+
+func (b *jitterBuffer) Read(p []byte) (n int, err error) {
+	b.mu.Lock()
+
+	if b.buffering && (b.data.Len() >= b.lowWater || b.err != nil) {
+		b.buffering = false
+		defer b.onBuffering(false)
+	}
+
+	if !b.buffering && b.data.Len() == 0 && b.err == nil {
+		b.buffering = true
+		defer b.onBuffering(true)
+	}
+
+	switch {
+	case b.buffering:
+		n = len(p) - len(p)%int(pcmSampleSizeBytes())
+		clear(p[:n])
+	case b.data.Len() > 0:
+		// only whole samples are read so that the channels stay aligned if the buffer runs dry
+		size := min(len(p), b.data.Len())
+		if size >= int(pcmSampleSizeBytes()) {
+			size -= size % int(pcmSampleSizeBytes())
+		}
+
+		n, _ = b.data.Read(p[:size])
+		b.cond.Broadcast()
+	default:
+		err = b.err
+	}
+
+	silent := b.buffering
+	fill := b.data.Len()
+
+	b.mu.Unlock()
+
+	b.recordFill(fill)
+
+	if !silent && n > 0 {
+		b.onRead(n)
+	}
+
+	return n, err
+}
+
+// how much audio is buffered ahead of playback
+func (b *jitterBuffer) buffered() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.data.Len()
+}
+
+// stops reading from the source. anything that is still buffered can be read, after which reads
+// return [io.EOF]
+func (b *jitterBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	if b.err == nil {
+		b.err = io.EOF
+	}
+
+	b.cond.Broadcast()
+}
+
+// reads from the source until it ends or the buffer is closed
+func (b *jitterBuffer) fill() {
+	chunk := make([]byte, jitterBufferChunkSize)
+
+	for {
+		b.mu.Lock()
+		for b.data.Len() >= b.highWater && !b.closed {
+			b.cond.Wait()
+		}
+
+		closed := b.closed
+		b.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		n, err := b.src.Read(chunk)
+
+		b.mu.Lock()
+
+		if !b.closed {
+			b.data.Write(chunk[:n])
+		}
+
+		if n > 0 {
+			b.lastData = time.Now()
+		}
+
+		if err != nil && b.err == nil {
+			b.err = err
+		}
+
+		b.cond.Broadcast()
+		b.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// returns true once everything has been read from the source, even if it has not all been played
+func (b *jitterBuffer) sourceEnded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.err != nil
+}
+
+// returns how long the source has gone without producing any audio while the buffer is running
+// low. returns 0 if the buffer holds enough audio or the source has ended
+func (b *jitterBuffer) stalledFor() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil || b.data.Len() >= b.lowWater {
+		return 0
+	}
+
+	return time.Since(b.lastData)
+}
+
+func (b *jitterBuffer) recordFill(fill int) {
+	if telemetry.JitterBufferFillGauge == nil {
+		return
+	}
+
+	telemetry.JitterBufferFillGauge.Record(
+		context.Background(),
+		pcmBytesToDuration(int64(fill)).Milliseconds(),
+		metric.WithAttributes(attribute.String("session", b.sessionID)),
+	)
+}
+
+// starts reading src in the background. the water marks are taken from the config
+func (i *BaseInput) prefetch(src io.Reader) *jitterBuffer {
+	b := &jitterBuffer{
+		src:         src,
+		lowWater:    int(durationToPCMBytes(time.Duration(config.Get().Audio.JitterBufferLowWaterMs) * time.Millisecond)),
+		highWater:   int(durationToPCMBytes(time.Duration(config.Get().Audio.JitterBufferHighWaterMs) * time.Millisecond)),
+		onRead:      i.advance,
+		onBuffering: i.setBuffering,
+		sessionID:   i.session.ID(),
+		lastData:    time.Now(),
+	}
+
+	b.cond = sync.NewCond(&b.mu)
+
+	// the source is closed along with the buffer by its owner, which ends any read that is blocking
+	i.session.running.Add(1)
+
+	go func() {
+		defer i.session.running.Done()
+		b.fill()
+	}()
+
+	return b
+}
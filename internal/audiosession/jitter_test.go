@@ -0,0 +1,139 @@
+package audiosession
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+func initTestConfig(t *testing.T) {
+	t.Helper()
+
+	initTestConfigJSON(t, `{"discord": {"appId": "app", "publicKey": "key", "token": "token"}}`)
+}
+
+// data must set the required discord options
+func initTestConfigJSON(t *testing.T, data string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	err := os.WriteFile(path, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verrs, err := config.Init(config.ConfigInitOptions{Files: []string{path}})
+	if err != nil || len(verrs) > 0 {
+		t.Fatalf("failed to initialize config: %v %v", err, verrs)
+	}
+}
+
+func waitForBuffered(t *testing.T, b *jitterBuffer, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.buffered() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d bytes to be buffered", n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJitterBuffer(t *testing.T) {
+	initTestConfig(t)
+
+	src, srcWriter := io.Pipe()
+
+	played := 0
+	buffering := make([]bool, 0)
+
+	b := &jitterBuffer{
+		src:         src,
+		lowWater:    8,
+		highWater:   16,
+		onRead:      func(n int) { played += n },
+		onBuffering: func(isBuffering bool) { buffering = append(buffering, isBuffering) },
+	}
+	b.cond = sync.NewCond(&b.mu)
+
+	var filling sync.WaitGroup
+	filling.Add(1)
+
+	go func() {
+		defer filling.Done()
+		b.fill()
+	}()
+
+	p := make([]byte, 4)
+
+	// nothing has arrived, so the read must not block
+	n, err := b.Read(p)
+	if err != nil || n != 4 || !bytes.Equal(p, make([]byte, 4)) {
+		t.Fatalf("expected 4 bytes of silence, got %d bytes %v, error %v", n, p[:n], err)
+	}
+
+	// less than the low water mark does not end buffering
+	srcWriter.Write([]byte{1, 1, 1, 1})
+	waitForBuffered(t, b, 4)
+
+	if n, _ := b.Read(p); n != 4 || !bytes.Equal(p, make([]byte, 4)) {
+		t.Fatalf("expected silence while below the low water mark, got %v", p[:n])
+	}
+
+	srcWriter.Write([]byte{2, 2, 2, 2})
+	waitForBuffered(t, b, 8)
+
+	n, _ = b.Read(p)
+	if n != 4 || !bytes.Equal(p, []byte{1, 1, 1, 1}) {
+		t.Fatalf("expected buffered audio, got %v", p[:n])
+	}
+
+	// the buffer stops reading from the source at the high water mark
+	go func() {
+		for range 8 {
+			if _, err := srcWriter.Write([]byte{3, 3, 3, 3}); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitForBuffered(t, b, 16)
+	time.Sleep(10 * time.Millisecond)
+
+	if buffered := b.buffered(); buffered != 16 {
+		t.Errorf("expected the buffer to stop at the high water mark of 16 bytes, buffered %d bytes", buffered)
+	}
+
+	b.close()
+	src.Close()
+	filling.Wait()
+
+	for {
+		_, err = b.Read(p)
+		if err != nil {
+			break
+		}
+	}
+
+	if err != io.EOF {
+		t.Errorf("expected io.EOF once the buffer is closed and drained, got %v", err)
+	}
+
+	// silence is not counted as played
+	if played != 4+16 {
+		t.Errorf("expected 20 bytes to be played, got %d", played)
+	}
+
+	if len(buffering) < 2 || !buffering[0] || buffering[1] {
+		t.Errorf("expected buffering to start and then stop, got %v", buffering)
+	}
+}
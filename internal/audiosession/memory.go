@@ -0,0 +1,73 @@
+package audiosession
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
+)
+
+// plays 16-bit signed little endian pcm that has already been decoded into memory. starting
+// playback never spawns any processes, so it is suited for short clips that have to start instantly
+type MemoryInput struct {
+	*BaseInput
+
+	name   string
+	reader *bytes.Reader
+}
+
+func (i *MemoryInput) Name() string {
+	return i.name
+}
+
+func (i *MemoryInput) Seek(position time.Duration) error {
+	position = max(position, 0)
+
+	if position > i.Duration().Get() {
+		return ErrSeekOutOfRange
+	}
+
+	// the reader can only be moved between ticks
+	i.session.Lock()
+	defer i.session.Unlock()
+
+	_, err := i.reader.Seek(durationToPCMBytes(position), io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	i.setPosition(position)
+
+	return nil
+}
+
+// add an input that plays pcm and will automatically be stopped once all of it has been played.
+// pcm is not copied and must not be modified after it is added, but it may be shared between inputs
+func (s *Session) AddMemoryInput(name string, pcm []byte) *MemoryInput {
+	input := s.NewMemoryInput(name, pcm)
+	s.AddInput(input)
+
+	return input
+}
+
+// creates an input that plays pcm without adding it to the session
+func (s *Session) NewMemoryInput(name string, pcm []byte) *MemoryInput {
+	reader := bytes.NewReader(pcm)
+
+	readerNode := audio.NewReaderNode(s.logger, nil, tickSizeBytes())
+
+	input := &MemoryInput{
+		BaseInput: NewBaseInput(s, readerNode),
+		name:      name,
+		reader:    reader,
+	}
+
+	input.metadata.Title = name
+	input.setDuration(pcmBytesToDuration(int64(len(pcm))))
+
+	readerNode.SetReader(input.trackPosition(ioext.NewErrNotifyReader(reader, input.endAsync)))
+
+	return input
+}
@@ -0,0 +1,222 @@
+package audiosession
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+var ErrOutputQueueTimeout = errors.New("timed out while draining output queue")
+
+// pcm that has been played by the session but not yet written to an output. the tick never waits
+// for the output, so a slow output only falls behind itself. once the queue is full the oldest pcm
+// is dropped to make room
+type outputQueue struct {
+	session *Session
+	name    string
+	w       io.Writer
+	maxSize int
+
+	// set if w can send the cached packets that pcm was decoded from
+	passthrough bool
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks []queuedPCM
+	size   int
+	closed bool
+
+	// number of bytes of pcm that were dropped because the output fell behind
+	nDropped int64
+
+	done chan struct{}
+}
+
+// pcm that was written to the queue at once, and the cached packets that it was decoded from if
+// the output can send them
+type queuedPCM struct {
+	pcm     []byte
+	packets *cachedPackets
+}
+
+// queues p to be written to the output. never blocks and never fails, since the session must not
+// stop because of one output
+func (q *outputQueue) Write(p []byte) (n int, err error) {
+	// outputs are written to while the tick is played, which is when its packets can be looked up
+	var packets *cachedPackets
+	if q.passthrough {
+		packets = q.session.passthroughPacketsLocked(p)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return len(p), nil
+	}
+
+	q.chunks = append(q.chunks, queuedPCM{pcm: bytes.Clone(p), packets: packets})
+	q.size += len(p)
+
+	if overflow := q.size - q.maxSize; overflow > 0 {
+		// whole samples are dropped so that the channels stay aligned
+		sampleSize := int(pcmSampleSizeBytes())
+		overflow += (sampleSize - overflow%sampleSize) % sampleSize
+
+		if q.nDropped == 0 {
+			q.session.logger.Warn("audio session output fell behind. dropping audio", "id", q.session.id, "output", q.name)
+		}
+
+		q.nDropped += int64(q.dropLocked(overflow))
+	}
+
+	q.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// drops up to n bytes of the oldest pcm and returns how many were dropped
+func (q *outputQueue) dropLocked(n int) (dropped int) {
+	for dropped < n && len(q.chunks) > 0 {
+		chunk := &q.chunks[0]
+
+		if len(chunk.pcm) <= n-dropped {
+			dropped += len(chunk.pcm)
+			q.chunks[0] = queuedPCM{}
+			q.chunks = q.chunks[1:]
+
+			continue
+		}
+
+		// the packets no longer decode to what is left of the pcm
+		chunk.pcm = chunk.pcm[n-dropped:]
+		chunk.packets = nil
+		dropped = n
+	}
+
+	q.size -= dropped
+
+	return dropped
+}
+
+// returns how much audio has been dropped because the output fell behind
+func (q *outputQueue) dropped() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return pcmBytesToDuration(q.nDropped)
+}
+
+// returns how many bytes of pcm are queued
+func (q *outputQueue) buffered() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return int64(q.size)
+}
+
+// writes queued pcm to the output until the queue is closed and drained. if the output fails, the
+// rest of the pcm is discarded
+func (q *outputQueue) drain() {
+	defer q.session.running.Done()
+	defer close(q.done)
+
+	for {
+		q.mu.Lock()
+
+		for len(q.chunks) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+
+		if len(q.chunks) == 0 {
+			q.mu.Unlock()
+			return
+		}
+
+		chunk := q.chunks[0]
+		q.chunks[0] = queuedPCM{}
+		q.chunks = q.chunks[1:]
+		q.size -= len(chunk.pcm)
+
+		q.mu.Unlock()
+
+		var err error
+		if chunk.packets != nil {
+			err = q.w.(opusPacketPassthrough).WritePassthrough(chunk.pcm, chunk.packets)
+		} else {
+			_, err = q.w.Write(chunk.pcm)
+		}
+
+		if err != nil {
+			q.session.logger.Error("failed to write to audio session output. discarding its audio", "id", q.session.id, "output", q.name, "error", err)
+			q.discard()
+
+			return
+		}
+	}
+}
+
+func (q *outputQueue) discard() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.chunks = nil
+	q.size = 0
+}
+
+// stops accepting pcm. the queued pcm is still written to the output
+func (q *outputQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// waits for the queue to be drained after it has been closed
+func (q *outputQueue) wait() {
+	<-q.done
+}
+
+// waits up to timeout for the queue to be drained after it has been closed
+//
+// returns [ErrOutputQueueTimeout] if the output did not catch up in time. the queue keeps draining
+// in the background
+func (q *outputQueue) waitTimeout(timeout time.Duration) error {
+	select {
+	case <-q.done:
+		return nil
+	case <-time.After(timeout):
+		return ErrOutputQueueTimeout
+	}
+}
+
+// starts writing pcm to w in the background. the size of the queue is taken from the config. fails
+// with [ErrSessionDestroyed] once the session has been destroyed
+func (s *Session) newOutputQueue(name string, w io.Writer) (*outputQueue, error) {
+	bufferDuration := time.Duration(config.Get().Audio.OutputBufferMs) * time.Millisecond
+
+	q := &outputQueue{
+		session: s,
+		name:    name,
+		w:       w,
+		maxSize: int(durationToPCMBytes(bufferDuration)),
+		done:    make(chan struct{}),
+	}
+	_, q.passthrough = w.(opusPacketPassthrough)
+	q.cond = sync.NewCond(&q.mu)
+
+	err := s.addRunning()
+	if err != nil {
+		return nil, err
+	}
+
+	go q.drain()
+
+	return q, nil
+}
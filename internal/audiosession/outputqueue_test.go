@@ -0,0 +1,95 @@
+package audiosession
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blocks every write until it is released
+type stalledWriter struct {
+	mu       sync.Mutex
+	released chan struct{}
+	written  bytes.Buffer
+}
+
+func (w *stalledWriter) Write(p []byte) (n int, err error) {
+	<-w.released
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.written.Write(p)
+}
+
+func TestOutputQueue(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+
+	w := &stalledWriter{released: make(chan struct{})}
+	q, err := session.newOutputQueue("test", w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a stalled output must not block the session
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		// the output takes up to a full queue before it stalls, so more than twice that is written
+		for i := range 6 {
+			q.Write(bytes.Repeat([]byte{byte(i + 1)}, q.maxSize/2))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writes to a stalled output not to block")
+	}
+
+	if q.dropped() == 0 {
+		t.Errorf("expected the oldest audio to be dropped once the queue is full")
+	}
+
+	close(w.released)
+	q.close()
+
+	if err := q.waitTimeout(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest audio is kept
+	written := w.written.Bytes()
+	if len(written) == 0 || written[len(written)-1] != 6 {
+		t.Errorf("expected the newest audio to be written")
+	}
+}
+
+func TestOutputsAreRefusedOnceSessionDestroyed(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	session.Destroy()
+
+	// the session may already be waiting for its goroutines, so no more can be started
+	if _, err := session.newOutputQueue("test", &bytes.Buffer{}); !errors.Is(err, ErrSessionDestroyed) {
+		t.Errorf("expected the output queue to be refused with %v, got %v", ErrSessionDestroyed, err)
+	}
+
+	if _, err := addTestDiscordVoiceConnOutput(t, session, "guild"); !errors.Is(err, ErrSessionDestroyed) {
+		t.Errorf("expected the voice connection to be refused with %v, got %v", ErrSessionDestroyed, err)
+	}
+
+	if _, err := session.AddRecordingOutput(RecordingOptions{Directory: t.TempDir(), Format: RecordingFormat_WAV}); !errors.Is(err, ErrSessionDestroyed) {
+		t.Errorf("expected the recording to be refused with %v, got %v", ErrSessionDestroyed, err)
+	}
+
+	if n := len(session.Outputs()); n != 0 {
+		t.Errorf("expected no outputs to be added to the destroyed session, got %d", n)
+	}
+}
@@ -0,0 +1,321 @@
+package audiosession
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+// outputs that encode opus are sent the packets that cached audio was decoded from instead of
+// encoding the audio a second time. this is only done during ticks that play a single cached input
+// at unity volume with nothing mixed in, since only then do the packets decode to exactly the pcm
+// that the session plays
+
+// an output writer that can send the packets that pcm was decoded from instead of encoding it
+type opusPacketPassthrough interface {
+	WritePassthrough(pcm []byte, packets *cachedPackets) error
+}
+
+// an input that may be decoding its audio from cached opus packets
+type opusPacketSource interface {
+	// returns nil unless the audio that is being played is read from the audio cache
+	opusPackets() *opusPacketTrack
+}
+
+// the packets that a tick of pcm was decoded from
+type cachedPackets struct {
+	// number of bytes of pcm before the first packet. the pcm before and after the packets is
+	// encoded, since the packets that it is part of are split between ticks
+	offset  int
+	packets []codecs.Packet
+}
+
+// the opus packets that a cached source has been decoded from, by the position of the pcm that they
+// decode to. packets are decoded ahead of playback, so they are kept for as long as playback can be
+// behind decoding
+type opusPacketTrack struct {
+	mu sync.Mutex
+
+	// position of the pcm that the next packet decodes to
+	decoded int64
+
+	// the position that the source was opened at. the packet that contains it is only partly played
+	start int64
+
+	// how far playback can be behind decoding
+	window int64
+
+	// oldest first
+	packets []trackedPacket
+}
+
+type trackedPacket struct {
+	position int64
+	data     []byte
+}
+
+// firstSample is the timestamp of the first packet that will be decoded
+func newOpusPacketTrack(firstSample uint64, start time.Duration) *opusPacketTrack {
+	highWater := durationToPCMBytes(time.Duration(config.Get().Audio.JitterBufferHighWaterMs) * time.Millisecond)
+
+	return &opusPacketTrack{
+		decoded: int64(firstSample) * pcmSampleSizeBytes(),
+		start:   durationToPCMBytes(start),
+
+		// the jitter buffer reads a chunk past its high water mark, the decoder holds on to the
+		// rest of a packet, and the packets of the tick that is being played may still be looked up
+		window: highWater + jitterBufferChunkSize + opusFrameSizeBytes() + tickSizeBytes(),
+	}
+}
+
+// registers the packet that pcm was decoded from. only packets that hold one opus frame at the
+// sample rate that discord uses can be passed through
+func (t *opusPacketTrack) add(packet codecs.Packet, pcm []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	position := t.decoded
+	t.decoded += int64(len(pcm))
+
+	played := t.decoded - t.window
+
+	n := 0
+	for n < len(t.packets) && t.packets[n].position+opusFrameSizeBytes() <= played {
+		n++
+	}
+
+	t.packets = t.packets[n:]
+
+	if packet.NumSamples != opusFrameSize || len(pcm) != int(opusFrameSizeBytes()) || position < t.start {
+		return
+	}
+
+	t.packets = append(t.packets, trackedPacket{position: position, data: packet.Data})
+}
+
+// returns the packets that decode to every whole frame of the pcm from start to end. fails if any
+// of them cannot be passed through
+func (t *opusPacketTrack) packetsBetween(start, end int64) (*cachedPackets, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	frameSize := opusFrameSizeBytes()
+
+	i, _ := slices.BinarySearchFunc(t.packets, start, func(packet trackedPacket, position int64) int {
+		return cmp.Compare(packet.position, position)
+	})
+
+	if i == len(t.packets) || t.packets[i].position-start >= frameSize {
+		return nil, false
+	}
+
+	packets := &cachedPackets{offset: int(t.packets[i].position - start)}
+
+	for position := t.packets[i].position; position+frameSize <= end; position += frameSize {
+		if i == len(t.packets) || t.packets[i].position != position {
+			return nil, false
+		}
+
+		packets.packets = append(packets.packets, codecs.Packet{Data: t.packets[i].data, NumSamples: opusFrameSize, Duration: opusFrameDuration})
+		i++
+	}
+
+	return packets, len(packets.packets) > 0
+}
+
+// the cached input that the current tick plays unchanged
+type passthroughTick struct {
+	track *opusPacketTrack
+	input *BaseInput
+
+	// the position of the input before the tick
+	start int64
+
+	resolved bool
+	packets  *cachedPackets
+}
+
+// decides whether the tick that is about to be played can pass cached packets through. called
+// with the session locked, after the inputs that start during the tick have been activated
+func (s *Session) beginPassthroughTickLocked() {
+	s.passthrough = passthroughTick{}
+
+	if len(s.inputs) != 1 || len(s.schedules) > 0 || s.schedulePadded || s.volumeNode.Factor() != 1 {
+		return
+	}
+
+	source, ok := s.inputs[0].(opusPacketSource)
+	if !ok {
+		return
+	}
+
+	track := source.opusPackets()
+	input := s.inputs[0].asBase()
+
+	if track == nil || input.delay != nil {
+		return
+	}
+
+	s.passthrough = passthroughTick{track: track, input: input, start: input.positionBytes.Load()}
+}
+
+// returns the packets that pcm was decoded from if pcm is the whole tick and was played unchanged
+// from the cache. called by outputs while the tick is played, so after the input has been read
+func (s *Session) passthroughPacketsLocked(pcm []byte) *cachedPackets {
+	tick := &s.passthrough
+	if tick.track == nil {
+		return nil
+	}
+
+	if !tick.resolved {
+		tick.resolved = true
+
+		// the input played silence for part of the tick if it was paused or buffering
+		end := tick.input.positionBytes.Load()
+		if end-tick.start == tickSizeBytes() {
+			tick.packets, _ = tick.track.packetsBetween(tick.start, end)
+		}
+	}
+
+	if len(pcm) != int(tickSizeBytes()) {
+		return nil
+	}
+
+	return tick.packets
+}
+
+var _ codecs.EncoderWriter = (*opusPassthroughWriter)(nil)
+var _ opusPacketPassthrough = (*opusPassthroughWriter)(nil)
+
+// encodes pcm into opus frames, or sends the packets that cached pcm was decoded from
+type opusPassthroughWriter struct {
+	enc     codecs.EncoderWriter
+	packets *packetRestamper
+
+	// pcm that does not fill a frame yet
+	pcm []byte
+}
+
+func (w *opusPassthroughWriter) Write(p []byte) (n int, err error) {
+	w.pcm = append(w.pcm, p...)
+
+	frameSize := int(opusFrameSizeBytes())
+
+	for len(w.pcm) >= frameSize {
+		_, err = w.enc.Write(w.pcm[:frameSize])
+		w.pcm = w.pcm[frameSize:]
+
+		if err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// sends packets in place of the pcm that they decode to, and encodes the rest of pcm
+func (w *opusPassthroughWriter) WritePassthrough(pcm []byte, packets *cachedPackets) error {
+	// the pcm before the packets completes the frame that was started by the previous tick
+	_, err := w.Write(pcm[:packets.offset])
+	if err != nil {
+		return err
+	}
+
+	// if it does not, as after audio was dropped, it is padded with silence so that every frame
+	// that is sent is the same size
+	if len(w.pcm) > 0 {
+		frame := make([]byte, opusFrameSizeBytes())
+		copy(frame, w.pcm)
+		w.pcm = w.pcm[:0]
+
+		_, err = w.enc.Write(frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.packets.Write(packets.packets)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(pcm[packets.offset+len(packets.packets)*int(opusFrameSizeBytes()):])
+	return err
+}
+
+// encodes the remaining pcm into a final padded opus frame
+func (w *opusPassthroughWriter) Flush() error {
+	_, err := w.enc.Write(w.pcm)
+	w.pcm = w.pcm[:0]
+
+	if err != nil {
+		return err
+	}
+
+	return w.enc.Flush()
+}
+
+func (w *opusPassthroughWriter) Close() error {
+	return w.Flush()
+}
+
+func newOpusPassthroughWriter(w codecs.PacketWriter) (*opusPassthroughWriter, error) {
+	// discord only accepts opus, so the codec is not configurable
+	opus, err := codecs.Lookup(codecs.CodecName_Opus)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := &packetRestamper{w: w}
+
+	enc, err := opus.NewEncoder(packets, codecs.EncoderOptions{
+		NumChannels:  config.Get().Audio.NumChannels,
+		SampleRateHz: config.Get().Audio.SampleRateHz,
+		FrameSize:    opusFrameSize,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &opusPassthroughWriter{enc: enc, packets: packets}, nil
+}
+
+// numbers the packets that are written from scratch, since packets from the encoder and from the
+// cache are interleaved
+type packetRestamper struct {
+	w codecs.PacketWriter
+
+	sequenceNumber uint32
+	timestamp      uint64
+}
+
+// writes every packet unless an error is encountered
+func (r *packetRestamper) Write(p []codecs.Packet) (n int, err error) {
+	packets := make([]codecs.Packet, len(p))
+
+	for i, packet := range p {
+		packet.SequenceNumber = r.sequenceNumber
+		packet.Timestamp = r.timestamp
+		packets[i] = packet
+
+		r.sequenceNumber++
+		r.timestamp += uint64(packet.NumSamples)
+	}
+
+	for len(packets) > 0 {
+		m, err := r.w.Write(packets)
+		packets = packets[m:]
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,338 @@
+package audiosession
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+)
+
+// records the packets that an encoder writes
+type packetCapture struct {
+	mu      sync.Mutex
+	packets []codecs.Packet
+}
+
+func (c *packetCapture) Write(p []codecs.Packet) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packets = append(c.packets, p...)
+
+	return len(p), nil
+}
+
+func (c *packetCapture) data() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make([][]byte, len(c.packets))
+	for i, packet := range c.packets {
+		data[i] = packet.Data
+	}
+
+	return data
+}
+
+// a tone that is not a whole number of opus frames long
+func sinePCM(d time.Duration) []byte {
+	nSamples := durationToSamples(d) + opusFrameSize/3
+	pcm := make([]byte, 0, nSamples*pcmSampleSizeBytes())
+
+	for i := range nSamples {
+		sample := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/48000))
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+	}
+
+	return pcm
+}
+
+// encodes pcm into opus frames
+func encodeTestOpus(t *testing.T, pcm []byte) []codecs.Packet {
+	t.Helper()
+
+	opus, err := codecs.Lookup(codecs.CodecName_Opus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := &packetCapture{}
+
+	enc, err := opus.NewEncoder(encoded, codecs.EncoderOptions{NumChannels: 2, SampleRateHz: 48000, FrameSize: opusFrameSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = enc.Write(pcm)
+	if err == nil {
+		err = enc.Close()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encoded.packets
+}
+
+func packetData(packets []codecs.Packet) [][]byte {
+	data := make([][]byte, len(packets))
+	for i, packet := range packets {
+		data[i] = packet.Data
+	}
+
+	return data
+}
+
+// records the opus frames that are sent to discord
+type frameCapture struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (c *frameCapture) add(frame []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frames = append(c.frames, frame)
+}
+
+func (c *frameCapture) data() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.frames)
+}
+
+// reads the packets that the cached input is played from
+func readTestCachedPackets(t *testing.T, input *YtdlpInput) [][]byte {
+	t.Helper()
+
+	entry, err := getAudioCache(input.session.logger).Open(input.cacheKey)
+	if err != nil {
+		t.Fatalf("expected the audio to be cached, got %v", err)
+	}
+
+	defer entry.Close()
+
+	cached := make([][]byte, 0)
+	for {
+		packet, err := entry.ReadPacket()
+		if err != nil {
+			return cached
+		}
+
+		cached = append(cached, packet.Data)
+	}
+}
+
+// plays the cached input, mixed with others, to a discord output until it has stopped. returns the
+// frames that were sent to discord
+func playCachedTestInput(t *testing.T, input *YtdlpInput, others ...Input) [][]byte {
+	t.Helper()
+
+	session := input.session
+	capture := &frameCapture{}
+
+	_, err := addCapturingTestDiscordVoiceConnOutput(t, session, "guild", capture.add)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.AddInput(input)
+	for _, other := range others {
+		session.AddInput(other)
+	}
+
+	go session.StartTicking()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for input.State() != inputState_Stopped {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the cached input to finish playing")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the output trails the session by its queue
+	time.Sleep(200 * time.Millisecond)
+
+	return capture.data()
+}
+
+// number of packets that are passed through for every whole tick of cached audio that is played
+// from the start
+func wholeTickPackets(d time.Duration) int {
+	return int(durationToSamples(d)/tickSizeSamples) * tickSizeSamples / opusFrameSize
+}
+
+type sliceReader struct {
+	packets []codecs.Packet
+}
+
+func (r *sliceReader) ReadPacket() (codecs.Packet, error) {
+	if len(r.packets) == 0 {
+		return codecs.Packet{}, io.EOF
+	}
+
+	packet := r.packets[0]
+	r.packets = r.packets[1:]
+
+	return packet, nil
+}
+
+// reports whether all of want appears in got in order and without anything in between
+func containsRun(got, want [][]byte) bool {
+	for i := range got {
+		if len(got)-i < len(want) {
+			return false
+		}
+
+		if slices.EqualFunc(got[i:i+len(want)], want, bytes.Equal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestOpusPassthroughWriter(t *testing.T) {
+	initTestConfig(t)
+
+	encoded := encodeTestOpus(t, sinePCM(500*time.Millisecond))
+
+	// the ticks start part way through a packet, the same way that they do after seeking
+	start := 5 * time.Millisecond
+	track := newOpusPacketTrack(0, start)
+
+	opus, err := codecs.Lookup(codecs.CodecName_Opus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := opus.NewDecoder(&sliceReader{packets: slices.Clone(encoded)}, codecs.DecoderOptions{NumChannels: 2, SampleRateHz: 48000, OnPacket: track.add})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded bytes.Buffer
+
+	_, err = io.Copy(&decoded, dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capture := &packetCapture{}
+
+	w, err := newOpusPassthroughWriter(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nTicks := 0
+	for position := durationToPCMBytes(start); position+tickSizeBytes() <= int64(decoded.Len()); position += tickSizeBytes() {
+		packets, ok := track.packetsBetween(position, position+tickSizeBytes())
+		if !ok {
+			t.Fatalf("expected the packets of the tick at %d to be tracked", position)
+		}
+
+		// the packet that is split between two ticks is encoded again
+		if want := tickSizeSamples/opusFrameSize - 1; len(packets.packets) != want {
+			t.Fatalf("expected %d packets in the tick at %d, got %d", want, position, len(packets.packets))
+		}
+
+		err = w.WritePassthrough(decoded.Bytes()[position:position+tickSizeBytes()], packets)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nTicks++
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	framesPerTick := tickSizeSamples / opusFrameSize
+	for i := range nTicks {
+		first := i*framesPerTick + 1
+		if !containsRun(capture.data(), packetData(encoded[first:first+framesPerTick-1])) {
+			t.Errorf("expected the whole packets of tick %d to be sent without being encoded again", i)
+		}
+	}
+
+	for i := 1; i < len(capture.packets); i++ {
+		if !capture.packets[i].Follows(capture.packets[i-1]) {
+			t.Fatalf("expected packet %d to follow the previous packet, got %+v after %+v", i, capture.packets[i], capture.packets[i-1])
+		}
+	}
+
+	// one frame of every tick and the end are encoded
+	if want := nTicks*framesPerTick + 1; len(capture.packets) != want {
+		t.Errorf("expected %d packets, got %d", want, len(capture.packets))
+	}
+}
+
+func TestYtdlpInputPlaysFromCache(t *testing.T) {
+	// ytdlp does not exist, so the input can only be created from the cache
+	input := newCachedTestYtdlpInput(t)
+	cached := readTestCachedPackets(t, input)
+
+	frames := playCachedTestInput(t, input)
+
+	if input.Metadata().Title != "cached title" {
+		t.Errorf("expected the cached input to have its cached title, got %q", input.Metadata().Title)
+	}
+
+	// the last tick is not whole, so it is encoded again
+	if !containsRun(frames, cached[:wholeTickPackets(time.Second)]) {
+		t.Errorf("expected the cached packets to be played without being encoded again")
+	}
+}
+
+func TestCachedPacketsAreNotSentWhenVolumeIsChanged(t *testing.T) {
+	input := newCachedTestYtdlpInput(t)
+	cached := readTestCachedPackets(t, input)
+
+	input.session.SetVolume(0.5)
+
+	frames := playCachedTestInput(t, input)
+
+	for _, frame := range frames {
+		if slices.ContainsFunc(cached, func(packet []byte) bool { return bytes.Equal(packet, frame) }) {
+			t.Fatal("expected the cached packets not to be sent while the volume is changed")
+		}
+	}
+
+	if len(frames) == 0 {
+		t.Error("expected the input to be encoded")
+	}
+}
+
+func TestCachedPacketsAreNotSentWhileMixed(t *testing.T) {
+	input := newCachedTestYtdlpInput(t)
+	cached := readTestCachedPackets(t, input)
+
+	// a constant offset, which cannot be mistaken for the cached tone once it plays on its own
+	offset := bytes.Repeat([]byte{0, 1}, int(durationToPCMBytes(2*time.Second))/2)
+	frames := playCachedTestInput(t, input, input.session.NewMemoryInput("offset", offset))
+
+	for _, frame := range frames {
+		if slices.ContainsFunc(cached, func(packet []byte) bool { return bytes.Equal(packet, frame) }) {
+			t.Fatal("expected the cached packets not to be sent while another input is mixed in")
+		}
+	}
+
+	if len(frames) == 0 {
+		t.Error("expected the inputs to be encoded")
+	}
+}
@@ -0,0 +1,59 @@
+package audiosession
+
+import (
+	"io"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+// how many samples per channel every input plays per tick. it is a whole number of opus frames so
+// that the frames that outputs encode start on a tick
+const tickSizeSamples = 8 * opusFrameSize
+
+// how many bytes of pcm every input plays per tick
+func tickSizeBytes() int64 {
+	return tickSizeSamples * pcmSampleSizeBytes()
+}
+
+// number of bytes of pcm in one opus frame
+func opusFrameSizeBytes() int64 {
+	return opusFrameSize * pcmSampleSizeBytes()
+}
+
+// number of bytes in one sample of 16-bit pcm across all channels
+func pcmSampleSizeBytes() int64 {
+	return int64(config.Get().Audio.NumChannels) * 2
+}
+
+func pcmBytesToDuration(n int64) time.Duration {
+	nSamples := n / pcmSampleSizeBytes()
+	return time.Duration(nSamples * int64(time.Second) / int64(config.Get().Audio.SampleRateHz))
+}
+
+// rounds down to a whole number of samples
+func durationToPCMBytes(d time.Duration) int64 {
+	return durationToSamples(d) * pcmSampleSizeBytes()
+}
+
+// number of samples per channel in d, rounded down
+func durationToSamples(d time.Duration) int64 {
+	return int64(d) * int64(config.Get().Audio.SampleRateHz) / int64(time.Second)
+}
+
+func samplesToDuration(nSamples uint64) time.Duration {
+	return time.Duration(nSamples * uint64(time.Second) / uint64(config.Get().Audio.SampleRateHz))
+}
+
+// calls onRead with the number of bytes that are read
+type positionReader struct {
+	r      io.Reader
+	onRead func(n int)
+}
+
+func (r *positionReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.onRead(n)
+
+	return n, err
+}
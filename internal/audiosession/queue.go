@@ -0,0 +1,631 @@
+package audiosession
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/events"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+)
+
+// how often the position of the current track is checked to decide when to preload the next track
+const queuePreloadPollInterval = 250 * time.Millisecond
+
+var ErrQueueIndexOutOfRange = errors.New("queue index out of range")
+
+var nextTrackId atomic.Uint64
+
+// a track that is waiting in a queue. the input for the track is only created once the track
+// is about to start playing
+type Track struct {
+	Name string
+
+	// the discord user that queued the track. empty if it was not queued by a user
+	RequesterID string
+
+	// set by [Queue.Enqueue] if it is not already set
+	EnqueuedAt time.Time
+
+	// identifies the track so that a preloaded input can be matched to it
+	id       uint64
+	newInput func(s *Session) (Input, error)
+
+	// creates the input part way through the track. not set if the track can only start from the
+	// beginning
+	newInputAt func(s *Session, offset time.Duration) (Input, error)
+
+	// how to create the track again after a restart. not set if the track cannot be saved
+	source optional.Optional[TrackSource]
+}
+
+// creates a track that plays the input returned by newInput. newInput must not add the input to
+// the session, which the queue does once the track starts
+func NewTrack(name string, newInput func(s *Session) (Input, error)) Track {
+	return Track{Name: name, id: nextTrackId.Add(1), newInput: newInput}
+}
+
+// creates a track that can start part way through, which lets a restored track carry on from where
+// it was saved without starting from the beginning first
+func newSeekableTrack(name string, newInputAt func(s *Session, offset time.Duration) (Input, error)) Track {
+	t := NewTrack(name, func(s *Session) (Input, error) { return newInputAt(s, 0) })
+	t.newInputAt = newInputAt
+
+	return t
+}
+
+func (t Track) WithRequester(requesterID string) Track {
+	t.RequesterID = requesterID
+	return t
+}
+
+func (t Track) withSource(source TrackSource) Track {
+	t.source = optional.Make(source)
+	return t
+}
+
+// creates the input of the track and fills in who queued it and when. the input starts at offset
+// if the track can start part way through, otherwise it starts from the beginning
+func (t Track) createInput(s *Session, offset time.Duration) (Input, error) {
+	var input Input
+	var err error
+
+	if offset > 0 && t.newInputAt != nil {
+		input, err = t.newInputAt(s, offset)
+	} else {
+		input, err = t.newInput(s)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	input.asBase().updateMetadata(func(metadata *InputMetadata) {
+		metadata.RequesterID = t.RequesterID
+		metadata.EnqueuedAt = t.EnqueuedAt
+	})
+
+	return input, nil
+}
+
+type LoopMode byte
+
+const (
+	// tracks are played once in the order that they are queued
+	LoopMode_Off LoopMode = iota
+	// the current track is played again until it is skipped
+	LoopMode_One
+	// tracks are added back to the end of the queue once they finish
+	LoopMode_All
+)
+
+// the input of the next track, which is created before the current track ends so that it can
+// start without a gap
+type preloadedTrack struct {
+	track Track
+	input Input
+}
+
+type QueueEvent_OnCurrentTrackChanged struct {
+	Previous optional.Optional[Track]
+	Current  optional.Optional[Track]
+}
+
+// plays tracks one after another. inputs that are added to the session directly are mixed with
+// the current track, which allows sound effects to play over the queue
+type Queue struct {
+	sync.Mutex
+
+	session      *Session
+	tracks       []Track
+	current      optional.Optional[Track]
+	currentInput Input
+	preloaded    *preloadedTrack
+
+	loopMode LoopMode
+
+	// the order of shuffled tracks only depends on the seed and the changes made to the queue
+	shuffle     bool
+	shuffleSeed uint64
+	rng         *rand.Rand
+
+	// the input that is being skipped, which is not repeated by [LoopMode_One]
+	skipping Input
+
+	// set while the input of the next track is being created, during which the queue is unlocked
+	starting bool
+
+	// inputs that start along with the next track
+	withNextTrack []*ScheduledInput
+
+	OnCurrentTrackChanged *events.EventEmitter[QueueEvent_OnCurrentTrackChanged]
+}
+
+// adds a track to the end of the queue, or at a random position while shuffling. the track
+// starts playing immediately if nothing else in the queue is playing
+//
+// returns the position of the track in the queue, where 0 means that the track is playing
+func (q *Queue) Enqueue(track Track) (position int, err error) {
+	if track.EnqueuedAt.IsZero() {
+		track.EnqueuedAt = time.Now()
+	}
+
+	q.Lock()
+
+	if q.currentInput != nil || q.starting {
+		defer q.Unlock()
+
+		index := q.insertLocked(track)
+		q.discardStalePreloadLocked()
+		q.publishChangedLocked()
+
+		return index + 1, nil
+	}
+
+	err = q.startLocked(track, 0)
+	if err != nil && !errors.Is(err, ErrSessionDestroyed) {
+		// tracks that were enqueued while the input was being created would otherwise never start
+		q.startNextLocked()
+	}
+
+	current := q.current
+	if current.IsSet() {
+		q.publishChangedLocked()
+	}
+
+	q.Unlock()
+
+	if current.IsSet() {
+		q.OnCurrentTrackChanged.Broadcast(QueueEvent_OnCurrentTrackChanged{Previous: optional.None[Track](), Current: current})
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// removes the next track from the queue without playing it
+func (q *Queue) Dequeue() (track Track, ok bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.tracks) == 0 {
+		return Track{}, false
+	}
+
+	track = q.tracks[0]
+	q.tracks = q.tracks[1:]
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+
+	return track, true
+}
+
+// moves the track at index from to index to. indices are zero based and do not include the
+// track that is playing
+func (q *Queue) Move(from, to int) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if from < 0 || from >= len(q.tracks) || to < 0 || to >= len(q.tracks) {
+		return ErrQueueIndexOutOfRange
+	}
+
+	track := q.tracks[from]
+	q.tracks = slices.Insert(slices.Delete(q.tracks, from, from+1), to, track)
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+
+	return nil
+}
+
+// removes the track at index. indices are zero based and do not include the track that is playing
+func (q *Queue) Remove(index int) (Track, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if index < 0 || index >= len(q.tracks) {
+		return Track{}, ErrQueueIndexOutOfRange
+	}
+
+	track := q.tracks[index]
+	q.tracks = slices.Delete(q.tracks, index, index+1)
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+
+	return track, nil
+}
+
+// removes all tracks that are waiting to be played. the track that is playing is not stopped
+func (q *Queue) Clear() {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.tracks) == 0 {
+		return
+	}
+
+	q.tracks = q.tracks[:0]
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+}
+
+// stops the track that is playing and advances to the next track, even if the track is looping
+func (q *Queue) Skip() {
+	q.Lock()
+	input := q.currentInput
+	q.skipping = input
+	q.Unlock()
+
+	if input != nil {
+		input.Stop()
+	}
+}
+
+// schedules input to start on the same sample as the next track that starts, such as to announce
+// the track. the input must not have been added to the session
+func (q *Queue) ScheduleWithNextTrack(input Input) *ScheduledInput {
+	q.Lock()
+	defer q.Unlock()
+
+	si := &ScheduledInput{session: q.session, input: input}
+
+	// a destroyed session has already cancelled its schedules
+	if q.session.IsDestroyed() {
+		si.state = scheduleState_Cancelled
+		input.Stop()
+
+		return si
+	}
+
+	q.withNextTrack = append(q.withNextTrack, si)
+
+	return si
+}
+
+// can be changed while a track is playing, and takes effect once the track ends
+func (q *Queue) SetLoopMode(mode LoopMode) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.loopMode = mode
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+}
+
+func (q *Queue) LoopMode() LoopMode {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.loopMode
+}
+
+// shuffles the tracks that are waiting to be played. the same seed always produces the same order
+// for the same tracks. disabling shuffle keeps the current order
+func (q *Queue) SetShuffle(enabled bool, seed uint64) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.shuffle = enabled
+	q.shuffleSeed = seed
+	q.rng = nil
+
+	if enabled {
+		q.rng = rand.New(rand.NewPCG(seed, seed))
+		q.rng.Shuffle(len(q.tracks), func(i, j int) { q.tracks[i], q.tracks[j] = q.tracks[j], q.tracks[i] })
+	}
+
+	q.discardStalePreloadLocked()
+	q.publishChangedLocked()
+}
+
+// returns whether the queue is shuffled and the seed that it was shuffled with
+func (q *Queue) Shuffle() (enabled bool, seed uint64) {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.shuffle, q.shuffleSeed
+}
+
+// returns the input of the track that is playing, or nil if nothing is playing
+func (q *Queue) CurrentInput() Input {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.currentInput
+}
+
+// returns the tracks that are waiting to be played
+func (q *Queue) Tracks() []Track {
+	q.Lock()
+	defer q.Unlock()
+
+	return slices.Clone(q.tracks)
+}
+
+// returns the track that is playing
+func (q *Queue) Current() optional.Optional[Track] {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.current
+}
+
+func (q *Queue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return len(q.tracks)
+}
+
+// starts track at offset, or from the beginning if it cannot start part way through. starting
+// ytdlp and ffmpeg can take a while, so the queue is unlocked while the input is created. nothing
+// else is started in the meantime and tracks that are enqueued wait for this one
+func (q *Queue) startLocked(track Track, offset time.Duration) error {
+	input := q.takePreloadedLocked(track)
+
+	if input == nil {
+		var err error
+
+		q.starting = true
+		q.Unlock()
+
+		input, err = track.createInput(q.session, offset)
+
+		q.Lock()
+		q.starting = false
+
+		if err != nil {
+			return err
+		}
+
+		// the session may have been destroyed while the input was being created
+		if q.session.IsDestroyed() {
+			input.Stop()
+			return ErrSessionDestroyed
+		}
+	}
+
+	q.current = optional.Make(track)
+	q.currentInput = input
+
+	// the delegate is added before the input is added to the session so that an input that stops
+	// during its first tick still advances the queue
+	input.OnStoppedEvent().AddDelegate(func(struct{}) { q.onInputStopped(input) })
+
+	q.session.addInputWithSchedules(input, q.withNextTrack)
+	q.withNextTrack = nil
+
+	metadata := input.Metadata()
+	q.session.logger.Info("started queued track", "id", q.session.id, "title", metadata.Title, "sourceUrl", metadata.SourceURL, "requesterId", metadata.RequesterID, "waited", time.Since(metadata.EnqueuedAt))
+
+	// a preloaded input can stop before the delegate was added. the queue is locked, so it has to
+	// be advanced asynchronously
+	if input.State() == inputState_Stopped {
+		q.session.running.Add(1)
+
+		go func() {
+			defer q.session.running.Done()
+			q.onInputStopped(input)
+		}()
+	}
+
+	q.session.running.Add(1)
+
+	go func() {
+		defer q.session.running.Done()
+		q.preloadBeforeEnd(input)
+	}()
+
+	return nil
+}
+
+// returns the preloaded input of track, or nil if track was not preloaded or its input has
+// already stopped. any other preloaded input is discarded
+func (q *Queue) takePreloadedLocked(track Track) Input {
+	preloaded := q.preloaded
+	q.preloaded = nil
+
+	if preloaded == nil {
+		return nil
+	}
+
+	if preloaded.track.id != track.id || preloaded.input.State() == inputState_Stopped {
+		preloaded.input.Stop()
+		return nil
+	}
+
+	q.session.logger.Debug("starting preloaded track", "track", track.Name)
+
+	return preloaded.input
+}
+
+// stops the preloaded input if its track is no longer the next track
+func (q *Queue) discardStalePreloadLocked() {
+	if q.preloaded == nil {
+		return
+	}
+
+	if next, ok := q.nextLocked(); ok && next.id == q.preloaded.track.id {
+		return
+	}
+
+	q.preloaded.input.Stop()
+	q.preloaded = nil
+}
+
+// returns the track that will play once the current track ends, assuming that it is not skipped
+func (q *Queue) nextLocked() (Track, bool) {
+	switch {
+	case q.loopMode == LoopMode_One && q.current.IsSet():
+		return q.current.Get(), true
+	case len(q.tracks) > 0:
+		return q.tracks[0], true
+	case q.loopMode == LoopMode_All && q.current.IsSet():
+		return q.current.Get(), true
+	default:
+		return Track{}, false
+	}
+}
+
+// adds a track to the end of the queue, or at a random position while shuffling. returns the
+// index of the track
+func (q *Queue) insertLocked(track Track) int {
+	index := len(q.tracks)
+	if q.shuffle {
+		index = q.rng.IntN(len(q.tracks) + 1)
+	}
+
+	q.tracks = slices.Insert(q.tracks, index, track)
+
+	return index
+}
+
+// waits until input is within the configured preload time of its end and then preloads the next
+// track. returns once the input stops. inputs without a known duration never trigger a preload
+func (q *Queue) preloadBeforeEnd(input Input) {
+	lead := time.Duration(config.Get().Audio.QueuePreloadMs) * time.Millisecond
+	if lead <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(queuePreloadPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if input.State() == inputState_Stopped {
+			return
+		}
+
+		duration := input.Duration()
+		if duration.IsSet() && duration.Get()-input.Position() <= lead {
+			q.preloadNext(input)
+			return
+		}
+	}
+}
+
+// creates the input of the next track while current is still playing
+func (q *Queue) preloadNext(current Input) {
+	q.Lock()
+
+	track, ok := q.nextLocked()
+	if q.preloaded != nil || !ok || q.currentInput == nil || !q.currentInput.Equals(current) {
+		q.Unlock()
+		return
+	}
+
+	q.Unlock()
+
+	// starting ytdlp and ffmpeg can take a while, so the queue is not locked in the meantime
+	input, err := track.createInput(q.session, 0)
+	if err != nil {
+		// the track is tried again once it starts, which reports the error
+		q.session.logger.Warn("failed to preload queued track", "track", track.Name, "error", err)
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	// the queue may have moved on while the input was being created
+	next, ok := q.nextLocked()
+	if q.preloaded != nil || !ok || next.id != track.id || q.currentInput == nil || !q.currentInput.Equals(current) {
+		input.Stop()
+		return
+	}
+
+	q.preloaded = &preloadedTrack{track: track, input: input}
+	q.session.logger.Debug("preloaded queued track", "track", track.Name)
+}
+
+// starts the first track in the queue that can be started
+func (q *Queue) startNextLocked() {
+	for len(q.tracks) > 0 {
+		track := q.tracks[0]
+		q.tracks = q.tracks[1:]
+
+		err := q.startLocked(track, 0)
+		if err == nil || errors.Is(err, ErrSessionDestroyed) {
+			return
+		}
+
+		q.session.logger.Error("failed to start queued track. skipping to the next track", "track", track.Name, "error", err)
+	}
+}
+
+func (q *Queue) onInputStopped(input Input) {
+	q.Lock()
+
+	if q.currentInput == nil || !q.currentInput.Equals(input) {
+		q.Unlock()
+		return
+	}
+
+	previous := q.current
+	q.current = optional.None[Track]()
+	q.currentInput = nil
+
+	skipped := q.skipping != nil && q.skipping.Equals(input)
+	q.skipping = nil
+
+	// a destroyed session stops its inputs, which must not start them again. a failed input is not
+	// repeated so that it does not fail over and over
+	destroyed := q.session.IsDestroyed()
+
+	switch {
+	case destroyed:
+	case q.loopMode == LoopMode_One && !skipped && input.Err() == nil:
+		q.tracks = slices.Insert(q.tracks, 0, previous.Get())
+	case q.loopMode == LoopMode_All:
+		q.requeueLocked(previous.Get())
+	}
+
+	q.startNextLocked()
+	current := q.current
+	q.publishChangedLocked()
+
+	q.Unlock()
+
+	// the next track is started before the previous input is removed so that the session never
+	// runs out of inputs between tracks
+	q.session.RemoveInput(input)
+
+	q.OnCurrentTrackChanged.Broadcast(QueueEvent_OnCurrentTrackChanged{Previous: previous, Current: current})
+}
+
+// adds a finished track back to the queue. while shuffling, the track is never put first so that
+// it does not play twice in a row unless it is the only track
+func (q *Queue) requeueLocked(track Track) {
+	index := len(q.tracks)
+	if q.shuffle && len(q.tracks) > 0 {
+		index = 1 + q.rng.IntN(len(q.tracks))
+	}
+
+	q.tracks = slices.Insert(q.tracks, index, track)
+}
+
+func (q *Queue) publishChangedLocked() {
+	q.session.Events.publish(QueueEvent_Changed{
+		SessionEventHeader: newSessionEventHeader(q.session),
+		Current:            q.current,
+		Tracks:             slices.Clone(q.tracks),
+		LoopMode:           q.loopMode,
+		Shuffle:            q.shuffle,
+	})
+}
+
+func newQueue(session *Session) *Queue {
+	return &Queue{
+		session:               session,
+		tracks:                make([]Track, 0),
+		current:               optional.None[Track](),
+		OnCurrentTrackChanged: events.NewEventEmitter[QueueEvent_OnCurrentTrackChanged](),
+	}
+}
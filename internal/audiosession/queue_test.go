@@ -0,0 +1,339 @@
+package audiosession
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+// the session is destroyed once the test ends. its background work is waited for so that it does
+// not outlive the config of the test
+func newTestSession(t *testing.T) *Session {
+	session := newSession(logging.NewLogger(), "test")
+
+	t.Cleanup(func() {
+		session.Destroy()
+		session.Wait()
+	})
+
+	return session
+}
+
+// a track of silence that counts how many times its input is created
+func newTestTrack(name string, nCreated *int) Track {
+	pcm := make([]byte, 0x10000)
+
+	return NewTrack(name, func(s *Session) (Input, error) {
+		*nCreated++
+		return s.NewMemoryInput(name, pcm), nil
+	})
+}
+
+func TestQueuePreload(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+
+	queue := session.Queue()
+
+	var nCreatedA, nCreatedB, nCreatedC int
+
+	_, err := queue.Enqueue(newTestTrack("a", &nCreatedA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue.Enqueue(newTestTrack("b", &nCreatedB))
+	queue.Enqueue(newTestTrack("c", &nCreatedC))
+
+	inputA := queue.currentInput
+	queue.preloadNext(inputA)
+
+	if nCreatedB != 1 || queue.preloaded == nil || queue.preloaded.track.Name != "b" {
+		t.Fatalf("expected track b to be preloaded")
+	}
+
+	preloadedB := queue.preloaded.input
+
+	queue.Skip()
+
+	if current := queue.Current(); !current.IsSet() || current.Get().Name != "b" {
+		t.Fatalf("expected track b to be playing after skipping")
+	}
+
+	if nCreatedB != 1 || !queue.currentInput.Equals(preloadedB) {
+		t.Errorf("expected the preloaded input of track b to be played instead of creating a new one")
+	}
+
+	// a preloaded track that is removed from the front of the queue is discarded
+	queue.preloadNext(preloadedB)
+
+	if queue.preloaded == nil || queue.preloaded.track.Name != "c" {
+		t.Fatalf("expected track c to be preloaded")
+	}
+
+	preloadedC := queue.preloaded.input
+
+	if _, err := queue.Remove(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if queue.preloaded != nil || preloadedC.State() != inputState_Stopped {
+		t.Errorf("expected the preloaded input of a removed track to be stopped")
+	}
+}
+
+func trackNames(tracks []Track) []string {
+	names := make([]string, len(tracks))
+	for i, track := range tracks {
+		names[i] = track.Name
+	}
+
+	return names
+}
+
+func TestQueueLoopAndShuffle(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+
+	queue := session.Queue()
+
+	var nCreated int
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		queue.Enqueue(newTestTrack(name, &nCreated))
+	}
+
+	queue.SetShuffle(true, 42)
+	shuffled := trackNames(queue.Tracks())
+	queue.SetShuffle(false, 0)
+
+	if !slices.Equal(trackNames(queue.Tracks()), shuffled) {
+		t.Errorf("expected disabling shuffle to keep the shuffled order")
+	}
+
+	// the same seed shuffles the same tracks into the same order
+	queue.Clear()
+	for _, name := range []string{"b", "c", "d", "e", "f"} {
+		queue.Enqueue(newTestTrack(name, &nCreated))
+	}
+
+	queue.SetShuffle(true, 42)
+	if names := trackNames(queue.Tracks()); !slices.Equal(names, shuffled) {
+		t.Errorf("expected seed 42 to reproduce %v, got %v", shuffled, names)
+	}
+
+	queue.SetShuffle(false, 0)
+
+	// the playing track repeats when it ends, but not when it is skipped
+	queue.SetLoopMode(LoopMode_One)
+	current := queue.Current().Get().Name
+
+	queue.currentInput.Stop()
+	if name := queue.Current().Get().Name; name != current {
+		t.Errorf("expected track %s to repeat, got %s", current, name)
+	}
+
+	next := queue.Tracks()[0].Name
+	queue.Skip()
+	if name := queue.Current().Get().Name; name != next {
+		t.Errorf("expected skipping to advance to track %s, got %s", next, name)
+	}
+
+	// the track that ended goes back to the end of the queue
+	queue.SetLoopMode(LoopMode_All)
+	queue.Skip()
+
+	if tracks := queue.Tracks(); tracks[len(tracks)-1].Name != next {
+		t.Errorf("expected track %s to be requeued, got %v", next, trackNames(tracks))
+	}
+}
+
+func TestQueueTrackMetadata(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	queue := session.Queue()
+
+	var nCreated int
+	queue.Enqueue(newTestTrack("a", &nCreated).WithRequester("1234"))
+
+	metadata := queue.CurrentInput().Metadata()
+
+	if metadata.Title != "a" || metadata.RequesterID != "1234" || metadata.EnqueuedAt.IsZero() {
+		t.Errorf("expected the input to describe the track that it was created for, got %+v", metadata)
+	}
+
+	if !metadata.Duration.IsSet() {
+		t.Errorf("expected the duration of a memory input to be known")
+	}
+}
+
+func TestQueueEditing(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	queue := session.Queue()
+
+	var nCreated int
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		position, err := queue.Enqueue(newTestTrack(name, &nCreated))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if position != i {
+			t.Errorf("expected track %s to be enqueued at position %d, got %d", name, i, position)
+		}
+	}
+
+	if current := queue.Current(); !current.IsSet() || current.Get().Name != "a" {
+		t.Fatalf("expected the first track to start playing")
+	}
+
+	// only the track that starts playing has its input created
+	if nCreated != 1 {
+		t.Errorf("expected 1 input to be created, got %d", nCreated)
+	}
+
+	err := queue.Move(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := trackNames(queue.Tracks()); !slices.Equal(names, []string{"c", "d", "b", "e"}) {
+		t.Errorf("expected track b to be moved to index 2, got %v", names)
+	}
+
+	track, ok := queue.Dequeue()
+	if !ok || track.Name != "c" {
+		t.Errorf("expected track c to be dequeued, got %q", track.Name)
+	}
+
+	track, err = queue.Remove(1)
+	if err != nil || track.Name != "b" {
+		t.Errorf("expected track b to be removed, got %q and %v", track.Name, err)
+	}
+
+	if names := trackNames(queue.Tracks()); !slices.Equal(names, []string{"d", "e"}) {
+		t.Errorf("expected tracks d and e to be left, got %v", names)
+	}
+
+	for _, indices := range [][2]int{{-1, 0}, {0, 2}, {2, 0}} {
+		if err := queue.Move(indices[0], indices[1]); !errors.Is(err, ErrQueueIndexOutOfRange) {
+			t.Errorf("expected moving %d to %d to fail with %v, got %v", indices[0], indices[1], ErrQueueIndexOutOfRange, err)
+		}
+	}
+
+	if _, err := queue.Remove(2); !errors.Is(err, ErrQueueIndexOutOfRange) {
+		t.Errorf("expected removing index 2 to fail with %v, got %v", ErrQueueIndexOutOfRange, err)
+	}
+
+	// clearing the queue does not stop the track that is playing
+	queue.Clear()
+
+	if queue.Len() != 0 {
+		t.Errorf("expected the queue to be empty, got %v", trackNames(queue.Tracks()))
+	}
+
+	if _, ok := queue.Dequeue(); ok {
+		t.Errorf("expected nothing to be dequeued from an empty queue")
+	}
+
+	if queue.CurrentInput() == nil || queue.CurrentInput().State() == inputState_Stopped {
+		t.Errorf("expected track a to keep playing")
+	}
+}
+
+func TestQueueAdvancesWhenTrackEnds(t *testing.T) {
+	initTestConfig(t)
+	initTestTelemetry(t)
+
+	session := newTestSession(t)
+	queue := session.Queue()
+
+	var nCreated int
+	queue.Enqueue(newTestTrack("a", &nCreated))
+	queue.Enqueue(newTestTrack("b", &nCreated))
+
+	changes := make(chan QueueEvent_OnCurrentTrackChanged, 2)
+	queue.OnCurrentTrackChanged.AddDelegate(func(event QueueEvent_OnCurrentTrackChanged) { changes <- event })
+
+	go session.StartTicking()
+
+	for _, want := range [][2]string{{"a", "b"}, {"b", ""}} {
+		select {
+		case event := <-changes:
+			current := ""
+			if event.Current.IsSet() {
+				current = event.Current.Get().Name
+			}
+
+			if !event.Previous.IsSet() || event.Previous.Get().Name != want[0] || current != want[1] {
+				t.Errorf("expected the queue to advance from %q to %q, got %+v", want[0], want[1], event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the queue to advance once track %s ended", want[0])
+		}
+	}
+
+	if queue.CurrentInput() != nil || queue.Len() != 0 {
+		t.Errorf("expected the queue to be empty once every track ended")
+	}
+}
+
+func TestQueueIsUnlockedWhileStartingTrack(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	queue := session.Queue()
+
+	creating := make(chan struct{})
+	release := make(chan struct{})
+
+	slow := NewTrack("slow", func(s *Session) (Input, error) {
+		close(creating)
+		<-release
+
+		return s.NewMemoryInput("slow", make([]byte, 0x10000)), nil
+	})
+
+	enqueued := make(chan error, 1)
+	go func() {
+		_, err := queue.Enqueue(slow)
+		enqueued <- err
+	}()
+
+	<-creating
+
+	// the queue can be used while the input is created, and tracks that are enqueued in the
+	// meantime wait for the track that is starting
+	var nCreated int
+
+	position, err := queue.Enqueue(newTestTrack("a", &nCreated))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if position != 1 || nCreated != 0 {
+		t.Errorf("expected track a to wait for the track that is starting, got position %d", position)
+	}
+
+	close(release)
+
+	err = <-enqueued
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if current := queue.Current(); !current.IsSet() || current.Get().Name != "slow" {
+		t.Errorf("expected the slow track to be playing")
+	}
+
+	if names := trackNames(queue.Tracks()); !slices.Equal(names, []string{"a"}) {
+		t.Errorf("expected track a to be queued, got %v", names)
+	}
+}
@@ -0,0 +1,498 @@
+package audiosession
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/events"
+)
+
+type RecordingFormat string
+
+const (
+	RecordingFormat_WAV     RecordingFormat = "wav"
+	RecordingFormat_OggOpus RecordingFormat = "opus"
+	RecordingFormat_FLAC    RecordingFormat = "flac"
+)
+
+const recordingSidecarExt = ".json"
+
+var ErrUnsupportedRecordingFormat = errors.New("unsupported recording format")
+
+// the registered codec that encodes each recording format
+var recordingCodecNames = map[RecordingFormat]string{
+	RecordingFormat_WAV:     codecs.CodecName_PCMS16LE,
+	RecordingFormat_OggOpus: codecs.CodecName_Opus,
+	RecordingFormat_FLAC:    codecs.CodecName_FLAC,
+}
+
+type RecordingOptions struct {
+	Directory string
+	Format    RecordingFormat
+
+	// a new file is started once the current file reaches either limit. 0 disables the limit
+	MaxSizeBytes int64
+	MaxDuration  time.Duration
+}
+
+// the sidecar that is written next to each recording
+type recordingMetadata struct {
+	File         string                   `json:"file"`
+	Format       RecordingFormat          `json:"format"`
+	SampleRateHz int                      `json:"sampleRateHz"`
+	NumChannels  int                      `json:"numChannels"`
+	StartedAt    time.Time                `json:"startedAt"`
+	DurationMs   int64                    `json:"durationMs"`
+	Inputs       []recordingInputMetadata `json:"inputs"`
+}
+
+// when an input played, relative to the start of the recording
+type recordingInputMetadata struct {
+	Kind    string `json:"kind"`
+	Title   string `json:"title"`
+	Source  string `json:"source"`
+	StartMs int64  `json:"startMs"`
+	EndMs   int64  `json:"endMs"`
+
+	input Input
+
+	// the session sample indices that the input started and stopped playing at. end is -1 while
+	// the input is playing
+	start int64
+	end   int64
+}
+
+// writes everything that the session plays to files. nothing is written until recording is started
+type RecordingOutput struct {
+	*BaseOutput
+
+	opts  RecordingOptions
+	codec codecs.Codec
+	queue *outputQueue
+
+	mu     sync.Mutex
+	file   *recordingFile
+	nFiles int
+	files  []string
+
+	onInputAddedHandle   events.DelegateHandle
+	onInputRemovedHandle events.DelegateHandle
+}
+
+type recordingFile struct {
+	f        *os.File
+	buffered *bufio.Writer
+	enc      codecs.EncoderWriter
+
+	nBytes    int64 // bytes written to the file, including headers
+	nPCMBytes int64 // bytes of pcm that were encoded
+	metadata  recordingMetadata
+
+	// the session sample index of the first sample in the file
+	startIndex int64
+
+	// every input that played while the file was recorded. they are written to the sidecar once
+	// the file is finished, since the file lags behind the session by however much audio is queued
+	inputs []recordingInputMetadata
+}
+
+// counts the bytes that are written to the file so that it can be rotated by size
+func (f *recordingFile) Write(p []byte) (n int, err error) {
+	n, err = f.buffered.Write(p)
+	f.nBytes += int64(n)
+
+	return n, err
+}
+
+func (f *recordingFile) position() time.Duration {
+	return pcmBytesToDuration(f.nPCMBytes)
+}
+
+// the session sample index that the next sample in the file will have
+func (f *recordingFile) endIndex() int64 {
+	return f.startIndex + f.nPCMBytes/pcmSampleSizeBytes()
+}
+
+// starts recording to a new file. does nothing if the output is already recording
+func (o *RecordingOutput) Start() error {
+	// the session is locked while ticking, which writes to the output, so it has to be locked first
+	playing := o.session.Inputs()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file != nil {
+		return nil
+	}
+
+	// the file starts with the audio that is already queued, which the session played before now
+	startIndex := o.session.clock.sampleIndex() - o.queue.buffered()/pcmSampleSizeBytes()
+
+	inputs := make([]recordingInputMetadata, 0, len(playing))
+	for _, input := range playing {
+		inputs = append(inputs, newRecordingInputMetadata(input, startIndex))
+	}
+
+	return o.openFile(startIndex, inputs)
+}
+
+// finishes the current file. recording can be started again afterwards
+func (o *RecordingOutput) Stop() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return nil
+	}
+
+	_, err := o.closeFile()
+	return err
+}
+
+func (o *RecordingOutput) IsRecording() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.file != nil
+}
+
+// returns the paths of every file that has been recorded to, including the current file
+func (o *RecordingOutput) Files() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	files := make([]string, len(o.files))
+	copy(files, o.files)
+
+	return files
+}
+
+// returns how much audio was left out of the recording because writing the files fell behind
+func (o *RecordingOutput) Dropped() time.Duration {
+	return o.queue.dropped()
+}
+
+func (o *RecordingOutput) followsPlayers() {}
+
+// writes the audio that is still queued and stops recording
+func (o *RecordingOutput) Close() error {
+	o.session.OnInputAdded.RemoveDelegate(o.onInputAddedHandle)
+	o.session.OnInputRemoved.RemoveDelegate(o.onInputRemovedHandle)
+
+	o.queue.close()
+	o.queue.wait()
+
+	return o.Stop()
+}
+
+// encodes pcm from the session into the current file. called from the output queue, so a slow disk
+// does not hold up the session
+func (o *RecordingOutput) Write(p []byte) (n int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return len(p), nil
+	}
+
+	_, err = o.file.enc.Write(p)
+	o.file.nPCMBytes += int64(len(p))
+
+	if err != nil {
+		o.session.logger.Error("failed to write recording. stopping recording", "file", o.file.metadata.File, "error", err)
+		o.closeFile()
+
+		return len(p), nil
+	}
+
+	sizeExceeded := o.opts.MaxSizeBytes > 0 && o.file.nBytes >= o.opts.MaxSizeBytes
+	durationExceeded := o.opts.MaxDuration > 0 && o.file.position() >= o.opts.MaxDuration
+
+	if sizeExceeded || durationExceeded {
+		endIndex := o.file.endIndex()
+
+		playing, err := o.closeFile()
+		if err != nil {
+			o.session.logger.Warn("failed to finish recording while rotating", "error", err)
+		}
+
+		// the inputs that were playing continue in the next file
+		err = o.openFile(endIndex, playing)
+		if err != nil {
+			o.session.logger.Error("failed to rotate recording. stopping recording", "error", err)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (o *RecordingOutput) onInputAdded(param SessionEvent_OnInputAdded) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return
+	}
+
+	// the input starts playing in the next tick
+	o.file.inputs = append(o.file.inputs, newRecordingInputMetadata(param.InputAdded, o.session.clock.sampleIndex()))
+}
+
+func (o *RecordingOutput) onInputRemoved(param SessionEvent_OnInputRemoved) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return
+	}
+
+	end := o.session.clock.sampleIndex()
+
+	for idx, entry := range o.file.inputs {
+		if entry.end == -1 && entry.input.Equals(param.InputRemoved) {
+			o.file.inputs[idx].end = end
+		}
+	}
+}
+
+// starts a file whose first sample is the session sample at startIndex. inputs are the inputs that
+// play in it so far. must be called with o.mu locked
+func (o *RecordingOutput) openFile(startIndex int64, inputs []recordingInputMetadata) error {
+	err := os.MkdirAll(o.opts.Directory, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	startedAt := time.Now()
+	name := fmt.Sprintf("%s-%s-%d.%s", o.session.ID(), startedAt.UTC().Format("20060102T150405Z"), o.nFiles, o.opts.Format)
+	path := filepath.Join(o.opts.Directory, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	file := &recordingFile{
+		f:        f,
+		buffered: bufio.NewWriter(f),
+		metadata: recordingMetadata{
+			File:         name,
+			Format:       o.opts.Format,
+			SampleRateHz: config.Get().Audio.SampleRateHz,
+			NumChannels:  config.Get().Audio.NumChannels,
+			StartedAt:    startedAt,
+		},
+		startIndex: startIndex,
+		inputs:     inputs,
+	}
+
+	// wav and flac rewrite their headers when they are closed, so they need to be able to seek
+	file.enc, err = o.codec.NewFileEncoder(&seekableRecordingFile{file}, codecs.EncoderOptions{
+		NumChannels:  config.Get().Audio.NumChannels,
+		SampleRateHz: config.Get().Audio.SampleRateHz,
+		FrameSize:    opusFrameSize,
+	})
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+
+		return fmt.Errorf("failed to create recording encoder: %w", err)
+	}
+
+	o.file = file
+	o.nFiles++
+	o.files = append(o.files, path)
+
+	o.session.logger.Info("started recording", "session", o.session.ID(), "file", path)
+
+	return nil
+}
+
+// finishes the current file and writes its sidecar. returns the inputs that were still playing at
+// the end of the file. must be called with o.mu locked
+func (o *RecordingOutput) closeFile() (playing []recordingInputMetadata, err error) {
+	file := o.file
+	o.file = nil
+
+	encErr := file.enc.Close()
+	flushErr := file.buffered.Flush()
+	closeErr := file.f.Close()
+
+	file.metadata.DurationMs = file.position().Milliseconds()
+	file.metadata.Inputs = make([]recordingInputMetadata, 0, len(file.inputs))
+
+	endIndex := file.endIndex()
+
+	for _, entry := range file.inputs {
+		if entry.end == -1 || entry.end > endIndex {
+			playing = append(playing, entry)
+		}
+
+		// inputs that were added after the last sample in the file start in the next one
+		if entry.start >= endIndex {
+			continue
+		}
+
+		entry.StartMs = samplesToDuration(uint64(max(entry.start-file.startIndex, 0))).Milliseconds()
+		entry.EndMs = file.metadata.DurationMs
+
+		if entry.end != -1 && entry.end <= endIndex {
+			entry.EndMs = samplesToDuration(uint64(max(entry.end-file.startIndex, 0))).Milliseconds()
+		}
+
+		file.metadata.Inputs = append(file.metadata.Inputs, entry)
+	}
+
+	sidecarErr := writeRecordingSidecar(file.f.Name(), file.metadata)
+
+	o.session.logger.Info("finished recording", "session", o.session.ID(), "file", file.f.Name(), "duration", file.position())
+
+	return playing, errors.Join(encErr, flushErr, closeErr, sidecarErr)
+}
+
+func writeRecordingSidecar(path string, metadata recordingMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording metadata: %w", err)
+	}
+
+	sidecarPath := strings.TrimSuffix(path, filepath.Ext(path)) + recordingSidecarExt
+
+	err = os.WriteFile(sidecarPath, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write recording metadata: %w", err)
+	}
+
+	return nil
+}
+
+// lets encoders rewrite their headers. the buffer is flushed before seeking so that the file
+// position matches everything that has been written
+type seekableRecordingFile struct {
+	*recordingFile
+}
+
+func (f *seekableRecordingFile) Seek(offset int64, whence int) (int64, error) {
+	err := f.buffered.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	return f.f.Seek(offset, whence)
+}
+
+// start is the session sample index that the input started playing at
+func newRecordingInputMetadata(input Input, start int64) recordingInputMetadata {
+	info := DescribeInput(input)
+
+	return recordingInputMetadata{
+		Kind:   info.Kind,
+		Title:  info.Metadata.Title,
+		Source: info.Metadata.SourceURL,
+		input:  input,
+		start:  start,
+		end:    -1,
+	}
+}
+
+// the options of recordings that are started from discord. ok is false if recording.directory is not
+// configured
+func ConfiguredRecordingOptions() (opts RecordingOptions, ok bool) {
+	cfg := config.Get().Recording
+	if !cfg.Directory.IsSet() {
+		return RecordingOptions{}, false
+	}
+
+	return RecordingOptions{
+		Directory:    cfg.Directory.Get(),
+		Format:       RecordingFormat(cfg.Format),
+		MaxSizeBytes: cfg.MaxSizeBytes,
+		MaxDuration:  time.Duration(cfg.MaxDurationMs) * time.Millisecond,
+	}, true
+}
+
+// returns the recording output of the session, if it has one
+func (s *Session) RecordingOutput() (*RecordingOutput, bool) {
+	for _, o := range s.Outputs() {
+		if ro, ok := o.(*RecordingOutput); ok {
+			return ro, true
+		}
+	}
+
+	return nil, false
+}
+
+var recordingOutputsMu sync.Mutex
+
+// returns the recording output of the session, adding one that records with opts if it does not
+// have one yet
+func (s *Session) GetOrAddRecordingOutput(opts RecordingOptions) (*RecordingOutput, error) {
+	recordingOutputsMu.Lock()
+	defer recordingOutputsMu.Unlock()
+
+	if output, ok := s.RecordingOutput(); ok {
+		return output, nil
+	}
+
+	return s.AddRecordingOutput(opts)
+}
+
+// add an output that records the session to files in opts.Directory. recording has to be started
+// with [RecordingOutput.Start]
+func (s *Session) AddRecordingOutput(opts RecordingOptions) (*RecordingOutput, error) {
+	codec, err := recordingCodec(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &RecordingOutput{opts: opts, codec: codec}
+
+	queue, err := s.newOutputQueue("recording", output)
+	if err != nil {
+		return nil, err
+	}
+
+	output.queue = queue
+	output.BaseOutput = NewBaseOutput(s, audio.NewWriterNode(s.logger, output.queue))
+
+	output.onInputAddedHandle = s.OnInputAdded.AddDelegate(output.onInputAdded)
+	output.onInputRemovedHandle = s.OnInputRemoved.AddDelegate(output.onInputRemoved)
+
+	// the session may have been destroyed while the output was being created
+	err = s.tryAddOutput(output)
+	if err != nil {
+		output.Close()
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// finds the registered codec that writes files in format
+func recordingCodec(format RecordingFormat) (codecs.Codec, error) {
+	name, ok := recordingCodecNames[format]
+	if !ok {
+		return codecs.Codec{}, ErrUnsupportedRecordingFormat
+	}
+
+	codec, err := codecs.Lookup(name)
+	if err != nil {
+		return codecs.Codec{}, fmt.Errorf("%w: %w", ErrUnsupportedRecordingFormat, err)
+	}
+
+	if codec.NewFileEncoderWriter == nil {
+		return codecs.Codec{}, fmt.Errorf("%w: %s has no file format", ErrUnsupportedRecordingFormat, name)
+	}
+
+	return codec, nil
+}
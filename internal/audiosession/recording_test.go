@@ -0,0 +1,254 @@
+package audiosession
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRecordingOutput(t *testing.T, session *Session, opts RecordingOptions) *RecordingOutput {
+	t.Helper()
+
+	opts.Directory = t.TempDir()
+	opts.Format = RecordingFormat_WAV
+
+	output, err := session.AddRecordingOutput(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return output
+}
+
+// plays d of silence in the session and writes it to the recording. the recording is written to
+// directly, which is what its output queue does once the audio reaches the front of the queue
+func playToRecording(t *testing.T, session *Session, output *RecordingOutput, d time.Duration) {
+	t.Helper()
+
+	playInSession(session, d)
+	writeToRecording(t, output, d)
+}
+
+// the session plays audio ahead of the recording, as if the audio were still queued
+func playInSession(session *Session, d time.Duration) {
+	session.clock.Write(make([]byte, durationToPCMBytes(d)))
+}
+
+// writes d of silence in 20ms chunks, which is how often a file can be rotated
+func writeToRecording(t *testing.T, output *RecordingOutput, d time.Duration) {
+	t.Helper()
+
+	for d > 0 {
+		chunk := min(d, 20*time.Millisecond)
+		d -= chunk
+
+		_, err := output.Write(make([]byte, durationToPCMBytes(chunk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readRecordingSidecar(t *testing.T, path string) recordingMetadata {
+	t.Helper()
+
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".wav") + recordingSidecarExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata recordingMetadata
+
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return metadata
+}
+
+func TestRecordingOutputRotatesByDuration(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	output := newTestRecordingOutput(t, session, RecordingOptions{MaxDuration: 100 * time.Millisecond})
+
+	err := output.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playToRecording(t, session, output, 250*time.Millisecond)
+
+	err = output.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := output.Files()
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	for i, want := range []int64{100, 100, 50} {
+		if got := readRecordingSidecar(t, files[i]).DurationMs; got != want {
+			t.Errorf("expected file %d to be %dms long, got %dms", i, want, got)
+		}
+	}
+}
+
+func TestRecordingOutputRotatesBySize(t *testing.T) {
+	initTestConfig(t)
+
+	// the limit is reached partway through the third chunk of every file
+	const maxSize = 44 + 2*0xf00*4 + 1
+
+	session := newTestSession(t)
+	output := newTestRecordingOutput(t, session, RecordingOptions{MaxSizeBytes: maxSize})
+
+	err := output.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playToRecording(t, session, output, 200*time.Millisecond)
+
+	err = output.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := output.Files()
+	if len(files) < 2 {
+		t.Fatalf("expected the recording to be split into several files, got %d", len(files))
+	}
+
+	// a file is only rotated after the write that takes it over the limit
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Size() > maxSize+durationToPCMBytes(20*time.Millisecond) {
+			t.Errorf("expected %s to be rotated once it reached %d bytes, got %d bytes", path, maxSize, info.Size())
+		}
+	}
+}
+
+func TestRecordingOutputSidecar(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	output := newTestRecordingOutput(t, session, RecordingOptions{})
+
+	err := output.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playToRecording(t, session, output, 100*time.Millisecond)
+
+	// the session is 50ms ahead of the recording when the input is added, so it starts at 150ms
+	// of the recording even though only 100ms have been written to the file
+	playInSession(session, 50*time.Millisecond)
+
+	input := session.NewMemoryInput("clip", make([]byte, durationToPCMBytes(time.Second)))
+	session.AddInput(input)
+
+	writeToRecording(t, output, 50*time.Millisecond)
+	playToRecording(t, session, output, 200*time.Millisecond)
+
+	session.RemoveInput(input)
+
+	playToRecording(t, session, output, 100*time.Millisecond)
+
+	err = output.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := readRecordingSidecar(t, output.Files()[0])
+
+	if metadata.DurationMs != 450 || metadata.Format != RecordingFormat_WAV {
+		t.Errorf("expected a 450ms wav recording, got %dms of %s", metadata.DurationMs, metadata.Format)
+	}
+
+	if len(metadata.Inputs) != 1 {
+		t.Fatalf("expected 1 input, got %d", len(metadata.Inputs))
+	}
+
+	if got := metadata.Inputs[0]; got.Title != "clip" || got.StartMs != 150 || got.EndMs != 350 {
+		t.Errorf("expected clip to play from 150ms to 350ms, got %q from %dms to %dms", got.Title, got.StartMs, got.EndMs)
+	}
+}
+
+func TestRecordingOutputStopAndStart(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	output := newTestRecordingOutput(t, session, RecordingOptions{})
+
+	for range 2 {
+		err := output.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !output.IsRecording() {
+			t.Fatal("expected the output to be recording")
+		}
+
+		playToRecording(t, session, output, 60*time.Millisecond)
+
+		err = output.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output.IsRecording() {
+			t.Fatal("expected the output to stop recording")
+		}
+
+		// audio that is played while recording is stopped is left out
+		playToRecording(t, session, output, 40*time.Millisecond)
+	}
+
+	files := output.Files()
+	if len(files) != 2 {
+		t.Fatalf("expected every start to record to a new file, got %d files", len(files))
+	}
+
+	for _, path := range files {
+		if got := readRecordingSidecar(t, path).DurationMs; got != 60 {
+			t.Errorf("expected %s to be 60ms long, got %dms", path, got)
+		}
+	}
+}
+
+func TestGetOrAddRecordingOutput(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	opts := RecordingOptions{Directory: t.TempDir(), Format: RecordingFormat_FLAC}
+
+	first, err := session.GetOrAddRecordingOutput(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := session.GetOrAddRecordingOutput(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("expected the session to keep a single recording output")
+	}
+
+	if output, ok := session.RecordingOutput(); !ok || output != first {
+		t.Errorf("expected the recording output to be found in the session")
+	}
+}
@@ -0,0 +1,153 @@
+package audiosession
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"accidentallycoded.com/fredboard/v3/internal/syncext"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var (
+	ErrSessionExists   = errors.New("audio session already exists")
+	ErrSessionNotFound = errors.New("audio session not found")
+	ErrShuttingDown    = errors.New("audio sessions are shutting down")
+)
+
+// all sessions that have not been destroyed, keyed by the id of their owner
+var allSessions = syncext.NewSyncData(make(map[string]*Session))
+
+// set once [Shutdown] is called. no sessions can be created after this
+var shuttingDown atomic.Bool
+
+// creates a session that is owned by id, such as a discord guild id
+//
+// returns [ErrSessionExists] if a session is already owned by id
+func Create(logger *logging.Logger, id string) (*Session, error) {
+	allSessions.Lock()
+	defer allSessions.Unlock()
+
+	if shuttingDown.Load() {
+		return nil, ErrShuttingDown
+	}
+
+	if _, ok := allSessions.Data[id]; ok {
+		return nil, ErrSessionExists
+	}
+
+	s := newSession(logger, id)
+	allSessions.Data[id] = s
+
+	return s, nil
+}
+
+// returns [ErrSessionNotFound] if no session is owned by id
+func Get(id string) (*Session, error) {
+	allSessions.Lock()
+	defer allSessions.Unlock()
+
+	s, ok := allSessions.Data[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return s, nil
+}
+
+// gets the session owned by id or creates it if it does not exist
+func GetOrCreate(logger *logging.Logger, id string) (s *Session, exists bool, err error) {
+	allSessions.Lock()
+	defer allSessions.Unlock()
+
+	s, ok := allSessions.Data[id]
+	if ok {
+		return s, true, nil
+	}
+
+	if shuttingDown.Load() {
+		return nil, false, ErrShuttingDown
+	}
+
+	s = newSession(logger, id)
+	allSessions.Data[id] = s
+
+	return s, false, nil
+}
+
+// destroys the session owned by id
+//
+// returns [ErrSessionNotFound] if no session is owned by id
+func Destroy(id string) error {
+	s, err := Get(id)
+	if err != nil {
+		return err
+	}
+
+	s.Destroy()
+	return nil
+}
+
+// returns all sessions that have not been destroyed, sorted by id
+func Sessions() []*Session {
+	allSessions.Lock()
+	defer allSessions.Unlock()
+
+	return slices.SortedFunc(maps.Values(allSessions.Data), func(a, b *Session) int { return strings.Compare(a.id, b.id) })
+}
+
+// saves and destroys every session and waits for their tick loops and child processes to finish. outputs are
+// flushed and voice connections are disconnected as part of destroying the sessions. sessions cannot
+// be created once shutdown has started
+//
+// returns ctx.Err() if ctx is done before all sessions have finished
+func Shutdown(ctx context.Context) error {
+	shuttingDown.Store(true)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+
+		for _, s := range Sessions() {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				err := s.saveSnapshot()
+				if err != nil {
+					s.logger.Warn("failed to save audio session snapshot", "id", s.id, "error", err)
+				}
+
+				s.Destroy()
+				s.Wait()
+			}()
+		}
+
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func unregister(s *Session) {
+	allSessions.Lock()
+	defer allSessions.Unlock()
+
+	// a new session may have been created with the same id
+	if allSessions.Data[s.id] == s {
+		delete(allSessions.Data, s.id)
+	}
+}
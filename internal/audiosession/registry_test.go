@@ -0,0 +1,210 @@
+package audiosession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+// creates a session in the registry that is destroyed once the test ends
+func createTestSession(t *testing.T, id string) *Session {
+	t.Helper()
+
+	s, err := Create(logging.NewLogger(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		s.Destroy()
+		s.Wait()
+	})
+
+	return s
+}
+
+func TestRegistry(t *testing.T) {
+	initTestConfig(t)
+
+	s := createTestSession(t, "registry")
+
+	if _, err := Create(logging.NewLogger(), "registry"); !errors.Is(err, ErrSessionExists) {
+		t.Errorf("expected creating a second session with the same id to fail with %v, got %v", ErrSessionExists, err)
+	}
+
+	got, err := Get("registry")
+	if err != nil || got != s {
+		t.Errorf("expected the session to be found by its id, got %v", err)
+	}
+
+	got, exists, err := GetOrCreate(logging.NewLogger(), "registry")
+	if err != nil || !exists || got != s {
+		t.Errorf("expected the existing session to be returned instead of creating a new one")
+	}
+
+	if _, err := Get("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected a missing session to fail with %v, got %v", ErrSessionNotFound, err)
+	}
+
+	if err := Destroy("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected destroying a missing session to fail with %v, got %v", ErrSessionNotFound, err)
+	}
+
+	err = Destroy("registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Get("registry"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected a destroyed session to be removed, got %v", err)
+	}
+
+	// the id can be used again once the session that owned it is destroyed
+	if createTestSession(t, "registry") == s {
+		t.Errorf("expected a new session to be created")
+	}
+}
+
+func TestRegistryListsSessions(t *testing.T) {
+	initTestConfig(t)
+
+	for _, id := range []string{"c", "a", "b"} {
+		createTestSession(t, id)
+	}
+
+	ids := make([]string, 0)
+	for _, s := range Sessions() {
+		ids = append(ids, s.id)
+	}
+
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("expected every session to be listed by id, got %v", ids)
+	}
+}
+
+func TestRegistryDestroysSessionWithoutOutputs(t *testing.T) {
+	initTestConfig(t)
+
+	s := createTestSession(t, "outputs")
+
+	first, err := addTestDiscordVoiceConnOutput(t, s, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := addTestDiscordVoiceConnOutput(t, s, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a recording only follows the players, so it does not keep the session alive on its own
+	recording := newTestRecordingOutput(t, s, RecordingOptions{})
+
+	s.RemoveOutput(first)
+
+	if s.IsDestroyed() {
+		t.Fatal("expected the session to be kept while it has a player")
+	}
+
+	s.RemoveOutput(second)
+
+	if !s.IsDestroyed() {
+		t.Errorf("expected the session to be destroyed once its last player was removed")
+	}
+
+	if slices.Contains(s.Outputs(), Output(recording)) {
+		t.Errorf("expected the recording to be removed with the session")
+	}
+
+	if _, err := Get("outputs"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected the session to be removed from the registry, got %v", err)
+	}
+}
+
+func TestShutdownWaitsForSessions(t *testing.T) {
+	dir := t.TempDir()
+	mediaDir := filepath.Join(dir, "media")
+	pidPath := filepath.Join(dir, "ffmpeg.pid")
+
+	// an ffmpeg that never produces any audio, so it keeps running until it is killed
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+
+	err := os.WriteFile(ffmpeg, []byte(fmt.Sprintf("#!/bin/sh\necho $$ > %q\nexec sleep 60\n", pidPath)), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.MkdirAll(mediaDir, 0o755)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(mediaDir, "clip.ogg"), nil, 0o644)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initTestConfigJSON(t, fmt.Sprintf(`{
+		"media": {"directory": %q},
+		"ffmpeg": {"exePath": %q},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`, mediaDir, ffmpeg))
+	initTestTelemetry(t)
+
+	t.Cleanup(func() { shuttingDown.Store(false) })
+
+	s := createTestSession(t, "shutdown")
+
+	_, err = s.AddFileInput("clip.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.StartTicking()
+
+	var pid int
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pid == 0 || s.State() != SessionState_Ticking {
+		if time.Now().After(deadline) {
+			t.Fatal("expected ffmpeg to start and the session to tick")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		data, err := os.ReadFile(pidPath)
+		if err == nil {
+			pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.State() != SessionState_NotTicking {
+		t.Errorf("expected the tick loop to have finished")
+	}
+
+	// ffmpeg has been waited for, so it no longer exists
+	if err := syscall.Kill(pid, 0); !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("expected ffmpeg to have exited, got %v", err)
+	}
+
+	if _, err := Create(logging.NewLogger(), "shutdown"); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected sessions not to be created during shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package audiosession
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+)
+
+type scheduleState byte
+
+const (
+	scheduleState_Pending scheduleState = iota
+	scheduleState_Started
+	scheduleState_Cancelled
+)
+
+// an input that is added to its session once playback reaches a sample index. the input starts on
+// exactly that sample unless the index had already passed when the input was activated
+type ScheduledInput struct {
+	session *Session
+	input   Input
+
+	// guarded by the session
+	sampleIndex int64
+	state       scheduleState
+}
+
+func (si *ScheduledInput) Input() Input {
+	return si.input
+}
+
+// cancels the input if it has not started and stops it. returns false if the input has already
+// started or been cancelled, in which case it is left alone
+func (si *ScheduledInput) Cancel() bool {
+	s := si.session
+
+	s.Lock()
+
+	if si.state != scheduleState_Pending {
+		s.Unlock()
+		return false
+	}
+
+	si.state = scheduleState_Cancelled
+	s.schedules = slices.DeleteFunc(s.schedules, func(other *ScheduledInput) bool { return other == si })
+	s.updateSchedulePaddingLocked()
+
+	s.Unlock()
+
+	si.input.Stop()
+
+	return true
+}
+
+// adds input to the session so that its first sample is played at sampleIndex, as returned by
+// [Session.SampleIndex]. an index that has already passed starts the input on the next tick. the
+// session starts ticking if it is not already, playing silence until the input starts
+//
+// the input must not have been added to the session
+func (s *Session) ScheduleInput(input Input, sampleIndex int64) *ScheduledInput {
+	si := &ScheduledInput{session: s, input: input}
+
+	s.Lock()
+	ok := s.scheduleLocked(si, sampleIndex)
+	ticking := s.state == SessionState_Ticking
+	s.Unlock()
+
+	if !ok {
+		input.Stop()
+		return si
+	}
+
+	if !ticking {
+		go s.StartTicking()
+	}
+
+	return si
+}
+
+// schedules input to start at t as measured by the clock of the session. see [Session.ScheduleInput]
+func (s *Session) ScheduleInputAt(input Input, t time.Time) *ScheduledInput {
+	return s.ScheduleInput(input, s.SampleIndexAt(t))
+}
+
+// returns false if the input must be stopped because the session has been destroyed
+func (s *Session) scheduleLocked(si *ScheduledInput, sampleIndex int64) bool {
+	if si.state != scheduleState_Pending {
+		return true
+	}
+
+	if s.destroyed {
+		si.state = scheduleState_Cancelled
+		return false
+	}
+
+	si.sampleIndex = sampleIndex
+	s.schedules = append(s.schedules, si)
+	s.updateSchedulePaddingLocked()
+
+	return true
+}
+
+// adds input to the session along with schedules, which start on the same sample as input
+func (s *Session) addInputWithSchedules(input Input, schedules []*ScheduledInput) {
+	s.Lock()
+
+	s.addInputLocked(input)
+
+	index := s.clock.sampleIndex()
+	cancelled := make([]Input, 0)
+
+	for _, si := range schedules {
+		if !s.scheduleLocked(si, index) {
+			cancelled = append(cancelled, si.input)
+		}
+	}
+
+	s.Unlock()
+
+	for _, c := range cancelled {
+		c.Stop()
+	}
+
+	s.onInputAdded(input)
+}
+
+// returns the index of the next sample that the session will play. the index counts every sample
+// since the session was created and never goes backwards
+func (s *Session) SampleIndex() int64 {
+	return s.clock.sampleIndex()
+}
+
+// returns the index of the sample that the session will play at t. while the session is not
+// ticking, it is assumed to start ticking now
+func (s *Session) SampleIndexAt(t time.Time) int64 {
+	s.Lock()
+	ticking := s.state == SessionState_Ticking
+	s.Unlock()
+
+	if ticking {
+		return s.clock.sampleIndexAt(t)
+	}
+
+	return s.clock.sampleIndex() + durationToSamples(time.Until(t))
+}
+
+// adds the scheduled inputs that start during the next tick to the session. the inputs are
+// returned so that their events can be published once the session is unlocked
+func (s *Session) activateSchedulesLocked() []*ScheduledInput {
+	if len(s.schedules) == 0 {
+		return nil
+	}
+
+	// a scheduled input is activated in the tick that its sample index falls within
+	tickStart := s.clock.sampleIndex()
+	tickEnd := tickStart + tickSizeSamples
+
+	activated := make([]*ScheduledInput, 0)
+
+	s.schedules = slices.DeleteFunc(s.schedules, func(si *ScheduledInput) bool {
+		if si.sampleIndex >= tickEnd {
+			return false
+		}
+
+		// the input is delayed by silence so that it starts part way through the tick
+		if delay := si.sampleIndex - tickStart; delay > 0 {
+			si.input.asBase().delayStart(delay * pcmSampleSizeBytes())
+		}
+
+		si.state = scheduleState_Started
+		s.addInputLocked(si.input)
+		activated = append(activated, si)
+
+		return true
+	})
+
+	s.updateSchedulePaddingLocked()
+
+	return activated
+}
+
+func (s *Session) publishScheduledStarts(activated []*ScheduledInput, tickStart int64) {
+	for _, si := range activated {
+		s.onInputAdded(si.input)
+
+		startIndex := max(si.sampleIndex, tickStart)
+		late := samplesToDuration(uint64(startIndex - si.sampleIndex))
+
+		if late > 0 {
+			s.logger.Warn("scheduled input started late", "id", s.id, "sampleIndex", si.sampleIndex, "late", late)
+		}
+
+		si.input.asBase().publish(func(h InputEventHeader) SessionEvent {
+			return InputEvent_ScheduledStart{InputEventHeader: h, SampleIndex: startIndex, Late: late}
+		})
+	}
+}
+
+// keeps the session ticking while inputs are waiting to start, even if nothing else is playing
+func (s *Session) updateSchedulePaddingLocked() {
+	switch {
+	case len(s.schedules) > 0 && !s.schedulePadded:
+		s.audioGraph.AddNode(s.schedulePadding)
+		s.audioGraph.CreateConnection(s.schedulePadding, s.rootMixer)
+		s.schedulePadded = true
+	case len(s.schedules) == 0 && s.schedulePadded:
+		s.audioGraph.RemoveNode(s.schedulePadding)
+		s.schedulePadded = false
+	}
+}
+
+// stops the inputs that are waiting to start. called when the session is destroyed
+func (s *Session) cancelSchedules() {
+	s.queue.Lock()
+	withNextTrack := s.queue.withNextTrack
+	s.queue.withNextTrack = nil
+	s.queue.Unlock()
+
+	s.Lock()
+
+	schedules := make([]*ScheduledInput, 0, len(s.schedules)+len(withNextTrack))
+	for _, si := range slices.Concat(s.schedules, withNextTrack) {
+		if si.state == scheduleState_Pending {
+			si.state = scheduleState_Cancelled
+			schedules = append(schedules, si)
+		}
+	}
+
+	s.schedules = nil
+	s.updateSchedulePaddingLocked()
+
+	s.Unlock()
+
+	for _, si := range schedules {
+		si.input.Stop()
+	}
+}
+
+// reads as much silence as is asked for
+type silenceReader struct{}
+
+func (silenceReader) Read(p []byte) (n int, err error) {
+	clear(p)
+	return len(p), nil
+}
+
+func newSchedulePadding(s *Session) audio.Node {
+	return audio.NewReaderNode(s.logger, silenceReader{}, tickSizeBytes())
+}
+
+var _ audio.Node = (*delayNode)(nil)
+
+// delays the audio of a node by the silence that carry starts with. the node writes as much per
+// tick as the wrapped node does so that it stays in step with the other inputs, and everything
+// that is still delayed is written once the wrapped node ends
+type delayNode struct {
+	node  audio.Node
+	carry []byte
+	err   error
+}
+
+func (node *delayNode) Tick(ctx context.Context, ins []io.Reader, outs []io.Writer) {
+	node.err = nil
+
+	var b bytes.Buffer
+	node.node.Tick(ctx, ins, []io.Writer{&b})
+	nodeErr := node.node.Err()
+
+	data := append(node.carry, b.Bytes()...)
+
+	size := b.Len()
+	if nodeErr != nil {
+		size = len(data)
+	}
+
+	errs := []error{nodeErr}
+
+	for outIdx, out := range outs {
+		_, err := out.Write(data[:size])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to write delayed audio to output %d: %w", outIdx, err))
+		}
+	}
+
+	node.carry = slices.Clone(data[size:])
+	node.err = errors.Join(errs...)
+}
+
+func (node *delayNode) Err() error {
+	return node.err
+}
+
+// delays the audio of the input by n bytes of silence. must be called with the session locked and
+// before the input is added to it, since [BaseInput.Subgraph] reads the delay without locking
+func (i *BaseInput) delayStart(n int64) {
+	i.delay = &delayNode{node: i.subgraph, carry: make([]byte, n)}
+}
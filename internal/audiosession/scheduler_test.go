@@ -0,0 +1,109 @@
+package audiosession
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// lets sessions tick without setting up opentelemetry
+func initTestTelemetry(t *testing.T) {
+	tracer, logger := telemetry.Tracer, telemetry.Logger
+
+	telemetry.Tracer = noop.NewTracerProvider().Tracer("test")
+	telemetry.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Cleanup(func() {
+		telemetry.Tracer, telemetry.Logger = tracer, logger
+	})
+}
+
+// records everything that the session plays
+type captureWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *captureWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func TestScheduleInput(t *testing.T) {
+	initTestConfig(t)
+	initTestTelemetry(t)
+
+	session := newTestSession(t)
+
+	capture := &captureWriter{}
+	captureNode := audio.NewWriterNode(session.logger, capture)
+
+	session.Lock()
+	session.audioGraph.AddNode(captureNode)
+	session.audioGraph.CreateConnection(session.rootTee, captureNode)
+	session.Unlock()
+
+	started := make(chan InputEvent_ScheduledStart, 1)
+
+	session.Events.Subscribe(func(event SessionEvent) {
+		if e, ok := event.(InputEvent_ScheduledStart); ok {
+			started <- e
+		}
+	})
+
+	cancelled := session.ScheduleInput(session.NewMemoryInput("cancelled", make([]byte, 0x10000)), 1<<40)
+	if !cancelled.Cancel() || cancelled.Cancel() {
+		t.Errorf("expected a pending input to be cancelled once")
+	}
+
+	if cancelled.Input().State() != inputState_Stopped {
+		t.Errorf("expected a cancelled input to be stopped")
+	}
+
+	// the index is part way through the second tick
+	const sampleIndex = 10000
+	pcm := bytes.Repeat([]byte{1}, 0x10000)
+	scheduled := session.ScheduleInput(session.NewMemoryInput("scheduled", pcm), sampleIndex)
+
+	select {
+	case e := <-started:
+		if e.SampleIndex != sampleIndex || e.Late != 0 {
+			t.Errorf("expected the input to start on time at %d, got %d late by %s", sampleIndex, e.SampleIndex, e.Late)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the scheduled input to start")
+	}
+
+	// the input stops once all of its audio has been played
+	deadline := time.Now().Add(5 * time.Second)
+	for scheduled.Input().State() != inputState_Stopped {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the scheduled input to finish")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	session.Lock()
+	played := bytes.Clone(capture.buf.Bytes())
+	session.Unlock()
+
+	first := bytes.IndexByte(played, 1)
+	if want := sampleIndex * int(pcmSampleSizeBytes()); first != want {
+		t.Errorf("expected the first sample of the input to be played at byte %d, got %d", want, first)
+	}
+
+	// none of the audio is lost to the delay
+	if n := bytes.Count(played, []byte{1}); n != len(pcm) {
+		t.Errorf("expected all %d bytes of the input to be played, got %d", len(pcm), n)
+	}
+}
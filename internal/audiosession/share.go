@@ -0,0 +1,55 @@
+package audiosession
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidShareToken = errors.New("invalid audio session share token")
+
+// returns the token that lets other guilds join the session and listeners stream it over http. the
+// token is created the first time that the session is shared. a session that has never been shared
+// can only be played by its owner
+func (s *Session) Share() (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.shareToken != "" {
+		return s.shareToken, nil
+	}
+
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to create share token: %w", err)
+	}
+
+	s.shareToken = hex.EncodeToString(b)
+
+	return s.shareToken, nil
+}
+
+// returns the session owned by id if token is the token that it was shared with
+//
+// returns [ErrSessionNotFound] if no session is owned by id and [ErrInvalidShareToken] if the
+// session has not been shared with token
+func GetShared(id string, token string) (*Session, error) {
+	s, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	shareToken := s.shareToken
+	s.Unlock()
+
+	if shareToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(shareToken)) != 1 {
+		return nil, ErrInvalidShareToken
+	}
+
+	return s, nil
+}
@@ -0,0 +1,48 @@
+package audiosession
+
+import (
+	"errors"
+	"testing"
+
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+func TestGetShared(t *testing.T) {
+	initTestConfig(t)
+
+	session, err := Create(logging.NewLogger(), "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		session.Destroy()
+		session.Wait()
+	})
+
+	// a session that has not been shared cannot be played by anyone else
+	if _, err := GetShared("owner", ""); !errors.Is(err, ErrInvalidShareToken) {
+		t.Errorf("expected %v before sharing, got %v", ErrInvalidShareToken, err)
+	}
+
+	token, err := session.Share()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again, _ := session.Share(); again != token {
+		t.Errorf("expected sharing again to return the same token")
+	}
+
+	if _, err := GetShared("owner", token+"0"); !errors.Is(err, ErrInvalidShareToken) {
+		t.Errorf("expected %v for the wrong token, got %v", ErrInvalidShareToken, err)
+	}
+
+	if s, err := GetShared("owner", token); err != nil || s != session {
+		t.Errorf("expected the shared session, got %v %v", s, err)
+	}
+
+	if _, err := GetShared("other", token); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected %v for a session that does not exist, got %v", ErrSessionNotFound, err)
+	}
+}
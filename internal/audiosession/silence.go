@@ -0,0 +1,58 @@
+package audiosession
+
+import (
+	"io"
+	"time"
+)
+
+// stops passing pcm on once it has been digitally silent for longer than threshold, so that
+// nothing is encoded or sent while nothing is playing. calls onAudio whenever pcm that is not
+// silent is written
+type silenceGate struct {
+	w         io.Writer
+	threshold time.Duration
+	onAudio   func()
+
+	silentFor time.Duration
+}
+
+func (g *silenceGate) Write(p []byte) (n int, err error) {
+	if !isSilent(p) {
+		g.silentFor = 0
+		g.onAudio()
+
+		return g.w.Write(p)
+	}
+
+	g.silentFor += pcmBytesToDuration(int64(len(p)))
+	if g.silentFor > g.threshold {
+		return len(p), nil
+	}
+
+	return g.w.Write(p)
+}
+
+// passes packets on if w can send them. silent pcm is gated the same way as by Write
+func (g *silenceGate) WritePassthrough(pcm []byte, packets *cachedPackets) error {
+	w, ok := g.w.(opusPacketPassthrough)
+	if !ok || isSilent(pcm) {
+		_, err := g.Write(pcm)
+		return err
+	}
+
+	g.silentFor = 0
+	g.onAudio()
+
+	return w.WritePassthrough(pcm, packets)
+}
+
+// returns whether every sample is 0
+func isSilent(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
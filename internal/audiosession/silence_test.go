@@ -0,0 +1,35 @@
+package audiosession
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSilenceGate(t *testing.T) {
+	initTestConfig(t)
+
+	var written bytes.Buffer
+	nAudio := 0
+
+	g := &silenceGate{w: &written, threshold: 20 * time.Millisecond, onAudio: func() { nAudio++ }}
+
+	// 10ms of pcm at the default 48kHz stereo
+	silence := make([]byte, durationToPCMBytes(10*time.Millisecond))
+	audio := bytes.Repeat([]byte{1}, len(silence))
+
+	for range 4 {
+		g.Write(silence)
+	}
+
+	if written.Len() != 2*len(silence) {
+		t.Errorf("expected silence to stop being written after the threshold, wrote %d bytes", written.Len())
+	}
+
+	written.Reset()
+	g.Write(audio)
+
+	if written.Len() != len(audio) || nAudio != 1 {
+		t.Errorf("expected audio to be written once it is no longer silent")
+	}
+}
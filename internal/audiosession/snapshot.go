@@ -0,0 +1,428 @@
+package audiosession
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/exec/ytdlp"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// increased whenever the snapshot format changes in a way that older snapshots cannot be restored
+const snapshotVersion = 1
+
+var (
+	ErrSnapshotVersion      = errors.New("audio session snapshot has an unsupported version")
+	ErrTrackNotRestorable   = errors.New("track cannot be restored from a snapshot")
+	ErrNoVoiceChannelJoined = errors.New("failed to join any of the voice channels of the audio session snapshot")
+)
+
+type TrackKind string
+
+const (
+	TrackKind_Ytdlp      TrackKind = "ytdlp"
+	TrackKind_File       TrackKind = "file"
+	TrackKind_HTTPStream TrackKind = "httpStream"
+)
+
+// describes how to create a track again. only tracks created by [NewYtdlpTrack], [NewFileTrack]
+// and [NewHTTPStreamTrack] have a source
+type TrackSource struct {
+	Kind    TrackKind               `json:"kind"`
+	URL     string                  `json:"url"`
+	Quality ytdlp.YtdlpAudioQuality `json:"quality,omitempty"`
+}
+
+type TrackSnapshot struct {
+	Source      TrackSource `json:"source"`
+	Name        string      `json:"name"`
+	RequesterID string      `json:"requesterId"`
+	EnqueuedAt  time.Time   `json:"enqueuedAt"`
+}
+
+type VoiceChannelSnapshot struct {
+	GuildID   string `json:"guildId"`
+	ChannelID string `json:"channelId"`
+}
+
+// the state of a session that is saved to disk so that it can carry on playing after a restart
+type SessionSnapshot struct {
+	Version int       `json:"version"`
+	ID      string    `json:"id"`
+	SavedAt time.Time `json:"savedAt"`
+
+	Volume      float64  `json:"volume"`
+	LoopMode    LoopMode `json:"loopMode"`
+	Shuffle     bool     `json:"shuffle"`
+	ShuffleSeed uint64   `json:"shuffleSeed"`
+
+	// the track that was playing and how far into it playback was
+	Current    optional.Optional[TrackSnapshot] `json:"current"`
+	PositionMs int64                            `json:"positionMs"`
+
+	// the tracks that were waiting to be played
+	Tracks []TrackSnapshot `json:"tracks"`
+
+	// the discord voice channels that the session was playing to
+	VoiceChannels []VoiceChannelSnapshot `json:"voiceChannels"`
+}
+
+func (t Track) snapshot() (TrackSnapshot, bool) {
+	if !t.source.IsSet() {
+		return TrackSnapshot{}, false
+	}
+
+	return TrackSnapshot{Source: t.source.Get(), Name: t.Name, RequesterID: t.RequesterID, EnqueuedAt: t.EnqueuedAt}, true
+}
+
+// creates the track that was saved
+//
+// returns [ErrTrackNotRestorable] if the kind of the track is not known
+func (t TrackSnapshot) Track() (Track, error) {
+	var track Track
+
+	switch t.Source.Kind {
+	case TrackKind_Ytdlp:
+		track = NewYtdlpTrack(t.Source.URL, t.Source.Quality)
+	case TrackKind_File:
+		track = NewFileTrack(t.Source.URL)
+	case TrackKind_HTTPStream:
+		track = NewHTTPStreamTrack(t.Source.URL)
+	default:
+		return Track{}, fmt.Errorf("%w: unknown kind %q", ErrTrackNotRestorable, t.Source.Kind)
+	}
+
+	track.Name = t.Name
+	track.RequesterID = t.RequesterID
+	track.EnqueuedAt = t.EnqueuedAt
+
+	return track, nil
+}
+
+// returns the current state of the session. tracks that cannot be created again, such as
+// tracks created with [NewTrack], are left out
+func (s *Session) Snapshot() SessionSnapshot {
+	snapshot := SessionSnapshot{
+		Version:       snapshotVersion,
+		ID:            s.id,
+		SavedAt:       time.Now(),
+		Volume:        s.Volume(),
+		Tracks:        make([]TrackSnapshot, 0),
+		VoiceChannels: make([]VoiceChannelSnapshot, 0),
+	}
+
+	q := s.queue
+	q.Lock()
+
+	snapshot.LoopMode = q.loopMode
+	snapshot.Shuffle = q.shuffle
+	snapshot.ShuffleSeed = q.shuffleSeed
+
+	if q.current.IsSet() && q.currentInput != nil {
+		if track, ok := q.current.Get().snapshot(); ok {
+			snapshot.Current = optional.Make(track)
+			snapshot.PositionMs = q.currentInput.Position().Milliseconds()
+		}
+	}
+
+	for _, t := range q.tracks {
+		if track, ok := t.snapshot(); ok {
+			snapshot.Tracks = append(snapshot.Tracks, track)
+		}
+	}
+
+	q.Unlock()
+
+	for _, o := range s.DiscordVoiceConnOutputs() {
+		o.Conn.RLock()
+		channel := VoiceChannelSnapshot{GuildID: o.Conn.GuildID, ChannelID: o.Conn.ChannelID}
+		o.Conn.RUnlock()
+
+		snapshot.VoiceChannels = append(snapshot.VoiceChannels, channel)
+	}
+
+	return snapshot
+}
+
+func snapshotPath(directory, id string) string {
+	return filepath.Join(directory, id+".json")
+}
+
+// saves the session to the snapshot directory. a session that is not playing to any voice channel
+// has nothing to restore, so its snapshot is removed instead. does nothing if no snapshot directory
+// is configured
+func (s *Session) saveSnapshot() error {
+	directory := config.Get().Snapshot.Directory
+	if !directory.IsSet() {
+		return nil
+	}
+
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	// a session that has been destroyed removed its snapshot, which must not be written again
+	if s.IsDestroyed() {
+		return nil
+	}
+
+	snapshot := s.Snapshot()
+	if len(snapshot.VoiceChannels) == 0 {
+		return removeSnapshotFile(directory.Get(), s.id)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode audio session snapshot: %w", err)
+	}
+
+	err = os.MkdirAll(directory.Get(), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create audio session snapshot directory: %w", err)
+	}
+
+	// the snapshot is written to a temporary file first so that a crash never leaves half of it behind
+	path := snapshotPath(directory.Get(), s.id)
+	tmpPath := path + ".tmp"
+
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write audio session snapshot: %w", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("failed to write audio session snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// removes the snapshot of a session that was destroyed on purpose so that it is not restored
+func (s *Session) removeSnapshot() {
+	directory := config.Get().Snapshot.Directory
+	if !directory.IsSet() {
+		return
+	}
+
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	err := removeSnapshotFile(directory.Get(), s.id)
+	if err != nil {
+		s.logger.Warn("failed to remove audio session snapshot", "id", s.id, "error", err)
+	}
+}
+
+func removeSnapshotFile(directory, id string) error {
+	err := os.Remove(snapshotPath(directory, id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove audio session snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// saves the session every configured interval until it is destroyed
+func (s *Session) saveSnapshotsPeriodically() {
+	defer s.running.Done()
+
+	ticker := time.NewTicker(time.Duration(config.Get().Snapshot.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.saveSnapshot()
+			if err != nil {
+				s.logger.Warn("failed to save audio session snapshot", "id", s.id, "error", err)
+			}
+		case <-s.destroyedChan:
+			return
+		}
+	}
+}
+
+// reads every snapshot in the snapshot directory. snapshots that cannot be read are left out and
+// returned as errors. returns no snapshots if no snapshot directory is configured
+func LoadSnapshots() ([]SessionSnapshot, error) {
+	snapshots := make([]SessionSnapshot, 0)
+
+	directory := config.Get().Snapshot.Directory
+	if !directory.IsSet() {
+		return snapshots, nil
+	}
+
+	entries, err := os.ReadDir(directory.Get())
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshots, nil
+	}
+
+	if err != nil {
+		return snapshots, fmt.Errorf("failed to read audio session snapshot directory: %w", err)
+	}
+
+	var errs []error
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(directory.Get(), entry.Name())
+
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read audio session snapshot %q: %w", path, err))
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, errors.Join(errs...)
+}
+
+func readSnapshot(path string) (SessionSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SessionSnapshot{}, err
+	}
+
+	var snapshot SessionSnapshot
+
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return SessionSnapshot{}, err
+	}
+
+	if snapshot.Version != snapshotVersion {
+		return SessionSnapshot{}, fmt.Errorf("%w: %d", ErrSnapshotVersion, snapshot.Version)
+	}
+
+	return snapshot, nil
+}
+
+// creates the session that was saved in snapshot, joins its voice channels with join and carries
+// on playing its current track from near where it was saved. voice channels that cannot be joined
+// and tracks that cannot be created are skipped
+//
+// returns [ErrNoVoiceChannelJoined] if none of the voice channels could be joined
+func Restore(logger *logging.Logger, snapshot SessionSnapshot, join func(guildID, channelID string) (*discordgo.VoiceConnection, error)) (*Session, error) {
+	s, err := Create(logger, snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range snapshot.VoiceChannels {
+		err := s.restoreVoiceChannel(channel, join)
+		if err != nil {
+			logger.Warn("failed to rejoin voice channel of restored audio session", "id", s.id, "guildId", channel.GuildID, "channelId", channel.ChannelID, "error", err)
+		}
+	}
+
+	if len(snapshot.VoiceChannels) > 0 && len(s.DiscordVoiceConnOutputs()) == 0 {
+		s.Destroy()
+		return nil, ErrNoVoiceChannelJoined
+	}
+
+	s.SetVolume(snapshot.Volume)
+
+	current := optional.None[Track]()
+	if snapshot.Current.IsSet() {
+		track, err := snapshot.Current.Get().Track()
+		if err != nil {
+			logger.Warn("failed to restore current track of audio session", "id", s.id, "error", err)
+		} else {
+			current = optional.Make(track)
+		}
+	}
+
+	tracks := make([]Track, 0, len(snapshot.Tracks))
+	for _, t := range snapshot.Tracks {
+		track, err := t.Track()
+		if err != nil {
+			logger.Warn("failed to restore queued track of audio session", "id", s.id, "error", err)
+			continue
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	// the bot left once there was nothing left to play before the session was saved, and it still
+	// does after it is restored
+	for _, output := range s.DiscordVoiceConnOutputs() {
+		s.RemoveOutputWhenInputsRunOut(output)
+	}
+
+	s.queue.restore(snapshot, current, time.Duration(snapshot.PositionMs)*time.Millisecond, tracks)
+
+	if len(s.Inputs()) > 0 {
+		go s.StartTicking()
+	}
+
+	logger.Info("restored audio session", "id", s.id, "savedAt", snapshot.SavedAt, "voiceChannels", len(s.DiscordVoiceConnOutputs()), "tracks", len(tracks))
+
+	return s, nil
+}
+
+func (s *Session) restoreVoiceChannel(channel VoiceChannelSnapshot, join func(guildID, channelID string) (*discordgo.VoiceConnection, error)) error {
+	conn, err := join(channel.GuildID, channel.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.AddDiscordVoiceConnOutput(conn)
+	if err != nil {
+		return errors.Join(err, conn.Disconnect())
+	}
+
+	return nil
+}
+
+// replaces the state of the queue with a snapshot and starts current at position. a track that
+// cannot start part way through, such as a live stream, starts from the beginning. if current
+// cannot be started, the queue carries on with the next track
+func (q *Queue) restore(snapshot SessionSnapshot, current optional.Optional[Track], position time.Duration, tracks []Track) {
+	q.Lock()
+
+	q.loopMode = snapshot.LoopMode
+	q.shuffleSeed = snapshot.ShuffleSeed
+	q.shuffle = snapshot.Shuffle
+	q.rng = nil
+
+	// the tracks were saved in their shuffled order, so they are not shuffled again
+	if q.shuffle {
+		q.rng = rand.New(rand.NewPCG(q.shuffleSeed, q.shuffleSeed))
+	}
+
+	q.tracks = tracks
+
+	if current.IsSet() {
+		err := q.startLocked(current.Get(), position)
+		if err != nil {
+			q.session.logger.Warn("failed to start restored track. skipping to the next track", "track", current.Get().Name, "error", err)
+		}
+	}
+
+	if q.currentInput == nil && !q.session.IsDestroyed() {
+		q.startNextLocked()
+	}
+
+	restored := q.current
+	q.publishChangedLocked()
+
+	q.Unlock()
+
+	if restored.IsSet() {
+		q.OnCurrentTrackChanged.Broadcast(QueueEvent_OnCurrentTrackChanged{Previous: optional.None[Track](), Current: restored})
+	}
+}
@@ -0,0 +1,94 @@
+package audiosession
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/exec/ytdlp"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+)
+
+// a minute of silence, which is long enough that the next track is not preloaded
+func newLongTestTrack(name string) Track {
+	return newSeekableTrack(name, func(s *Session, offset time.Duration) (Input, error) {
+		input := s.NewMemoryInput(name, make([]byte, durationToPCMBytes(time.Minute)))
+
+		err := input.Seek(offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return input, nil
+	})
+}
+
+func TestSessionSnapshot(t *testing.T) {
+	initTestConfig(t)
+
+	session := newTestSession(t)
+	session.SetVolume(0.5)
+
+	queue := session.Queue()
+	queue.SetLoopMode(LoopMode_All)
+
+	queue.Enqueue(newLongTestTrack("current"))
+	queue.Enqueue(NewFileTrack("/media/a.ogg").WithRequester("user"))
+	queue.Enqueue(newLongTestTrack("unsaved"))
+	queue.Enqueue(NewYtdlpTrack("https://example.com/b", ytdlp.YtdlpAudioQuality_BestAudio))
+
+	data, err := json.Marshal(session.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot SessionSnapshot
+
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot.Volume != 0.5 || snapshot.LoopMode != LoopMode_All {
+		t.Errorf("expected volume 0.5 and loop mode %d, got volume %f and loop mode %d", LoopMode_All, snapshot.Volume, snapshot.LoopMode)
+	}
+
+	// tracks that cannot be created again are left out
+	if snapshot.Current.IsSet() {
+		t.Errorf("expected the current track not to be saved")
+	}
+
+	if len(snapshot.Tracks) != 2 {
+		t.Fatalf("expected 2 saved tracks, got %d", len(snapshot.Tracks))
+	}
+
+	track, err := snapshot.Tracks[0].Track()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.Name != "a.ogg" || track.RequesterID != "user" || track.source.Get() != (TrackSource{Kind: TrackKind_File, URL: "/media/a.ogg"}) {
+		t.Errorf("expected the file track to be restored, got %+v", track)
+	}
+
+	if snapshot.Tracks[1].Source.Kind != TrackKind_Ytdlp || snapshot.Tracks[1].Source.Quality != ytdlp.YtdlpAudioQuality_BestAudio {
+		t.Errorf("expected the ytdlp track to be saved with its quality, got %+v", snapshot.Tracks[1].Source)
+	}
+
+	// the restored track carries on from where it was saved
+	restored := newTestSession(t)
+	restored.Queue().restore(snapshot, optional.Make(newLongTestTrack("restored")), 10*time.Second, nil)
+
+	input := restored.Queue().CurrentInput()
+	if input == nil {
+		t.Fatal("expected the restored track to be playing")
+	}
+
+	if input.Position() != 10*time.Second {
+		t.Errorf("expected the restored track to resume at 10s, got %s", input.Position())
+	}
+
+	if restored.Queue().LoopMode() != LoopMode_All {
+		t.Errorf("expected the loop mode to be restored")
+	}
+}
@@ -0,0 +1,357 @@
+package audiosession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/exec/ffmpeg"
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
+	"accidentallycoded.com/fredboard/v3/internal/syncext"
+)
+
+const (
+	streamReconnectMinBackoff = time.Second
+	streamReconnectMaxBackoff = 30 * time.Second
+
+	// the input is stopped after this many reconnects in a row fail
+	streamMaxReconnectAttempts = 10
+
+	// how long connecting to the server and waiting for its response can take. the body has no
+	// timeout since the stream never ends, instead the connection is dropped once it stalls
+	streamDialTimeout           = 10 * time.Second
+	streamResponseHeaderTimeout = 10 * time.Second
+)
+
+var streamHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: streamDialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   streamDialTimeout,
+		ResponseHeaderTimeout: streamResponseHeaderTimeout,
+	},
+}
+
+var (
+	ErrStreamConnectFailed   = errors.New("failed to connect to stream")
+	ErrStreamReconnectFailed = errors.New("gave up reconnecting to stream")
+)
+
+// plays a live http audio stream, such as an icecast or shoutcast internet radio station. the
+// stream is reconnected with exponential backoff if it ends or fails
+type HTTPStreamInput struct {
+	*BaseInput
+
+	url    string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// every connection writes its pcm to pcm, which the buffer reads ahead of playback. the buffer
+	// runs dry while reconnecting, so the input plays silence without holding up the session
+	pcm    *io.PipeWriter
+	buffer *jitterBuffer
+
+	name  syncext.SyncData[string]
+	title syncext.SyncData[string]
+
+	// opens a connection to the stream
+	dial func() (*streamConn, error)
+
+	// the wait before reconnecting doubles from minBackoff after every failed attempt, up to maxBackoff
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// drops the connection once the server has not sent anything for the timeout, such as when it stops
+// writing without closing the connection. reading then fails, which ends the connection so that
+// the stream is reconnected to
+type stallDeadlineReader struct {
+	r       io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newStallDeadlineReader(r io.Reader, timeout time.Duration, drop func()) *stallDeadlineReader {
+	return &stallDeadlineReader{r: r, timeout: timeout, timer: time.AfterFunc(timeout, drop)}
+}
+
+func (r *stallDeadlineReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+
+	return n, err
+}
+
+func (r *stallDeadlineReader) stop() {
+	r.timer.Stop()
+}
+
+// one connection to the stream and the ffmpeg process that decodes it
+type streamConn struct {
+	pcm    io.Reader
+	close  func()
+	exited <-chan *exec.ExitError
+}
+
+func (i *HTTPStreamInput) URL() string {
+	return i.url
+}
+
+// the name of the station from the icy-name header. empty if the server did not send one
+func (i *HTTPStreamInput) Name() string {
+	i.name.Lock()
+	defer i.name.Unlock()
+
+	return i.name.Data
+}
+
+// the title of the song that is currently playing from the icy metadata. empty if the server has
+// not sent one
+func (i *HTTPStreamInput) Title() string {
+	i.title.Lock()
+	defer i.title.Unlock()
+
+	return i.title.Data
+}
+
+func (i *HTTPStreamInput) onMetadata(metadata map[string]string) {
+	title, ok := metadata[icyMetadataStreamTitle]
+	if !ok {
+		return
+	}
+
+	i.title.Lock()
+	changed := i.title.Data != title
+	i.title.Data = title
+	i.title.Unlock()
+
+	if changed {
+		i.session.logger.Debug("stream title changed", "url", i.url, "title", title)
+		i.publish(func(h InputEventHeader) SessionEvent {
+			return InputEvent_TitleChanged{InputEventHeader: h, Title: title}
+		})
+	}
+}
+
+func (i *HTTPStreamInput) connect() (*streamConn, error) {
+	logger := i.session.logger
+
+	// cancelling the request drops the connection, which also unblocks a read that is waiting on it
+	ctx, cancel := context.WithCancel(i.ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.url, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// ask the server to interleave the song title with the audio
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := streamHTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, errors.Join(ErrStreamConnectFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%w: %s", ErrStreamConnectFailed, resp.Status)
+	}
+
+	if name := resp.Header.Get("icy-name"); name != "" {
+		i.name.Set(name)
+		i.updateMetadata(func(metadata *InputMetadata) { metadata.Title = name })
+	}
+
+	stallTimeout := time.Duration(config.Get().Audio.StallTimeoutMs) * time.Millisecond
+	deadline := newStallDeadlineReader(resp.Body, stallTimeout, func() {
+		logger.Warn("stream stalled. dropping the connection", "url", i.url, "stalledFor", stallTimeout)
+		cancel()
+	})
+
+	var body io.Reader = deadline
+
+	if metaInt, err := strconv.Atoi(resp.Header.Get("icy-metaint")); err == nil && metaInt > 0 {
+		body = newICYReader(deadline, metaInt, i.onMetadata)
+	}
+
+	transcoder, err, transcoderExitChan := ffmpeg.NewTranscoder(
+		logger,
+		ffmpeg.Config{ExePath: config.Get().Ffmpeg.ExePath},
+		body,
+		ffmpeg.Format_PCMSigned16BitLittleEndian,
+		config.Get().Audio.SampleRateHz,
+		config.Get().Audio.NumChannels,
+	)
+
+	if err != nil {
+		deadline.stop()
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create transcoder: %w", err)
+	}
+
+	return &streamConn{
+		pcm:    transcoder,
+		exited: transcoderExitChan,
+		close: func() {
+			deadline.stop()
+			transcoder.Close()
+			resp.Body.Close()
+			cancel()
+		},
+	}, nil
+}
+
+// plays conn until it ends and then reconnects until the input is stopped or reconnecting fails
+// too many times in a row
+func (i *HTTPStreamInput) run(conn *streamConn) {
+	defer i.session.running.Done()
+
+	logger := i.session.logger
+	nFailedAttempts := 0
+
+	for {
+		connectedAt := time.Now()
+
+		_, err := io.Copy(i.pcm, conn.pcm)
+		conn.close()
+
+		exitErr := <-conn.exited
+		if exitErr != nil {
+			logger.Debug("stream transcoder exited with exit error", "url", i.url, "err", exitErr)
+		}
+
+		if i.ctx.Err() != nil {
+			return
+		}
+
+		logger.Warn("stream ended. reconnecting", "url", i.url, "error", err)
+
+		// a connection that stayed up for a while means that the stream is healthy again
+		if time.Since(connectedAt) > i.maxBackoff {
+			nFailedAttempts = 0
+		}
+
+		for {
+			if nFailedAttempts >= streamMaxReconnectAttempts {
+				logger.Warn("giving up reconnecting to stream", "url", i.url, "nAttempts", nFailedAttempts)
+				i.pcm.CloseWithError(ErrStreamReconnectFailed)
+				return
+			}
+
+			backoff := min(i.minBackoff<<nFailedAttempts, i.maxBackoff)
+
+			select {
+			case <-i.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			conn, err = i.dial()
+			if err == nil {
+				break
+			}
+
+			nFailedAttempts++
+			logger.Warn("failed to reconnect to stream", "url", i.url, "attempt", nFailedAttempts, "backoff", backoff, "error", err)
+		}
+
+		logger.Info("reconnected to stream", "url", i.url)
+	}
+}
+
+// add an http stream input that plays until it is stopped or the stream cannot be reconnected to
+func (s *Session) AddHTTPStreamInput(url string) (*HTTPStreamInput, error) {
+	input, err := s.newHTTPStreamInput(url)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AddInput(input)
+
+	return input, nil
+}
+
+// connects to the stream without adding the input to the session
+func (s *Session) newHTTPStreamInput(url string) (*HTTPStreamInput, error) {
+	readerNode := audio.NewReaderNode(s.logger, nil, tickSizeBytes())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	input := &HTTPStreamInput{
+		BaseInput:  NewBaseInput(s, readerNode),
+		url:        url,
+		ctx:        ctx,
+		cancel:     cancel,
+		minBackoff: streamReconnectMinBackoff,
+		maxBackoff: streamReconnectMaxBackoff,
+	}
+
+	input.dial = input.connect
+
+	// the title is replaced by the name of the station once it is known
+	input.metadata.Title = url
+	input.metadata.SourceURL = url
+
+	err := input.start(readerNode)
+	if err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+// connects to the stream and starts playing it in the background
+func (i *HTTPStreamInput) start(readerNode *audio.ReaderNode) error {
+	conn, err := i.dial()
+	if err != nil {
+		i.cancel()
+		return err
+	}
+
+	pcm, pcmWriter := io.Pipe()
+	i.pcm = pcmWriter
+	i.buffer = i.prefetch(pcm)
+
+	// the buffer only ends once the input has been stopped or reconnecting has failed, which has
+	// already been logged
+	readerNode.SetReader(ioext.NewErrNotifyReader(i.buffer, i.endAsync))
+
+	// disconnect from the stream and kill ffmpeg once the input is stopped
+	i.whenStopped(func() {
+		i.cancel()
+		i.buffer.close()
+		pcmWriter.CloseWithError(io.EOF)
+	})
+
+	// shutdown waits for the connection to be closed
+	i.session.running.Add(1)
+	go i.run(conn)
+
+	return nil
+}
+
+// creates a track that plays an http stream input when it reaches the front of a queue
+func NewHTTPStreamTrack(url string) Track {
+	return NewTrack(url, func(s *Session) (Input, error) {
+		input, err := s.newHTTPStreamInput(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return input, nil
+	}).withSource(TrackSource{Kind: TrackKind_HTTPStream, URL: url})
+}
@@ -0,0 +1,198 @@
+package audiosession
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio"
+)
+
+// a connection that plays pcm and then ends as if the server had closed it
+func newTestStreamConn(pcm []byte) *streamConn {
+	exited := make(chan *exec.ExitError)
+	close(exited)
+
+	return &streamConn{pcm: bytes.NewReader(pcm), close: func() {}, exited: exited}
+}
+
+// records when the stream is dialed. dials after the first one fail unless results says otherwise
+type testStreamDialer struct {
+	mu      sync.Mutex
+	dials   []time.Time
+	results []error
+}
+
+func (d *testStreamDialer) dial() (*streamConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := len(d.dials)
+	d.dials = append(d.dials, time.Now())
+
+	if n > 0 && (n > len(d.results) || d.results[n-1] != nil) {
+		return nil, ErrStreamConnectFailed
+	}
+
+	return newTestStreamConn(make([]byte, opusFrameSizeBytes())), nil
+}
+
+func (d *testStreamDialer) times() []time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]time.Time(nil), d.dials...)
+}
+
+// starts an input that plays url. the input connects to url itself if dial is nil
+func startTestStreamInput(t *testing.T, session *Session, url string, dial func() (*streamConn, error)) *HTTPStreamInput {
+	t.Helper()
+
+	readerNode := audio.NewReaderNode(session.logger, nil, tickSizeBytes())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	input := &HTTPStreamInput{
+		BaseInput:  NewBaseInput(session, readerNode),
+		url:        url,
+		ctx:        ctx,
+		cancel:     cancel,
+		dial:       dial,
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: 40 * time.Millisecond,
+	}
+
+	if input.dial == nil {
+		input.dial = input.connect
+	}
+
+	err := input.start(readerNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.AddInput(input)
+	go session.StartTicking()
+
+	t.Cleanup(input.Stop)
+
+	return input
+}
+
+func TestHTTPStreamInputReconnects(t *testing.T) {
+	initTestConfig(t)
+	initTestTelemetry(t)
+
+	session := newTestSession(t)
+
+	// the first reconnect attempts fail and the third one succeeds
+	dialer := &testStreamDialer{results: []error{ErrStreamConnectFailed, ErrStreamConnectFailed, nil}}
+	input := startTestStreamInput(t, session, "http://example.com/stream", dialer.dial)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(dialer.times()) < 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the stream to be reconnected to, got %d dials", len(dialer.times()))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if input.State() == inputState_Stopped {
+		t.Fatalf("expected the input to keep playing while it reconnects, got %v", input.Err())
+	}
+
+	// the wait doubles after every failed attempt
+	dials := dialer.times()
+	for i, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if got := dials[i+1].Sub(dials[i]); got < want {
+			t.Errorf("expected reconnect attempt %d to wait at least %s, got %s", i+1, want, got)
+		}
+	}
+
+	// the connection that was reconnected to ended right away, so the stream is not healthy yet and
+	// the next attempt waits as long as the last one
+	if got := dials[4].Sub(dials[3]); got < 40*time.Millisecond {
+		t.Errorf("expected the backoff to carry on after a short connection, waited %s", got)
+	}
+}
+
+func TestHTTPStreamInputGivesUpReconnecting(t *testing.T) {
+	initTestConfig(t)
+	initTestTelemetry(t)
+
+	session := newTestSession(t)
+
+	// only the first connection succeeds
+	dialer := &testStreamDialer{}
+	input := startTestStreamInput(t, session, "http://example.com/stream", dialer.dial)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for input.State() != inputState_Stopped {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the input to stop once reconnecting failed")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !errors.Is(input.Err(), ErrStreamReconnectFailed) {
+		t.Errorf("expected the input to fail with %v, got %v", ErrStreamReconnectFailed, input.Err())
+	}
+
+	if n := len(dialer.times()); n != 1+streamMaxReconnectAttempts {
+		t.Errorf("expected %d reconnect attempts, got %d", streamMaxReconnectAttempts, n-1)
+	}
+}
+
+func TestHTTPStreamInputReconnectsWhenServerStalls(t *testing.T) {
+	// an ffmpeg that passes the stream through as if it was pcm
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+
+	err := os.WriteFile(ffmpeg, []byte("#!/bin/sh\nexec cat\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initTestConfigJSON(t, fmt.Sprintf(`{
+		"audio": {"stallTimeoutMs": 100},
+		"ffmpeg": {"exePath": %q},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`, ffmpeg))
+	initTestTelemetry(t)
+
+	// the server sends some audio and then stops writing without closing the connection
+	requests := make(chan struct{}, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+
+		w.Write(make([]byte, opusFrameSizeBytes()))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	session := newTestSession(t)
+	input := startTestStreamInput(t, session, server.URL, nil)
+
+	for n := range 2 {
+		select {
+		case <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the stream to be connected to %d times, got %d", 2, n)
+		}
+	}
+
+	if input.State() == inputState_Stopped {
+		t.Errorf("expected the input to keep playing while it reconnects, got %v", input.Err())
+	}
+}
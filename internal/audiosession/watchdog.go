@@ -0,0 +1,70 @@
+package audiosession
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+// how often the watchdog checks an input while the stall timeout is longer than this
+const stallCheckInterval = time.Second
+
+var ErrInputStalled = errors.New("input stopped receiving audio from its source")
+
+// restarts the source of the input whenever it has gone for the configured timeout without
+// producing any audio. once it has been restarted the configured number of times, the next stall
+// fails the input instead. stops once the input is stopped
+//
+// stalledFor returns how long the source has not produced audio while the input needs it, and
+// restart opens the source again from where playback is
+func (i *BaseInput) watchForStalls(stalledFor func() time.Duration, restart func() error) {
+	timeout := time.Duration(config.Get().Audio.StallTimeoutMs) * time.Millisecond
+	maxRestarts := config.Get().Audio.StallMaxRestarts
+
+	stopped := make(chan struct{})
+	i.whenStopped(func() { close(stopped) })
+
+	i.session.running.Add(1)
+
+	go func() {
+		defer i.session.running.Done()
+
+		ticker := time.NewTicker(min(stallCheckInterval, timeout/4))
+		defer ticker.Stop()
+
+		nStalls := 0
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-stopped:
+				return
+			}
+
+			d := stalledFor()
+			if d < timeout || i.State() == inputState_Stopped {
+				continue
+			}
+
+			nStalls++
+			i.publish(func(h InputEventHeader) SessionEvent {
+				return InputEvent_Stalled{InputEventHeader: h, StalledFor: d, NStalls: nStalls}
+			})
+
+			if nStalls > maxRestarts {
+				i.fail(fmt.Errorf("%w: no audio for %s at %s after %d restarts", ErrInputStalled, d.Round(time.Millisecond), i.Position().Round(time.Second), maxRestarts))
+				return
+			}
+
+			i.session.logger.Warn("input stalled. restarting it from where it stalled", "id", i.session.id, "stalledFor", d, "position", i.Position(), "restart", nStalls, "maxRestarts", maxRestarts)
+
+			err := restart()
+			if err != nil {
+				i.fail(fmt.Errorf("%w: failed to restart after %s without audio: %w", ErrInputStalled, d.Round(time.Millisecond), err))
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+package audiosession
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/config"
+)
+
+func TestInputStallWatchdog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	err := os.WriteFile(path, []byte(`{
+		"audio": {"stallTimeoutMs": 40, "stallMaxRestarts": 2},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verrs, err := config.Init(config.ConfigInitOptions{Files: []string{path}})
+	if err != nil || len(verrs) > 0 {
+		t.Fatalf("failed to initialize config: %v %v", err, verrs)
+	}
+
+	session := newTestSession(t)
+
+	var mu sync.Mutex
+	var nStalls int
+	var errored error
+
+	session.Events.Subscribe(func(event SessionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch e := event.(type) {
+		case InputEvent_Stalled:
+			nStalls = e.NStalls
+		case InputEvent_Errored:
+			errored = e.Err
+		}
+	})
+
+	input := session.NewMemoryInput("stalled", make([]byte, 0x10000))
+	session.AddInput(input)
+
+	// the source never produces any audio
+	var nRestarts atomic.Int32
+	input.watchForStalls(func() time.Duration { return time.Hour }, func() error {
+		nRestarts.Add(1)
+		return nil
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for input.State() != inputState_Stopped && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !errors.Is(input.Err(), ErrInputStalled) {
+		t.Fatalf("expected the input to fail with %v, got %v", ErrInputStalled, input.Err())
+	}
+
+	if n := nRestarts.Load(); n != 2 {
+		t.Errorf("expected the input to be restarted 2 times, got %d", n)
+	}
+
+	// events are delivered asynchronously
+	deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := errored != nil
+		mu.Unlock()
+
+		if done {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if nStalls != 3 || !errors.Is(errored, ErrInputStalled) {
+		t.Errorf("expected 3 stall events followed by the failure, got %d stalls and error %v", nStalls, errored)
+	}
+}
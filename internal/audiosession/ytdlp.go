@@ -1,76 +1,475 @@
 package audiosession
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"accidentallycoded.com/fredboard/v3/internal/audio"
+	"accidentallycoded.com/fredboard/v3/internal/audiocache"
 	"accidentallycoded.com/fredboard/v3/internal/config"
 	"accidentallycoded.com/fredboard/v3/internal/exec/ffmpeg"
 	"accidentallycoded.com/fredboard/v3/internal/exec/ytdlp"
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
+	"accidentallycoded.com/fredboard/v3/internal/syncext"
 )
 
+// while paused, ffmpeg and ytdlp are left running. they block once their output pipes are full
+// and continue where they left off when the input is resumed
 type YtdlpInput struct {
 	*BaseInput
+
+	url        string
+	quality    ytdlp.YtdlpAudioQuality
+	cacheKey   string
+	readerNode *audio.ReaderNode
+
+	seekMu   sync.Mutex
+	sourceMu sync.Mutex
+	source   *ytdlpSource
 }
 
-func (i *YtdlpInput) Pause() {
-	i.BaseInput.Pause()
+// the pcm that is being played. a new source is opened every time that the input seeks
+type ytdlpSource struct {
+	pcm    io.Reader
+	buffer *jitterBuffer
+	close  func()
+
+	// set once the source has been replaced by seeking. the end of a replaced source does not stop the input
+	replaced atomic.Bool
+
+	// set once the source has been closed. ytdlp and ffmpeg are expected to exit with an error after this
+	closed atomic.Bool
+
+	// the metadata that the audio was cached with. nil unless the audio is from the audio cache
+	tags map[string]string
 
-	// TODO
-	panic("unimplemeted")
+	// the packets that the audio is decoded from. nil unless the audio is from the audio cache
+	packets *opusPacketTrack
 }
 
-func (i *YtdlpInput) Resume() {
-	i.BaseInput.Resume()
+func (i *YtdlpInput) URL() string {
+	return i.url
+}
+
+// returns how much audio has been read ahead of playback
+func (i *YtdlpInput) Buffered() time.Duration {
+	i.sourceMu.Lock()
+	source := i.source
+	i.sourceMu.Unlock()
 
-	// TODO
-	panic("unimplemeted")
+	return pcmBytesToDuration(int64(source.buffer.buffered()))
 }
 
-// add a ytdlp input that will automatically be stopped when EOF is reached
-func (s *Session) AddYtdlpInput(url string, quality ytdlp.YtdlpAudioQuality) (Input, error) {
-	videoReader, err, videoReaderExitChan := ytdlp.NewVideoReader(s.logger, ytdlp.Config{ExePath: config.Get().Ytdlp.ExePath, CookiesPath: config.Get().Ytdlp.CookiesFile}, url, quality)
+// moves playback to position. if the audio is cached, the cache entry is read from position.
+// otherwise ytdlp and ffmpeg are restarted and everything before position is discarded
+func (i *YtdlpInput) Seek(position time.Duration) error {
+	position = max(position, 0)
+
+	duration := i.Duration()
+	if duration.IsSet() && position > duration.Get() {
+		return ErrSeekOutOfRange
+	}
+
+	i.seekMu.Lock()
+	defer i.seekMu.Unlock()
+
+	source, err := i.openSource(position)
+	if err != nil {
+		return fmt.Errorf("failed to seek ytdlp input: %w", err)
+	}
+
+	// the reader can only be swapped between ticks
+	i.session.Lock()
+
+	i.sourceMu.Lock()
+	prevSource := i.source
+	i.source = source
+	i.sourceMu.Unlock()
+
+	prevSource.replaced.Store(true)
+	i.readerNode.SetReader(source.pcm)
+	i.setPosition(position)
+
+	i.session.Unlock()
+
+	prevSource.close()
+
+	// the input may have been stopped while the new source was being opened
+	if i.State() == inputState_Stopped {
+		i.closeSource()
+	}
+
+	return nil
+}
+
+func (i *YtdlpInput) opusPackets() *opusPacketTrack {
+	i.sourceMu.Lock()
+	defer i.sourceMu.Unlock()
+
+	return i.source.packets
+}
+
+// returns how long the current source has gone without producing audio while the input needs it
+func (i *YtdlpInput) stalledFor() time.Duration {
+	i.sourceMu.Lock()
+	source := i.source
+	i.sourceMu.Unlock()
+
+	return source.buffer.stalledFor()
+}
+
+func (i *YtdlpInput) closeSource() {
+	i.sourceMu.Lock()
+	source := i.source
+	i.sourceMu.Unlock()
+
+	source.close()
+}
+
+// opens the audio at offset. the audio cache is used if the audio has been played before
+func (i *YtdlpInput) openSource(offset time.Duration) (*ytdlpSource, error) {
+	logger := i.session.logger
+	cache := getAudioCache(logger)
+
+	if cache != nil {
+		source, err := i.openCachedSource(cache, offset)
+
+		switch {
+		case err == nil:
+			logger.Debug("playing ytdlp input from audio cache", "url", i.url, "offset", offset)
+			return source, nil
+		case errors.Is(err, ErrSeekOutOfRange):
+			return nil, err
+		case !errors.Is(err, audiocache.ErrCacheMiss):
+			logger.Warn("failed to play ytdlp input from audio cache", "url", i.url, "error", err)
+		}
+	}
+
+	return i.openTranscodedSource(cache, offset)
+}
+
+func (i *YtdlpInput) openCachedSource(cache *audiocache.Cache, offset time.Duration) (*ytdlpSource, error) {
+	logger := i.session.logger
+
+	pcm, err := openCachedPCM(cache, i.cacheKey, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	i.setDuration(samplesToDuration(pcm.NumSamples()))
+
+	buffer := i.prefetch(pcm)
+	source := &ytdlpSource{
+		buffer:  buffer,
+		tags:    pcm.Tags(),
+		packets: pcm.packets,
+		close: func() {
+			buffer.close()
+			pcm.Close()
+		},
+	}
+
+	// stop the input the same way that an uncached input is stopped once all of its audio is read
+	source.pcm = ioext.NewErrNotifyReader(buffer, func(err error) {
+		pcm.Close()
+
+		if source.replaced.Load() {
+			return
+		}
+
+		if err != io.EOF {
+			logger.Warn("failed to read ytdlp input from audio cache", "error", err)
+			cache.Remove(i.cacheKey)
+
+			err = fmt.Errorf("failed to read from audio cache: %w", err)
+		}
+
+		i.endAsync(err)
+	})
+
+	return source, nil
+}
+
+// cache may be nil if caching is disabled
+func (i *YtdlpInput) openTranscodedSource(cache *audiocache.Cache, offset time.Duration) (*ytdlpSource, error) {
+	logger := i.session.logger
+
+	videoReader, err, videoReaderExitChan := ytdlp.NewVideoReader(logger, ytdlp.Config{ExePath: config.Get().Ytdlp.ExePath, CookiesPath: config.Get().Ytdlp.CookiesFile}, i.url, i.quality)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create video reader: %w", err)
 	}
 
-	transcoder, err, transcoderExitChan := ffmpeg.NewTranscoder(
-		s.logger,
+	transcoder, err, transcoderExitChan := ffmpeg.NewTranscoderWithStartOffset(
+		logger,
 		ffmpeg.Config{ExePath: config.Get().Ffmpeg.ExePath},
 		videoReader,
 		ffmpeg.Format_PCMSigned16BitLittleEndian,
 		config.Get().Audio.SampleRateHz,
 		config.Get().Audio.NumChannels,
+		offset,
 	)
 
 	if err != nil {
+		videoReader.Close()
 		return nil, fmt.Errorf("failed to create transcoder: %w", err)
 	}
 
-	// TODO: Put 0x8000 in config
-	videoReaderNode := audio.NewReaderNode(s.logger, transcoder, 0x8000)
+	var pcm io.Reader = transcoder
+	var cachingPCM *cachingReader
 
-	input := &YtdlpInput{BaseInput: NewBaseInput(s, videoReaderNode)}
-	s.AddInput(input)
+	// only the complete audio can be cached
+	if cache != nil && offset == 0 {
+		cachingPCM, err = newCachingReader(logger, transcoder, cache, i.cacheKey, i.cacheTags)
+		if err != nil {
+			logger.Warn("failed to create audio cache entry. playing without caching", "url", i.url, "error", err)
+		} else {
+			pcm = cachingPCM
+		}
+	}
+
+	source := &ytdlpSource{buffer: i.prefetch(pcm)}
+
+	source.close = func() {
+		source.closed.Store(true)
+		source.buffer.close()
+
+		// the entry is only committed once all of the audio has been read, so anything that
+		// closes the source early must discard it
+		if cachingPCM != nil {
+			cachingPCM.Close()
+		}
+
+		transcoder.Close()
+		videoReader.Close()
+	}
+
+	// errors are reported by the exit goroutine once the processes have exited
+	source.pcm = ioext.NewErrNotifyReader(source.buffer, func(err error) {
+		if !source.replaced.Load() {
+			i.endAsync(io.EOF)
+		}
+	})
+
+	// shutdown waits for the processes to exit
+	i.session.running.Add(1)
 
 	go func() {
+		defer i.session.running.Done()
+
+		failed := false
+		var failure error
+
 		err := <-videoReaderExitChan
-		if err != nil {
-			s.logger.Error("ytdlp videoReader exited with exit error", "err", err)
-		} else {
-			s.logger.Debug("ytdlp videoReader exited successfully")
+		switch {
+		case err != nil && source.closed.Load():
+			logger.Debug("ytdlp videoReader was killed after the source was closed", "err", err)
+			failed = true
+		case err != nil:
+			logger.Error("ytdlp videoReader exited with exit error", "err", err)
+			failed = true
+			failure = fmt.Errorf("ytdlp exited with an error: %w", err)
+		default:
+			logger.Debug("ytdlp videoReader exited successfully")
 		}
 
 		err = <-transcoderExitChan
-		if err != nil {
-			s.logger.Error("ytdlp transcoder exited with exit error", "err", err)
-		} else {
-			s.logger.Debug("ytdlp transcoder exited successfully")
+		switch {
+		case err != nil && source.closed.Load():
+			logger.Debug("ytdlp transcoder was killed after the source was closed", "err", err)
+			failed = true
+		case err != nil:
+			logger.Error("ytdlp transcoder exited with exit error", "err", err)
+			failed = true
+			failure = errors.Join(failure, fmt.Errorf("ffmpeg exited with an error: %w", err))
+		default:
+			logger.Debug("ytdlp transcoder exited successfully")
 		}
 
-		input.Stop()
+		// the audio may be incomplete if either process failed, so it is only cached if both of
+		// them succeeded
+		if cachingPCM != nil {
+			cachingPCM.Finish(!failed)
+		}
+
+		// a successful exit stops the input once all of the pcm has been read
+		switch {
+		case failure != nil && !source.replaced.Load():
+			i.fail(failure)
+		case failed && !source.replaced.Load():
+			i.Stop()
+		}
 	}()
 
+	return source, nil
+}
+
+// the metadata of urls that have been played, so that playing a url again does not have to start
+// ytdlp. cleared once it holds ytdlpMetadataCacheSize urls
+var ytdlpMetadataCache = syncext.NewSyncData(make(map[string]*ytdlp.Metadata))
+
+const ytdlpMetadataCacheSize = 1000
+
+// the keys of the metadata that is stored with cached audio
+const (
+	ytdlpCacheTag_Title        = "title"
+	ytdlpCacheTag_ThumbnailURL = "thumbnailUrl"
+)
+
+// returns the metadata that is stored with the cached audio of the input, so that playing it from
+// the cache does not have to start ytdlp. returns nil if the metadata has not been fetched
+func (i *YtdlpInput) cacheTags() map[string]string {
+	ytdlpMetadataCache.Lock()
+	metadata, ok := ytdlpMetadataCache.Data[i.url]
+	ytdlpMetadataCache.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return map[string]string{
+		ytdlpCacheTag_Title:        metadata.Title,
+		ytdlpCacheTag_ThumbnailURL: ytdlpThumbnailURL(metadata),
+	}
+}
+
+// fills in the metadata of the input from the tags of its cached audio, or from ytdlp if the audio
+// was not cached with any. the duration from ytdlp is only used if it is not already known from the
+// audio cache, since it is not known until the transcoder is finished otherwise
+func (i *YtdlpInput) fetchMetadata(tags map[string]string) {
+	if title, ok := tags[ytdlpCacheTag_Title]; ok {
+		i.updateMetadata(func(m *InputMetadata) {
+			if title != "" {
+				m.Title = title
+			}
+
+			m.ThumbnailURL = tags[ytdlpCacheTag_ThumbnailURL]
+		})
+
+		return
+	}
+
+	ytdlpMetadataCache.Lock()
+	metadata, ok := ytdlpMetadataCache.Data[i.url]
+	ytdlpMetadataCache.Unlock()
+
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		var err error
+
+		metadata, err = ytdlp.GetMetadata(ctx, ytdlp.Config{ExePath: config.Get().Ytdlp.ExePath, CookiesPath: config.Get().Ytdlp.CookiesFile}, i.url)
+		if err != nil {
+			i.session.logger.Warn("failed to get metadata of ytdlp input", "url", i.url, "error", err)
+			return
+		}
+
+		ytdlpMetadataCache.Lock()
+		if len(ytdlpMetadataCache.Data) >= ytdlpMetadataCacheSize {
+			clear(ytdlpMetadataCache.Data)
+		}
+		ytdlpMetadataCache.Data[i.url] = metadata
+		ytdlpMetadataCache.Unlock()
+	}
+
+	i.updateMetadata(func(m *InputMetadata) {
+		if metadata.Title != "" {
+			m.Title = metadata.Title
+		}
+
+		m.ThumbnailURL = ytdlpThumbnailURL(metadata)
+	})
+
+	if metadata.Duration > 0 && !i.Duration().IsSet() {
+		i.setDuration(time.Duration(metadata.Duration * float64(time.Second)))
+	}
+}
+
+// the largest thumbnail looks the best wherever it is shown
+func ytdlpThumbnailURL(metadata *ytdlp.Metadata) string {
+	url, width := "", 0
+
+	for _, thumbnail := range metadata.Thumbnails {
+		if thumbnail.Width >= width {
+			url, width = thumbnail.Url, thumbnail.Width
+		}
+	}
+
+	return url
+}
+
+// add a ytdlp input that will automatically be stopped when EOF is reached
+//
+// if the audio has been played before, it is played from the audio cache without downloading or
+// transcoding it again. ytdlp is only started to fetch the metadata if it was not cached along with
+// the audio and has not been fetched since the process started
+func (s *Session) AddYtdlpInput(url string, quality ytdlp.YtdlpAudioQuality) (Input, error) {
+	input, err := s.newYtdlpInput(url, quality, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AddInput(input)
+
+	return input, nil
+}
+
+// starts ytdlp and ffmpeg at offset without adding the input to the session
+func (s *Session) newYtdlpInput(url string, quality ytdlp.YtdlpAudioQuality, offset time.Duration) (*YtdlpInput, error) {
+	readerNode := audio.NewReaderNode(s.logger, nil, tickSizeBytes())
+
+	input := &YtdlpInput{
+		BaseInput:  NewBaseInput(s, readerNode),
+		url:        url,
+		quality:    quality,
+		cacheKey:   ytdlpCacheKey(url, quality),
+		readerNode: readerNode,
+	}
+
+	// the title is replaced once the metadata has been fetched
+	input.metadata.Title = url
+	input.metadata.SourceURL = url
+
+	source, err := input.openSource(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	input.source = source
+	input.setPosition(offset)
+	readerNode.SetReader(source.pcm)
+
+	// kill ytdlp and ffmpeg if the input is stopped before all of the audio has been played
+	input.whenStopped(input.closeSource)
+
+	// ytdlp can hang partway through a download without exiting, which is only noticed by the
+	// audio drying up. seeking to where playback is opens a new source
+	input.watchForStalls(input.stalledFor, func() error { return input.Seek(input.Position()) })
+
+	go input.fetchMetadata(source.tags)
+
 	return input, nil
 }
+
+// creates a track that plays a ytdlp input when it reaches the front of a queue
+func NewYtdlpTrack(url string, quality ytdlp.YtdlpAudioQuality) Track {
+	return newSeekableTrack(url, func(s *Session, offset time.Duration) (Input, error) {
+		input, err := s.newYtdlpInput(url, quality, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return input, nil
+	}).withSource(TrackSource{Kind: TrackKind_Ytdlp, URL: url, Quality: quality})
+}
+
+func ytdlpCacheKey(url string, quality ytdlp.YtdlpAudioQuality) string {
+	metadata := audioCacheMetadata()
+	return audiocache.Key("ytdlp", url, quality, metadata.Codec, metadata.SampleRateHz, metadata.NumChannels, metadata.FrameSize)
+}
@@ -0,0 +1,128 @@
+package audiosession
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/exec/ytdlp"
+)
+
+// creates an input from a second of cached audio. ytdlp does not exist, so the input can only be
+// played and seeked from the cache
+func newCachedTestYtdlpInput(t *testing.T) *YtdlpInput {
+	t.Helper()
+
+	initTestConfigJSON(t, fmt.Sprintf(`{
+		"cache": {"directory": %q},
+		"ytdlp": {"exePath": "/nonexistent/yt-dlp"},
+		"discord": {"appId": "app", "publicKey": "key", "token": "token"}
+	}`, t.TempDir()))
+	initTestTelemetry(t)
+
+	// the cache is created the first time that it is used
+	audioCacheOnce, audioCache = sync.Once{}, nil
+	t.Cleanup(func() { audioCacheOnce, audioCache = sync.Once{}, nil })
+
+	session := newTestSession(t)
+
+	const url = "https://example.com/cached"
+	const quality = ytdlp.YtdlpAudioQuality_BestAudio
+
+	cache := getAudioCache(session.logger)
+	if cache == nil {
+		t.Fatal("expected the audio cache to be enabled")
+	}
+
+	tags := func() map[string]string { return map[string]string{ytdlpCacheTag_Title: "cached title"} }
+
+	caching, err := newCachingReader(session.logger, bytes.NewReader(sinePCM(time.Second)), cache, ytdlpCacheKey(url, quality), tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.Copy(io.Discard, caching)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caching.Finish(true)
+
+	input, err := session.newYtdlpInput(url, quality, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(input.Stop)
+
+	return input
+}
+
+func TestYtdlpInputSeek(t *testing.T) {
+	input := newCachedTestYtdlpInput(t)
+
+	prevSource := input.source
+
+	err := input.Seek(500 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if input.Position() != 500*time.Millisecond {
+		t.Errorf("expected the input to be at 500ms after seeking, got %s", input.Position())
+	}
+
+	if !prevSource.replaced.Load() || input.source == prevSource {
+		t.Errorf("expected seeking to replace the source of the input")
+	}
+
+	// seeking is not the end of the old source, so it does not stop the input
+	if input.State() == inputState_Stopped {
+		t.Errorf("expected the input to keep playing after seeking, got %v", input.Err())
+	}
+}
+
+func TestYtdlpInputSeekOutOfRange(t *testing.T) {
+	input := newCachedTestYtdlpInput(t)
+
+	if !input.Duration().IsSet() {
+		t.Fatal("expected the duration of cached audio to be known")
+	}
+
+	err := input.Seek(input.Duration().Get() + time.Second)
+	if !errors.Is(err, ErrSeekOutOfRange) {
+		t.Errorf("expected seeking past the end to fail with %v, got %v", ErrSeekOutOfRange, err)
+	}
+
+	if input.Position() != 0 {
+		t.Errorf("expected a failed seek to leave the position unchanged, got %s", input.Position())
+	}
+}
+
+func TestYtdlpInputSeekAfterStop(t *testing.T) {
+	input := newCachedTestYtdlpInput(t)
+
+	input.Stop()
+
+	// the source that is opened by seeking is closed again straight away
+	err := input.Seek(500 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if input.State() != inputState_Stopped {
+		t.Errorf("expected seeking not to start a stopped input again")
+	}
+
+	input.source.buffer.mu.Lock()
+	closed := input.source.buffer.closed
+	input.source.buffer.mu.Unlock()
+
+	if !closed {
+		t.Errorf("expected the source of a stopped input to be closed after seeking")
+	}
+}
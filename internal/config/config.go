@@ -11,6 +11,7 @@ import (
 type (
 	LoggingHandlerType  string
 	LoggingHandlerLevel string
+	DiscordSendPolicy   string
 )
 
 const (
@@ -23,6 +24,11 @@ const (
 	LoggingHandlerLevel_Error LoggingHandlerLevel = "error"
 	LoggingHandlerLevel_Fatal LoggingHandlerLevel = "fatal"
 	LoggingHandlerLevel_Panic LoggingHandlerLevel = "panic"
+
+	// what to do with an opus frame when the queue of frames waiting to be sent to discord is full
+	DiscordSendPolicy_DropOldest DiscordSendPolicy = "dropOldest"
+	DiscordSendPolicy_DropNewest DiscordSendPolicy = "dropNewest"
+	DiscordSendPolicy_Block      DiscordSendPolicy = "block"
 )
 
 var validatedConfig optional.Optional[Config]
@@ -31,12 +37,43 @@ type AudioConfig struct {
 	NumChannels  int
 	SampleRateHz int
 	BitrateKbps  int
+	CacheCodec   string
+
+	// how much audio inputs read ahead of playback, and how much they need before playing again
+	// once they have run dry
+	JitterBufferHighWaterMs int
+	JitterBufferLowWaterMs  int
+
+	// how long before the current track of a queue ends that the next track is started in the
+	// background. 0 disables preloading
+	QueuePreloadMs int
+
+	// how much audio each output can fall behind the session before the oldest audio is dropped
+	OutputBufferMs int
+
+	// how long an input can go without receiving any audio from its source before it is restarted
+	// from where it stalled, and how many times it is restarted before it fails
+	StallTimeoutMs   int
+	StallMaxRestarts int
 }
 
 type DiscordConfig struct {
 	AppId     string
 	PublicKey string
 	Token     string
+
+	// how many opus frames can wait to be sent to a voice connection, and what happens to frames
+	// once the queue is full. SendTimeoutMs is how long [DiscordSendPolicy_Block] waits for room.
+	// sessions play up to half of Audio.OutputBufferMs ahead, so the queue should hold more than
+	// that to avoid dropping frames while discord is keeping up
+	SendQueueFrames int
+	SendPolicy      DiscordSendPolicy
+	SendTimeoutMs   int
+
+	// frames stop being sent once the audio has been silent for SilenceMs. voice connections are
+	// left once they have been silent for IdleTimeoutMs. 0 disables leaving
+	SilenceMs     int
+	IdleTimeoutMs int
 }
 
 type LoggingHandlerConfig struct {
@@ -51,6 +88,11 @@ type LoggingConfig struct {
 
 type WebConfig struct {
 	Address string
+
+	// sessions can only be streamed over http when StreamEnabled is set. every listener has its own
+	// encoder, so a session has at most MaxStreamListeners of them
+	StreamEnabled      bool
+	MaxStreamListeners int
 }
 
 type YtdlpConfig struct {
@@ -62,13 +104,59 @@ type FfmpegConfig struct {
 	ExePath optional.Optional[string]
 }
 
+type CacheConfig struct {
+	Directory    optional.Optional[string]
+	MaxSizeBytes int64
+}
+
+type MediaConfig struct {
+	Directory optional.Optional[string]
+}
+
+type SnapshotConfig struct {
+	// sessions are saved to and restored from this directory. sessions are not saved if it is not set
+	Directory  optional.Optional[string]
+	IntervalMs int
+}
+
+type RecordingConfig struct {
+	// recordings are written to this directory. /record is disabled if it is not set
+	Directory optional.Optional[string]
+
+	// one of "wav", "opus" or "flac"
+	Format string
+
+	// a new file is started once the current file reaches either limit. 0 disables the limit
+	MaxSizeBytes  int64
+	MaxDurationMs int
+}
+
+type SoundboardClipConfig struct {
+	Volume     optional.Optional[float64]
+	CooldownMs optional.Optional[int]
+}
+
+type SoundboardConfig struct {
+	Directory  optional.Optional[string]
+	Volume     float64
+	CooldownMs int
+
+	// overrides the volume and cooldown of clips by name
+	Clips map[string]SoundboardClipConfig
+}
+
 type Config struct {
-	Audio   AudioConfig
-	Discord DiscordConfig
-	Logging LoggingConfig
-	Web     WebConfig
-	Ytdlp   YtdlpConfig
-	Ffmpeg  FfmpegConfig
+	Audio      AudioConfig
+	Discord    DiscordConfig
+	Logging    LoggingConfig
+	Web        WebConfig
+	Ytdlp      YtdlpConfig
+	Ffmpeg     FfmpegConfig
+	Cache      CacheConfig
+	Media      MediaConfig
+	Snapshot   SnapshotConfig
+	Recording  RecordingConfig
+	Soundboard SoundboardConfig
 }
 
 type ConfigInitOptions struct {
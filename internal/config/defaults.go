@@ -1,6 +1,7 @@
 package config
 
 import (
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
 	"accidentallycoded.com/fredboard/v3/internal/optional"
 )
 
@@ -21,6 +22,58 @@ func applyDefaults(cfg *unvalidatedConfig) {
 		cfg.Audio.GetMut().BitrateKbps.Set(64)
 	}
 
+	if !cfg.Audio.Get().CacheCodec.IsSet() {
+		cfg.Audio.GetMut().CacheCodec.Set(codecs.CodecName_Opus)
+	}
+
+	if !cfg.Audio.Get().JitterBufferHighWaterMs.IsSet() {
+		cfg.Audio.GetMut().JitterBufferHighWaterMs.Set(5000)
+	}
+
+	if !cfg.Audio.Get().JitterBufferLowWaterMs.IsSet() {
+		cfg.Audio.GetMut().JitterBufferLowWaterMs.Set(500)
+	}
+
+	if !cfg.Audio.Get().QueuePreloadMs.IsSet() {
+		cfg.Audio.GetMut().QueuePreloadMs.Set(10000)
+	}
+
+	if !cfg.Audio.Get().OutputBufferMs.IsSet() {
+		cfg.Audio.GetMut().OutputBufferMs.Set(1000)
+	}
+
+	if !cfg.Audio.Get().StallTimeoutMs.IsSet() {
+		cfg.Audio.GetMut().StallTimeoutMs.Set(15000)
+	}
+
+	if !cfg.Audio.Get().StallMaxRestarts.IsSet() {
+		cfg.Audio.GetMut().StallMaxRestarts.Set(3)
+	}
+
+	if !cfg.Discord.IsSet() {
+		cfg.Discord = optional.Make(unvalidatedDiscordConfig{})
+	}
+
+	if !cfg.Discord.Get().SendQueueFrames.IsSet() {
+		cfg.Discord.GetMut().SendQueueFrames.Set(50)
+	}
+
+	if !cfg.Discord.Get().SendPolicy.IsSet() {
+		cfg.Discord.GetMut().SendPolicy.Set(DiscordSendPolicy_DropOldest)
+	}
+
+	if !cfg.Discord.Get().SendTimeoutMs.IsSet() {
+		cfg.Discord.GetMut().SendTimeoutMs.Set(100)
+	}
+
+	if !cfg.Discord.Get().SilenceMs.IsSet() {
+		cfg.Discord.GetMut().SilenceMs.Set(200)
+	}
+
+	if !cfg.Discord.Get().IdleTimeoutMs.IsSet() {
+		cfg.Discord.GetMut().IdleTimeoutMs.Set(5 * 60 * 1000) // 5 minutes
+	}
+
 	if !cfg.Logging.IsSet() {
 		cfg.Logging.Set(unvalidatedLoggingConfig{})
 	}
@@ -42,4 +95,57 @@ func applyDefaults(cfg *unvalidatedConfig) {
 	if !cfg.Web.Get().Address.IsSet() {
 		cfg.Web.GetMut().Address.Set(":8080")
 	}
+
+	// streams are public to anyone with the token, so they have to be enabled explicitly
+	if !cfg.Web.Get().StreamEnabled.IsSet() {
+		cfg.Web.GetMut().StreamEnabled.Set(false)
+	}
+
+	if !cfg.Web.Get().MaxStreamListeners.IsSet() {
+		cfg.Web.GetMut().MaxStreamListeners.Set(8)
+	}
+
+	if !cfg.Cache.IsSet() {
+		cfg.Cache.Set(unvalidatedCacheConfig{})
+	}
+
+	if !cfg.Cache.Get().MaxSizeBytes.IsSet() {
+		cfg.Cache.GetMut().MaxSizeBytes.Set(1 << 30) // 1 GiB
+	}
+
+	if !cfg.Snapshot.IsSet() {
+		cfg.Snapshot.Set(unvalidatedSnapshotConfig{})
+	}
+
+	if !cfg.Snapshot.Get().IntervalMs.IsSet() {
+		cfg.Snapshot.GetMut().IntervalMs.Set(30000)
+	}
+
+	if !cfg.Recording.IsSet() {
+		cfg.Recording.Set(unvalidatedRecordingConfig{})
+	}
+
+	if !cfg.Recording.Get().Format.IsSet() {
+		cfg.Recording.GetMut().Format.Set("flac")
+	}
+
+	if !cfg.Recording.Get().MaxSizeBytes.IsSet() {
+		cfg.Recording.GetMut().MaxSizeBytes.Set(0)
+	}
+
+	if !cfg.Recording.Get().MaxDurationMs.IsSet() {
+		cfg.Recording.GetMut().MaxDurationMs.Set(0)
+	}
+
+	if !cfg.Soundboard.IsSet() {
+		cfg.Soundboard.Set(unvalidatedSoundboardConfig{})
+	}
+
+	if !cfg.Soundboard.Get().Volume.IsSet() {
+		cfg.Soundboard.GetMut().Volume.Set(1)
+	}
+
+	if !cfg.Soundboard.Get().CooldownMs.IsSet() {
+		cfg.Soundboard.GetMut().CooldownMs.Set(3000)
+	}
 }
@@ -7,9 +7,16 @@ import (
 )
 
 type jsonAudioConfig struct {
-	NumChannels  optional.Optional[int] `json:"numChannels"`
-	SampleRateHz optional.Optional[int] `json:"sampleRateHz"`
-	BitrateKbps  optional.Optional[int] `json:"bitrateKbps"`
+	NumChannels             optional.Optional[int]    `json:"numChannels"`
+	SampleRateHz            optional.Optional[int]    `json:"sampleRateHz"`
+	BitrateKbps             optional.Optional[int]    `json:"bitrateKbps"`
+	CacheCodec              optional.Optional[string] `json:"cacheCodec"`
+	JitterBufferHighWaterMs optional.Optional[int]    `json:"jitterBufferHighWaterMs"`
+	JitterBufferLowWaterMs  optional.Optional[int]    `json:"jitterBufferLowWaterMs"`
+	QueuePreloadMs          optional.Optional[int]    `json:"queuePreloadMs"`
+	OutputBufferMs          optional.Optional[int]    `json:"outputBufferMs"`
+	StallTimeoutMs          optional.Optional[int]    `json:"stallTimeoutMs"`
+	StallMaxRestarts        optional.Optional[int]    `json:"stallMaxRestarts"`
 }
 
 func (c jsonAudioConfig) merge(cfg unvalidatedAudioConfig) unvalidatedAudioConfig {
@@ -25,6 +32,34 @@ func (c jsonAudioConfig) merge(cfg unvalidatedAudioConfig) unvalidatedAudioConfi
 		cfg.BitrateKbps.Set(c.BitrateKbps.Get())
 	}
 
+	if !cfg.CacheCodec.IsSet() && c.CacheCodec.IsSet() {
+		cfg.CacheCodec.Set(c.CacheCodec.Get())
+	}
+
+	if !cfg.JitterBufferHighWaterMs.IsSet() && c.JitterBufferHighWaterMs.IsSet() {
+		cfg.JitterBufferHighWaterMs.Set(c.JitterBufferHighWaterMs.Get())
+	}
+
+	if !cfg.JitterBufferLowWaterMs.IsSet() && c.JitterBufferLowWaterMs.IsSet() {
+		cfg.JitterBufferLowWaterMs.Set(c.JitterBufferLowWaterMs.Get())
+	}
+
+	if !cfg.QueuePreloadMs.IsSet() && c.QueuePreloadMs.IsSet() {
+		cfg.QueuePreloadMs.Set(c.QueuePreloadMs.Get())
+	}
+
+	if !cfg.OutputBufferMs.IsSet() && c.OutputBufferMs.IsSet() {
+		cfg.OutputBufferMs.Set(c.OutputBufferMs.Get())
+	}
+
+	if !cfg.StallTimeoutMs.IsSet() && c.StallTimeoutMs.IsSet() {
+		cfg.StallTimeoutMs.Set(c.StallTimeoutMs.Get())
+	}
+
+	if !cfg.StallMaxRestarts.IsSet() && c.StallMaxRestarts.IsSet() {
+		cfg.StallMaxRestarts.Set(c.StallMaxRestarts.Get())
+	}
+
 	return cfg
 }
 
@@ -32,6 +67,12 @@ type jsonDiscordConfig struct {
 	AppId     optional.Optional[string] `json:"appId"`
 	PublicKey optional.Optional[string] `json:"publicKey"`
 	Token     optional.Optional[string] `json:"token"`
+
+	SendQueueFrames optional.Optional[int]               `json:"sendQueueFrames"`
+	SendPolicy      optional.Optional[DiscordSendPolicy] `json:"sendPolicy"`
+	SendTimeoutMs   optional.Optional[int]               `json:"sendTimeoutMs"`
+	SilenceMs       optional.Optional[int]               `json:"silenceMs"`
+	IdleTimeoutMs   optional.Optional[int]               `json:"idleTimeoutMs"`
 }
 
 func (c jsonDiscordConfig) merge(cfg unvalidatedDiscordConfig) unvalidatedDiscordConfig {
@@ -47,6 +88,26 @@ func (c jsonDiscordConfig) merge(cfg unvalidatedDiscordConfig) unvalidatedDiscor
 		cfg.Token.Set(c.Token.Get())
 	}
 
+	if !cfg.SendQueueFrames.IsSet() && c.SendQueueFrames.IsSet() {
+		cfg.SendQueueFrames.Set(c.SendQueueFrames.Get())
+	}
+
+	if !cfg.SendPolicy.IsSet() && c.SendPolicy.IsSet() {
+		cfg.SendPolicy.Set(c.SendPolicy.Get())
+	}
+
+	if !cfg.SendTimeoutMs.IsSet() && c.SendTimeoutMs.IsSet() {
+		cfg.SendTimeoutMs.Set(c.SendTimeoutMs.Get())
+	}
+
+	if !cfg.SilenceMs.IsSet() && c.SilenceMs.IsSet() {
+		cfg.SilenceMs.Set(c.SilenceMs.Get())
+	}
+
+	if !cfg.IdleTimeoutMs.IsSet() && c.IdleTimeoutMs.IsSet() {
+		cfg.IdleTimeoutMs.Set(c.IdleTimeoutMs.Get())
+	}
+
 	return cfg
 }
 
@@ -69,7 +130,9 @@ func (c jsonLoggingConfig) merge(cfg unvalidatedLoggingConfig) unvalidatedLoggin
 }
 
 type jsonWebConfig struct {
-	Address optional.Optional[string] `json:"address"`
+	Address            optional.Optional[string] `json:"address"`
+	StreamEnabled      optional.Optional[bool]   `json:"streamEnabled"`
+	MaxStreamListeners optional.Optional[int]    `json:"maxStreamListeners"`
 }
 
 func (c jsonWebConfig) merge(cfg unvalidatedWebConfig) unvalidatedWebConfig {
@@ -77,6 +140,14 @@ func (c jsonWebConfig) merge(cfg unvalidatedWebConfig) unvalidatedWebConfig {
 		cfg.Address.Set(c.Address.Get())
 	}
 
+	if !cfg.StreamEnabled.IsSet() && c.StreamEnabled.IsSet() {
+		cfg.StreamEnabled.Set(c.StreamEnabled.Get())
+	}
+
+	if !cfg.MaxStreamListeners.IsSet() && c.MaxStreamListeners.IsSet() {
+		cfg.MaxStreamListeners.Set(c.MaxStreamListeners.Get())
+	}
+
 	return cfg
 }
 
@@ -109,13 +180,129 @@ func (c jsonFfmpegConfig) merge(cfg unvalidatedFfmpegConfig) unvalidatedFfmpegCo
 	return cfg
 }
 
+type jsonCacheConfig struct {
+	Directory    optional.Optional[string] `json:"directory"`
+	MaxSizeBytes optional.Optional[int64]  `json:"maxSizeBytes"`
+}
+
+func (c jsonCacheConfig) merge(cfg unvalidatedCacheConfig) unvalidatedCacheConfig {
+	if !cfg.Directory.IsSet() && c.Directory.IsSet() {
+		cfg.Directory.Set(c.Directory.Get())
+	}
+
+	if !cfg.MaxSizeBytes.IsSet() && c.MaxSizeBytes.IsSet() {
+		cfg.MaxSizeBytes.Set(c.MaxSizeBytes.Get())
+	}
+
+	return cfg
+}
+
+type jsonMediaConfig struct {
+	Directory optional.Optional[string] `json:"directory"`
+}
+
+func (c jsonMediaConfig) merge(cfg unvalidatedMediaConfig) unvalidatedMediaConfig {
+	if !cfg.Directory.IsSet() && c.Directory.IsSet() {
+		cfg.Directory.Set(c.Directory.Get())
+	}
+
+	return cfg
+}
+
+type jsonSnapshotConfig struct {
+	Directory  optional.Optional[string] `json:"directory"`
+	IntervalMs optional.Optional[int]    `json:"intervalMs"`
+}
+
+func (c jsonSnapshotConfig) merge(cfg unvalidatedSnapshotConfig) unvalidatedSnapshotConfig {
+	if !cfg.Directory.IsSet() && c.Directory.IsSet() {
+		cfg.Directory.Set(c.Directory.Get())
+	}
+
+	if !cfg.IntervalMs.IsSet() && c.IntervalMs.IsSet() {
+		cfg.IntervalMs.Set(c.IntervalMs.Get())
+	}
+
+	return cfg
+}
+
+type jsonRecordingConfig struct {
+	Directory     optional.Optional[string] `json:"directory"`
+	Format        optional.Optional[string] `json:"format"`
+	MaxSizeBytes  optional.Optional[int64]  `json:"maxSizeBytes"`
+	MaxDurationMs optional.Optional[int]    `json:"maxDurationMs"`
+}
+
+func (c jsonRecordingConfig) merge(cfg unvalidatedRecordingConfig) unvalidatedRecordingConfig {
+	if !cfg.Directory.IsSet() && c.Directory.IsSet() {
+		cfg.Directory.Set(c.Directory.Get())
+	}
+
+	if !cfg.Format.IsSet() && c.Format.IsSet() {
+		cfg.Format.Set(c.Format.Get())
+	}
+
+	if !cfg.MaxSizeBytes.IsSet() && c.MaxSizeBytes.IsSet() {
+		cfg.MaxSizeBytes.Set(c.MaxSizeBytes.Get())
+	}
+
+	if !cfg.MaxDurationMs.IsSet() && c.MaxDurationMs.IsSet() {
+		cfg.MaxDurationMs.Set(c.MaxDurationMs.Get())
+	}
+
+	return cfg
+}
+
+type jsonSoundboardClipConfig struct {
+	Volume     optional.Optional[float64] `json:"volume"`
+	CooldownMs optional.Optional[int]     `json:"cooldownMs"`
+}
+
+type jsonSoundboardConfig struct {
+	Directory  optional.Optional[string]           `json:"directory"`
+	Volume     optional.Optional[float64]          `json:"volume"`
+	CooldownMs optional.Optional[int]              `json:"cooldownMs"`
+	Clips      map[string]jsonSoundboardClipConfig `json:"clips"`
+}
+
+func (c jsonSoundboardConfig) merge(cfg unvalidatedSoundboardConfig) unvalidatedSoundboardConfig {
+	if !cfg.Directory.IsSet() && c.Directory.IsSet() {
+		cfg.Directory.Set(c.Directory.Get())
+	}
+
+	if !cfg.Volume.IsSet() && c.Volume.IsSet() {
+		cfg.Volume.Set(c.Volume.Get())
+	}
+
+	if !cfg.CooldownMs.IsSet() && c.CooldownMs.IsSet() {
+		cfg.CooldownMs.Set(c.CooldownMs.Get())
+	}
+
+	for name, clip := range c.Clips {
+		if cfg.Clips == nil {
+			cfg.Clips = make(map[string]unvalidatedSoundboardClipConfig)
+		}
+
+		if _, ok := cfg.Clips[name]; !ok {
+			cfg.Clips[name] = unvalidatedSoundboardClipConfig{Volume: clip.Volume, CooldownMs: clip.CooldownMs}
+		}
+	}
+
+	return cfg
+}
+
 type jsonConfig struct {
-	Audio   optional.Optional[jsonAudioConfig]   `json:"audio"`
-	Discord optional.Optional[jsonDiscordConfig] `json:"discord"`
-	Logging optional.Optional[jsonLoggingConfig] `json:"logging"`
-	Web     optional.Optional[jsonWebConfig]     `json:"web"`
-	Ytdlp   optional.Optional[jsonYtdlpConfig]   `json:"ytdlp"`
-	Ffmpeg  optional.Optional[jsonFfmpegConfig]  `json:"ffmpeg"`
+	Audio      optional.Optional[jsonAudioConfig]      `json:"audio"`
+	Discord    optional.Optional[jsonDiscordConfig]    `json:"discord"`
+	Logging    optional.Optional[jsonLoggingConfig]    `json:"logging"`
+	Web        optional.Optional[jsonWebConfig]        `json:"web"`
+	Ytdlp      optional.Optional[jsonYtdlpConfig]      `json:"ytdlp"`
+	Ffmpeg     optional.Optional[jsonFfmpegConfig]     `json:"ffmpeg"`
+	Cache      optional.Optional[jsonCacheConfig]      `json:"cache"`
+	Media      optional.Optional[jsonMediaConfig]      `json:"media"`
+	Snapshot   optional.Optional[jsonSnapshotConfig]   `json:"snapshot"`
+	Recording  optional.Optional[jsonRecordingConfig]  `json:"recording"`
+	Soundboard optional.Optional[jsonSoundboardConfig] `json:"soundboard"`
 }
 
 func fromJson(data []byte) (cfg unvalidatedConfig, err error) {
@@ -168,5 +355,40 @@ func fromJson(data []byte) (cfg unvalidatedConfig, err error) {
 		cfg.Ffmpeg.Set(v.Ffmpeg.Get().merge(cfg.Ffmpeg.Get()))
 	}
 
+	if v.Cache.IsSet() {
+		if !cfg.Cache.IsSet() {
+			cfg.Cache = optional.Make(unvalidatedCacheConfig{})
+		}
+		cfg.Cache.Set(v.Cache.Get().merge(cfg.Cache.Get()))
+	}
+
+	if v.Media.IsSet() {
+		if !cfg.Media.IsSet() {
+			cfg.Media = optional.Make(unvalidatedMediaConfig{})
+		}
+		cfg.Media.Set(v.Media.Get().merge(cfg.Media.Get()))
+	}
+
+	if v.Snapshot.IsSet() {
+		if !cfg.Snapshot.IsSet() {
+			cfg.Snapshot = optional.Make(unvalidatedSnapshotConfig{})
+		}
+		cfg.Snapshot.Set(v.Snapshot.Get().merge(cfg.Snapshot.Get()))
+	}
+
+	if v.Recording.IsSet() {
+		if !cfg.Recording.IsSet() {
+			cfg.Recording = optional.Make(unvalidatedRecordingConfig{})
+		}
+		cfg.Recording.Set(v.Recording.Get().merge(cfg.Recording.Get()))
+	}
+
+	if v.Soundboard.IsSet() {
+		if !cfg.Soundboard.IsSet() {
+			cfg.Soundboard = optional.Make(unvalidatedSoundboardConfig{})
+		}
+		cfg.Soundboard.Set(v.Soundboard.Get().merge(cfg.Soundboard.Get()))
+	}
+
 	return cfg, nil
 }
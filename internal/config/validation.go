@@ -2,20 +2,35 @@ package config
 
 import (
 	"fmt"
+	"slices"
 
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
 	"accidentallycoded.com/fredboard/v3/internal/optional"
 )
 
 type unvalidatedAudioConfig struct {
-	NumChannels  optional.Optional[int]
-	SampleRateHz optional.Optional[int]
-	BitrateKbps  optional.Optional[int]
+	NumChannels             optional.Optional[int]
+	SampleRateHz            optional.Optional[int]
+	BitrateKbps             optional.Optional[int]
+	CacheCodec              optional.Optional[string]
+	JitterBufferHighWaterMs optional.Optional[int]
+	JitterBufferLowWaterMs  optional.Optional[int]
+	QueuePreloadMs          optional.Optional[int]
+	OutputBufferMs          optional.Optional[int]
+	StallTimeoutMs          optional.Optional[int]
+	StallMaxRestarts        optional.Optional[int]
 }
 
 type unvalidatedDiscordConfig struct {
 	AppId     optional.Optional[string]
 	PublicKey optional.Optional[string]
 	Token     optional.Optional[string]
+
+	SendQueueFrames optional.Optional[int]
+	SendPolicy      optional.Optional[DiscordSendPolicy]
+	SendTimeoutMs   optional.Optional[int]
+	SilenceMs       optional.Optional[int]
+	IdleTimeoutMs   optional.Optional[int]
 }
 
 type unvalidatedLoggingHandlerConfig struct {
@@ -29,7 +44,9 @@ type unvalidatedLoggingConfig struct {
 }
 
 type unvalidatedWebConfig struct {
-	Address optional.Optional[string]
+	Address            optional.Optional[string]
+	StreamEnabled      optional.Optional[bool]
+	MaxStreamListeners optional.Optional[int]
 }
 
 type unvalidatedYtdlpConfig struct {
@@ -41,13 +58,51 @@ type unvalidatedFfmpegConfig struct {
 	ExePath optional.Optional[string]
 }
 
+type unvalidatedCacheConfig struct {
+	Directory    optional.Optional[string]
+	MaxSizeBytes optional.Optional[int64]
+}
+
+type unvalidatedMediaConfig struct {
+	Directory optional.Optional[string]
+}
+
+type unvalidatedSnapshotConfig struct {
+	Directory  optional.Optional[string]
+	IntervalMs optional.Optional[int]
+}
+
+type unvalidatedRecordingConfig struct {
+	Directory     optional.Optional[string]
+	Format        optional.Optional[string]
+	MaxSizeBytes  optional.Optional[int64]
+	MaxDurationMs optional.Optional[int]
+}
+
+type unvalidatedSoundboardClipConfig struct {
+	Volume     optional.Optional[float64]
+	CooldownMs optional.Optional[int]
+}
+
+type unvalidatedSoundboardConfig struct {
+	Directory  optional.Optional[string]
+	Volume     optional.Optional[float64]
+	CooldownMs optional.Optional[int]
+	Clips      map[string]unvalidatedSoundboardClipConfig
+}
+
 type unvalidatedConfig struct {
-	Audio   optional.Optional[unvalidatedAudioConfig]
-	Discord optional.Optional[unvalidatedDiscordConfig]
-	Logging optional.Optional[unvalidatedLoggingConfig]
-	Web     optional.Optional[unvalidatedWebConfig]
-	Ytdlp   optional.Optional[unvalidatedYtdlpConfig]
-	Ffmpeg  optional.Optional[unvalidatedFfmpegConfig]
+	Audio      optional.Optional[unvalidatedAudioConfig]
+	Discord    optional.Optional[unvalidatedDiscordConfig]
+	Logging    optional.Optional[unvalidatedLoggingConfig]
+	Web        optional.Optional[unvalidatedWebConfig]
+	Ytdlp      optional.Optional[unvalidatedYtdlpConfig]
+	Ffmpeg     optional.Optional[unvalidatedFfmpegConfig]
+	Cache      optional.Optional[unvalidatedCacheConfig]
+	Media      optional.Optional[unvalidatedMediaConfig]
+	Snapshot   optional.Optional[unvalidatedSnapshotConfig]
+	Recording  optional.Optional[unvalidatedRecordingConfig]
+	Soundboard optional.Optional[unvalidatedSoundboardConfig]
 }
 
 type ConfigurationValidationError struct {
@@ -94,6 +149,77 @@ func (c unvalidatedAudioConfig) validate() (cfg AudioConfig, errs []Configuratio
 		cfg.BitrateKbps = c.BitrateKbps.Get()
 	}
 
+	switch {
+	case !c.CacheCodec.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.cacheCodec", "required option is not set"))
+	default:
+		codec, err := codecs.Lookup(c.CacheCodec.Get())
+		switch {
+		case err != nil:
+			errs = append(errs, NewConfigurationValidationError("audio.cacheCodec", err.Error()))
+		case !codec.Supports(cfg.SampleRateHz, cfg.NumChannels):
+			errs = append(errs, NewConfigurationValidationError("audio.cacheCodec", "codec does not support the configured sample rate and number of channels"))
+		default:
+			cfg.CacheCodec = c.CacheCodec.Get()
+		}
+	}
+
+	switch {
+	case !c.JitterBufferHighWaterMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.jitterBufferHighWaterMs", "required option is not set"))
+	case c.JitterBufferHighWaterMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("audio.jitterBufferHighWaterMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.JitterBufferHighWaterMs = c.JitterBufferHighWaterMs.Get()
+	}
+
+	switch {
+	case !c.JitterBufferLowWaterMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.jitterBufferLowWaterMs", "required option is not set"))
+	case c.JitterBufferLowWaterMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("audio.jitterBufferLowWaterMs", "invalid value (must not be negative)"))
+	case c.JitterBufferLowWaterMs.Get() > cfg.JitterBufferHighWaterMs:
+		errs = append(errs, NewConfigurationValidationError("audio.jitterBufferLowWaterMs", "invalid value (must not be greater than audio.jitterBufferHighWaterMs)"))
+	default:
+		cfg.JitterBufferLowWaterMs = c.JitterBufferLowWaterMs.Get()
+	}
+
+	switch {
+	case !c.QueuePreloadMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.queuePreloadMs", "required option is not set"))
+	case c.QueuePreloadMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("audio.queuePreloadMs", "invalid value (must not be negative)"))
+	default:
+		cfg.QueuePreloadMs = c.QueuePreloadMs.Get()
+	}
+
+	switch {
+	case !c.OutputBufferMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.outputBufferMs", "required option is not set"))
+	case c.OutputBufferMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("audio.outputBufferMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.OutputBufferMs = c.OutputBufferMs.Get()
+	}
+
+	switch {
+	case !c.StallTimeoutMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.stallTimeoutMs", "required option is not set"))
+	case c.StallTimeoutMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("audio.stallTimeoutMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.StallTimeoutMs = c.StallTimeoutMs.Get()
+	}
+
+	switch {
+	case !c.StallMaxRestarts.IsSet():
+		errs = append(errs, NewConfigurationValidationError("audio.stallMaxRestarts", "required option is not set"))
+	case c.StallMaxRestarts.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("audio.stallMaxRestarts", "invalid value (must not be negative)"))
+	default:
+		cfg.StallMaxRestarts = c.StallMaxRestarts.Get()
+	}
+
 	return cfg, errs
 }
 
@@ -121,6 +247,54 @@ func (c unvalidatedDiscordConfig) validate() (cfg DiscordConfig, errs []Configur
 		cfg.Token = c.Token.Get()
 	}
 
+	switch {
+	case !c.SendQueueFrames.IsSet():
+		errs = append(errs, NewConfigurationValidationError("discord.sendQueueFrames", "required option is not set"))
+	case c.SendQueueFrames.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("discord.sendQueueFrames", "invalid value (must be greater than 0)"))
+	default:
+		cfg.SendQueueFrames = c.SendQueueFrames.Get()
+	}
+
+	switch {
+	case !c.SendPolicy.IsSet():
+		errs = append(errs, NewConfigurationValidationError("discord.sendPolicy", "required option is not set"))
+	case
+		c.SendPolicy.Get() == DiscordSendPolicy_DropOldest,
+		c.SendPolicy.Get() == DiscordSendPolicy_DropNewest,
+		c.SendPolicy.Get() == DiscordSendPolicy_Block:
+		cfg.SendPolicy = c.SendPolicy.Get()
+	default:
+		errs = append(errs, NewConfigurationValidationError("discord.sendPolicy", "invalid send policy"))
+	}
+
+	switch {
+	case !c.SendTimeoutMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("discord.sendTimeoutMs", "required option is not set"))
+	case c.SendTimeoutMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("discord.sendTimeoutMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.SendTimeoutMs = c.SendTimeoutMs.Get()
+	}
+
+	switch {
+	case !c.SilenceMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("discord.silenceMs", "required option is not set"))
+	case c.SilenceMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("discord.silenceMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.SilenceMs = c.SilenceMs.Get()
+	}
+
+	switch {
+	case !c.IdleTimeoutMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("discord.idleTimeoutMs", "required option is not set"))
+	case c.IdleTimeoutMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("discord.idleTimeoutMs", "invalid value (must not be negative)"))
+	default:
+		cfg.IdleTimeoutMs = c.IdleTimeoutMs.Get()
+	}
+
 	return cfg, errs
 }
 
@@ -188,6 +362,22 @@ func (c unvalidatedWebConfig) validate() (cfg WebConfig, errs []ConfigurationVal
 		cfg.Address = c.Address.Get()
 	}
 
+	switch {
+	case !c.StreamEnabled.IsSet():
+		errs = append(errs, NewConfigurationValidationError("web.streamEnabled", "required option is not set"))
+	default:
+		cfg.StreamEnabled = c.StreamEnabled.Get()
+	}
+
+	switch {
+	case !c.MaxStreamListeners.IsSet():
+		errs = append(errs, NewConfigurationValidationError("web.maxStreamListeners", "required option is not set"))
+	case c.MaxStreamListeners.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("web.maxStreamListeners", "invalid value (must be greater than 0)"))
+	default:
+		cfg.MaxStreamListeners = c.MaxStreamListeners.Get()
+	}
+
 	return cfg, errs
 }
 
@@ -208,6 +398,162 @@ func (c unvalidatedFfmpegConfig) validate() (cfg FfmpegConfig, errs []Configurat
 	return cfg, errs
 }
 
+func (c unvalidatedCacheConfig) validate() (cfg CacheConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Directory.IsSet() && c.Directory.Get() == "":
+		errs = append(errs, NewConfigurationValidationError("cache.directory", "invalid value (must not be empty)"))
+	default:
+		cfg.Directory = c.Directory
+	}
+
+	switch {
+	case !c.MaxSizeBytes.IsSet():
+		errs = append(errs, NewConfigurationValidationError("cache.maxSizeBytes", "required option is not set"))
+	case c.MaxSizeBytes.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("cache.maxSizeBytes", "invalid value (must be greater than 0)"))
+	default:
+		cfg.MaxSizeBytes = c.MaxSizeBytes.Get()
+	}
+
+	return cfg, errs
+}
+
+func (c unvalidatedMediaConfig) validate() (cfg MediaConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Directory.IsSet() && c.Directory.Get() == "":
+		errs = append(errs, NewConfigurationValidationError("media.directory", "invalid value (must not be empty)"))
+	default:
+		cfg.Directory = c.Directory
+	}
+
+	return cfg, errs
+}
+
+func (c unvalidatedSnapshotConfig) validate() (cfg SnapshotConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Directory.IsSet() && c.Directory.Get() == "":
+		errs = append(errs, NewConfigurationValidationError("snapshot.directory", "invalid value (must not be empty)"))
+	default:
+		cfg.Directory = c.Directory
+	}
+
+	switch {
+	case !c.IntervalMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("snapshot.intervalMs", "required option is not set"))
+	case c.IntervalMs.Get() <= 0:
+		errs = append(errs, NewConfigurationValidationError("snapshot.intervalMs", "invalid value (must be greater than 0)"))
+	default:
+		cfg.IntervalMs = c.IntervalMs.Get()
+	}
+
+	return cfg, errs
+}
+
+func (c unvalidatedRecordingConfig) validate() (cfg RecordingConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Directory.IsSet() && c.Directory.Get() == "":
+		errs = append(errs, NewConfigurationValidationError("recording.directory", "invalid value (must not be empty)"))
+	default:
+		cfg.Directory = c.Directory
+	}
+
+	switch {
+	case !c.Format.IsSet():
+		errs = append(errs, NewConfigurationValidationError("recording.format", "required option is not set"))
+	case !slices.Contains([]string{"wav", "opus", "flac"}, c.Format.Get()):
+		errs = append(errs, NewConfigurationValidationError("recording.format", "invalid value (must be one of wav, opus or flac)"))
+	default:
+		cfg.Format = c.Format.Get()
+	}
+
+	switch {
+	case !c.MaxSizeBytes.IsSet():
+		errs = append(errs, NewConfigurationValidationError("recording.maxSizeBytes", "required option is not set"))
+	case c.MaxSizeBytes.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("recording.maxSizeBytes", "invalid value (must not be negative)"))
+	default:
+		cfg.MaxSizeBytes = c.MaxSizeBytes.Get()
+	}
+
+	switch {
+	case !c.MaxDurationMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("recording.maxDurationMs", "required option is not set"))
+	case c.MaxDurationMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("recording.maxDurationMs", "invalid value (must not be negative)"))
+	default:
+		cfg.MaxDurationMs = c.MaxDurationMs.Get()
+	}
+
+	return cfg, errs
+}
+
+func (c unvalidatedSoundboardClipConfig) validate(name string) (cfg SoundboardClipConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Volume.IsSet() && c.Volume.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError(fmt.Sprintf("soundboard.clips[%q].volume", name), "invalid value (must not be negative)"))
+	default:
+		cfg.Volume = c.Volume
+	}
+
+	switch {
+	case c.CooldownMs.IsSet() && c.CooldownMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError(fmt.Sprintf("soundboard.clips[%q].cooldownMs", name), "invalid value (must not be negative)"))
+	default:
+		cfg.CooldownMs = c.CooldownMs
+	}
+
+	return cfg, errs
+}
+
+func (c unvalidatedSoundboardConfig) validate() (cfg SoundboardConfig, errs []ConfigurationValidationError) {
+	errs = make([]ConfigurationValidationError, 0)
+
+	switch {
+	case c.Directory.IsSet() && c.Directory.Get() == "":
+		errs = append(errs, NewConfigurationValidationError("soundboard.directory", "invalid value (must not be empty)"))
+	default:
+		cfg.Directory = c.Directory
+	}
+
+	switch {
+	case !c.Volume.IsSet():
+		errs = append(errs, NewConfigurationValidationError("soundboard.volume", "required option is not set"))
+	case c.Volume.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("soundboard.volume", "invalid value (must not be negative)"))
+	default:
+		cfg.Volume = c.Volume.Get()
+	}
+
+	switch {
+	case !c.CooldownMs.IsSet():
+		errs = append(errs, NewConfigurationValidationError("soundboard.cooldownMs", "required option is not set"))
+	case c.CooldownMs.Get() < 0:
+		errs = append(errs, NewConfigurationValidationError("soundboard.cooldownMs", "invalid value (must not be negative)"))
+	default:
+		cfg.CooldownMs = c.CooldownMs.Get()
+	}
+
+	cfg.Clips = make(map[string]SoundboardClipConfig, len(c.Clips))
+
+	for name, clip := range c.Clips {
+		clipCfg, clipErrs := clip.validate(name)
+		cfg.Clips[name] = clipCfg
+		errs = append(errs, clipErrs...)
+	}
+
+	return cfg, errs
+}
+
 func validate(uCfg unvalidatedConfig) (cfg Config, errs []ConfigurationValidationError) {
 	var verrs []ConfigurationValidationError
 
@@ -259,6 +605,46 @@ func validate(uCfg unvalidatedConfig) (cfg Config, errs []ConfigurationValidatio
 		errs = append(errs, verrs...)
 	}
 
+	if !uCfg.Cache.IsSet() {
+		uCfg.Cache = optional.Make(unvalidatedCacheConfig{})
+	}
+
+	if cfg.Cache, verrs = uCfg.Cache.Get().validate(); len(verrs) > 0 {
+		errs = append(errs, verrs...)
+	}
+
+	if !uCfg.Media.IsSet() {
+		uCfg.Media = optional.Make(unvalidatedMediaConfig{})
+	}
+
+	if cfg.Media, verrs = uCfg.Media.Get().validate(); len(verrs) > 0 {
+		errs = append(errs, verrs...)
+	}
+
+	if !uCfg.Snapshot.IsSet() {
+		uCfg.Snapshot = optional.Make(unvalidatedSnapshotConfig{})
+	}
+
+	if cfg.Snapshot, verrs = uCfg.Snapshot.Get().validate(); len(verrs) > 0 {
+		errs = append(errs, verrs...)
+	}
+
+	if !uCfg.Recording.IsSet() {
+		uCfg.Recording = optional.Make(unvalidatedRecordingConfig{})
+	}
+
+	if cfg.Recording, verrs = uCfg.Recording.Get().validate(); len(verrs) > 0 {
+		errs = append(errs, verrs...)
+	}
+
+	if !uCfg.Soundboard.IsSet() {
+		uCfg.Soundboard = optional.Make(unvalidatedSoundboardConfig{})
+	}
+
+	if cfg.Soundboard, verrs = uCfg.Soundboard.Get().validate(); len(verrs) > 0 {
+		errs = append(errs, verrs...)
+	}
+
 	if len(errs) > 0 {
 		return Config{}, errs
 	}
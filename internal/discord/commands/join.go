@@ -1,17 +1,47 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+
 	"accidentallycoded.com/fredboard/v3/internal/audiosession"
 	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 	"github.com/bwmarrin/discordgo"
 )
 
+type joinCommandOptions struct {
+	sessionId optional.Optional[string]
+	token     optional.Optional[string]
+}
+
+func getJoinCommandOptions(interaction *discordgo.Interaction) (*joinCommandOptions, error) {
+	sessionId, err := interactions.GetOptionalStringOpt(interaction, "session")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option \"session\": %w", err)
+	}
+
+	token, err := interactions.GetOptionalStringOpt(interaction, "token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option \"token\": %w", err)
+	}
+
+	return &joinCommandOptions{sessionId, token}, nil
+}
+
 func Join(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
 	if interactions.Acknowledge(logger, session, interaction) != nil {
 		return
 	}
 
+	opts, err := getJoinCommandOptions(interaction)
+	if err != nil {
+		logger.Error("failed to execute /Join command due to failure while getting command options", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
 	conn, exists, err := interactions.FindOrCreateVoiceConn(session, interaction)
 	if err != nil {
 		logger.Error("failed to execute /Join command due to failure while finding or creating voice connection", "interaction", interaction, "error", err)
@@ -25,27 +55,46 @@ func Join(logger *logging.Logger, session *discordgo.Session, interaction *disco
 		return
 	}
 
-	// TODO: ensure that audio session does not already exist for conn (it shouldnt exist, but it should still be asserted)
-
-	audioSession := audiosession.New(logger)
-	output, err := audioSession.AddDiscordVoiceConnOutput(conn)
+	var audioSession *audiosession.Session
+	var output *audiosession.DiscordVoiceConnOutput
 
-	if err != nil {
-		logger.Error("failed to execute /Join command due error while adding discord voice conn output to the audio session", "interaction", interaction, "conn", conn, "audioSession", audioSession, "error", err)
-		interactions.RespondWithError(logger, session, interaction, err)
-		return
+	// the voice connection is disconnected once the output is removed from the session
+	switch {
+	case !opts.sessionId.IsSet():
+		// the session of the guild may still exist without the voice connection, such as while
+		// another guild listens to it, in which case the voice connection joins it again
+		audioSession, output, _, err = audiosession.JoinOwnSession(logger, interaction.GuildID, conn)
+	case opts.sessionId.Get() == interaction.GuildID:
+		audioSession, err = audiosession.Get(opts.sessionId.Get())
+	case !opts.token.IsSet():
+		err = audiosession.ErrInvalidShareToken
+	default:
+		// fan the audio of a session that another guild has shared out to this voice channel
+		audioSession, err = audiosession.GetShared(opts.sessionId.Get(), opts.token.Get())
+	}
 
-		// TODO: destroy audio session
+	if err == nil && output == nil {
+		output, err = audioSession.AddDiscordVoiceConnOutput(conn)
 	}
 
-	audioSession.OnOutputRemoved.AddDelegate(func(param audiosession.SessionEvent_OnOutputRemoved) {
-		if param.OutputRemoved == output {
-			err := conn.Disconnect()
-			if err != nil {
-				logger.Error("an error ocurred while disconnecting discord voice connection", "interaction", interaction, "error", err)
-			}
-		}
-	})
+	// a session that was destroyed since it was found has ended the same as one that was never found
+	ended := errors.Is(err, audiosession.ErrSessionNotFound) || errors.Is(err, audiosession.ErrSessionDestroyed)
+
+	switch {
+	case err == nil:
+	case opts.sessionId.IsSet() && opts.sessionId.Get() == interaction.GuildID && ended:
+		logger.Debug("rejecting /Join command due to the server not having an audio session", "interaction", interaction, "error", err)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "This server does not have any audio to join. Use /join without a session or /play instead.", errors.Join(err, conn.Disconnect()))
+		return
+	case ended || errors.Is(err, audiosession.ErrInvalidShareToken):
+		logger.Debug("rejecting /Join command due to the audio session not being shared with the token", "interaction", interaction, "error", err)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "The server has not shared its audio with that token. Ask it to use /share.", errors.Join(err, conn.Disconnect()))
+		return
+	default:
+		logger.Error("failed to execute /Join command due to error while joining the audio session", "interaction", interaction, "conn", conn, "error", err)
+		interactions.RespondWithError(logger, session, interaction, errors.Join(err, conn.Disconnect()))
+		return
+	}
 
 	interactions.RespondWithMessage(logger, session, interaction, "Joined")
 	logger.Debug("completed /Join command", "interaction", interaction, "conn", conn, "audioSession", audioSession, "output", output)
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+var loopModes = map[string]audiosession.LoopMode{
+	"off": audiosession.LoopMode_Off,
+	"one": audiosession.LoopMode_One,
+	"all": audiosession.LoopMode_All,
+}
+
+// repeats the track that is playing or the whole queue. takes effect once the track that is playing ends
+func Loop(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	opt, err := interactions.GetRequiredStringOpt(interaction, "mode")
+	if err != nil {
+		logger.Debug("rejecting /Loop command due to missing mode", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	mode, ok := loopModes[opt]
+	if !ok {
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Unknown mode %q, use off, one or all.", opt))
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Loop")
+	if !ok {
+		return
+	}
+
+	audioSession.Queue().SetLoopMode(mode)
+
+	switch mode {
+	case audiosession.LoopMode_One:
+		interactions.RespondWithMessage(logger, session, interaction, "Repeating the track that is playing")
+	case audiosession.LoopMode_All:
+		interactions.RespondWithMessage(logger, session, interaction, "Repeating the queue")
+	default:
+		interactions.RespondWithMessage(logger, session, interaction, "Stopped repeating")
+	}
+
+	logger.Debug("completed /Loop command", "interaction", interaction, "audioSession", audioSession, "mode", opt)
+}
+
+// plays the queue in a random order. the order can be repeated by shuffling with the same seed
+func Shuffle(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	enabled, err := interactions.GetOptionalBoolOpt(interaction, "enabled")
+	if err != nil {
+		logger.Debug("rejecting /Shuffle command due to invalid option", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	seedOpt, err := interactions.GetOptionalIntOpt(interaction, "seed")
+	if err != nil {
+		logger.Debug("rejecting /Shuffle command due to invalid seed", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Shuffle")
+	if !ok {
+		return
+	}
+
+	if enabled.IsSet() && !enabled.Get() {
+		audioSession.Queue().SetShuffle(false, 0)
+
+		interactions.RespondWithMessage(logger, session, interaction, "Stopped shuffling")
+		logger.Debug("completed /Shuffle command", "interaction", interaction, "audioSession", audioSession, "enabled", false)
+
+		return
+	}
+
+	// discord integers only hold 53 bits, so a random seed is kept small enough to be given back
+	seed := rand.Uint64() >> 11
+	if seedOpt.IsSet() {
+		seed = uint64(seedOpt.Get())
+	}
+
+	audioSession.Queue().SetShuffle(true, seed)
+
+	interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Shuffled the queue with seed %d", seed))
+	logger.Debug("completed /Shuffle command", "interaction", interaction, "audioSession", audioSession, "enabled", true, "seed", seed)
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+func NowPlaying(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	conn, err := interactions.FindVoiceConn(session, interaction)
+	if err == interactions.ErrVoiceConnectionNotFound {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /NowPlaying command due to an error while finding the discord voice connection", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	// the voice connection may be playing a session that is owned by another guild
+	output, err := audiosession.FindDiscordVoiceConnOutput(conn)
+	if err != nil {
+		logger.Error("failed to execute /NowPlaying command due to error while finding the associated audio session output", "interaction", interaction, "conn", conn, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	input := output.Session().Queue().CurrentInput()
+	if input == nil {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	metadata := input.Metadata()
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "Playing **%s**", metadata.Title)
+
+	if metadata.Duration.IsSet() {
+		fmt.Fprintf(&message, " (%s / %s)", formatPlaybackTime(input.Position()), formatPlaybackTime(metadata.Duration.Get()))
+	} else {
+		fmt.Fprintf(&message, " (%s)", formatPlaybackTime(input.Position()))
+	}
+
+	if metadata.RequesterID != "" {
+		fmt.Fprintf(&message, ", requested by <@%s>", metadata.RequesterID)
+	}
+
+	if metadata.SourceURL != "" && metadata.SourceURL != metadata.Title {
+		fmt.Fprintf(&message, "\n%s", metadata.SourceURL)
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, message.String())
+	logger.Debug("completed /NowPlaying command", "interaction", interaction, "audioSession", output.Session(), "title", metadata.Title)
+}
+
+// formats d as m:ss, or h:mm:ss once it is an hour or longer
+func formatPlaybackTime(d time.Duration) string {
+	d = d.Truncate(time.Second)
+
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
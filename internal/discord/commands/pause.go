@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// pauses the track that is playing. sound effects that are mixed in keep playing
+func Pause(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Pause")
+	if !ok {
+		return
+	}
+
+	input := audioSession.Queue().CurrentInput()
+	if input == nil {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	input.Pause()
+
+	interactions.RespondWithMessage(logger, session, interaction, "Paused")
+	logger.Debug("completed /Pause command", "interaction", interaction, "audioSession", audioSession)
+}
+
+// resumes the track that is playing from where it was paused
+func Resume(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Resume")
+	if !ok {
+		return
+	}
+
+	input := audioSession.Queue().CurrentInput()
+	if input == nil {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	input.Resume()
+
+	interactions.RespondWithMessage(logger, session, interaction, "Resumed")
+	logger.Debug("completed /Resume command", "interaction", interaction, "audioSession", audioSession)
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 
 	"accidentallycoded.com/fredboard/v3/internal/audiosession"
@@ -12,6 +13,10 @@ import (
 
 type playCommandOptions struct {
 	url string
+
+	// the url is a live stream, such as an internet radio station, instead of something that ytdlp
+	// can download
+	stream bool
 }
 
 func getPlayCommandOptions(interaction *discordgo.Interaction) (*playCommandOptions, error) {
@@ -20,7 +25,12 @@ func getPlayCommandOptions(interaction *discordgo.Interaction) (*playCommandOpti
 		return nil, fmt.Errorf("failed to get required option \"url\": %w", err)
 	}
 
-	return &playCommandOptions{url}, nil
+	stream, err := interactions.GetOptionalBoolOpt(interaction, "stream")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option \"stream\": %w", err)
+	}
+
+	return &playCommandOptions{url: url, stream: stream.IsSet() && stream.Get()}, nil
 }
 
 func Play(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
@@ -36,47 +46,54 @@ func Play(logger *logging.Logger, session *discordgo.Session, interaction *disco
 	}
 
 	audioSession, output, exists, err := interactions.FindOrCreateAudioSession(logger, session, interaction)
+	if errors.Is(err, interactions.ErrAudioSessionNotOwned) {
+		logger.Debug("rejecting /Play command due to the voice channel playing another server's audio session", "interaction", interaction)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "Only the server that owns the audio can change what is playing.", err)
+		return
+	}
+
 	if err != nil {
 		logger.Error("failed to execute /Play command due to failure while finding or creating audio session", "interaction", interaction, "error", err)
 		interactions.RespondWithError(logger, session, interaction, err)
 		return
 	}
 
-	input, err := audioSession.AddYtdlpInput(opts.url, ytdlp.YtdlpAudioQuality_BestAudio)
+	// the queue removes inputs from the session once they stop
+	var track audiosession.Track
+	if opts.stream {
+		track = audiosession.NewHTTPStreamTrack(opts.url)
+	} else {
+		track = audiosession.NewYtdlpTrack(opts.url, ytdlp.YtdlpAudioQuality_BestAudio)
+	}
+
+	track = track.WithRequester(interaction.Member.User.ID)
+	position, err := audioSession.Queue().Enqueue(track)
 	if err != nil {
-		logger.Error("failed to execute /Play command due error while adding ytdlp input to the audio session", "interaction", interaction, "audioSession", audioSession, "error", err)
+		logger.Error("failed to execute /Play command due error while adding the track to the audio session", "interaction", interaction, "audioSession", audioSession, "error", err)
+
+		if !exists {
+			// removes the output, which disconnects the voice connection. the session is only
+			// destroyed if nothing else is playing it
+			audioSession.RemoveOutput(output)
+		}
+
 		interactions.RespondWithError(logger, session, interaction, err)
 		return
-
-		// TODO: destroy audio session if it was just created
 	}
 
-	input.OnStoppedEvent().AddDelegate(func(struct{}) {
-		audioSession.RemoveInput(input)
-	})
-
 	if !exists {
-		audioSession.OnInputRemoved.AddDelegate(func(param audiosession.SessionEvent_OnInputRemoved) {
-			if param.NInputsRemaining == 0 {
-				logger.Debug("removing discord voice conn due to all inputs to the audio session being removed", "audioSession", audioSession, "output", output)
-				audioSession.RemoveOutput(output)
-			}
-		})
+		audioSession.RemoveOutputWhenInputsRunOut(output)
 	}
 
-	audioSession.OnOutputRemoved.AddDelegate(func(param audiosession.SessionEvent_OnOutputRemoved) {
-		if param.OutputRemoved == output {
-			err := output.Conn.Disconnect()
-			if err != nil {
-				logger.Error("an error ocurred while disconnecting discord voice connection", "interaction", interaction, "error", err)
-			}
-		}
-	})
-
 	if audioSession.State() == audiosession.SessionState_NotTicking {
 		go audioSession.StartTicking()
 	}
 
-	interactions.RespondWithMessage(logger, session, interaction, "Playing...")
-	logger.Debug("completed /Yt command", "interaction", interaction, "audioSession", audioSession, "input", input, "output", output)
+	if position == 0 {
+		interactions.RespondWithMessage(logger, session, interaction, "Playing...")
+	} else {
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Queued at position %d", position))
+	}
+
+	logger.Debug("completed /Yt command", "interaction", interaction, "audioSession", audioSession, "position", position, "output", output)
 }
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// the most tracks that are listed, which keeps the reply under the length that discord allows
+const maxListedTracks = 20
+
+// lists the tracks that are waiting to be played. positions start at 1, the same as the position
+// that /play replies with
+func Queue(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Queue")
+	if !ok {
+		return
+	}
+
+	queue := audioSession.Queue()
+	tracks := queue.Tracks()
+
+	var message strings.Builder
+
+	if input := queue.CurrentInput(); input != nil {
+		fmt.Fprintf(&message, "Playing **%s**\n", input.Metadata().Title)
+	}
+
+	if len(tracks) == 0 {
+		message.WriteString("The queue is empty.")
+	}
+
+	for i, track := range tracks[:min(len(tracks), maxListedTracks)] {
+		fmt.Fprintf(&message, "%d. **%s**", i+1, track.Name)
+
+		if track.RequesterID != "" {
+			fmt.Fprintf(&message, ", requested by <@%s>", track.RequesterID)
+		}
+
+		message.WriteString("\n")
+	}
+
+	if len(tracks) > maxListedTracks {
+		fmt.Fprintf(&message, "and %d more", len(tracks)-maxListedTracks)
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, message.String())
+	logger.Debug("completed /Queue command", "interaction", interaction, "audioSession", audioSession, "nTracks", len(tracks))
+}
+
+// stops the track that is playing and plays the next one, even if the track is looping
+func Skip(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Skip")
+	if !ok {
+		return
+	}
+
+	if audioSession.Queue().CurrentInput() == nil {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	audioSession.Queue().Skip()
+
+	interactions.RespondWithMessage(logger, session, interaction, "Skipped")
+	logger.Debug("completed /Skip command", "interaction", interaction, "audioSession", audioSession)
+}
+
+// moves a track to another position in the queue
+func Move(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	from, err := interactions.GetRequiredIntOpt(interaction, "from")
+	if err != nil {
+		logger.Debug("rejecting /Move command due to missing position", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	to, err := interactions.GetRequiredIntOpt(interaction, "to")
+	if err != nil {
+		logger.Debug("rejecting /Move command due to missing position", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Move")
+	if !ok {
+		return
+	}
+
+	err = audioSession.Queue().Move(int(from)-1, int(to)-1)
+	if errors.Is(err, audiosession.ErrQueueIndexOutOfRange) {
+		interactions.RespondWithMessage(logger, session, interaction, queuePositionOutOfRangeMessage(audioSession.Queue().Len()))
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Move command due to error while moving the track", "interaction", interaction, "audioSession", audioSession, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Moved the track at position %d to position %d", from, to))
+	logger.Debug("completed /Move command", "interaction", interaction, "audioSession", audioSession, "from", from, "to", to)
+}
+
+// removes a track from the queue without playing it. the next track is removed unless a position
+// is given
+func Remove(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	position, err := interactions.GetOptionalIntOpt(interaction, "position")
+	if err != nil {
+		logger.Debug("rejecting /Remove command due to invalid position", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Remove")
+	if !ok {
+		return
+	}
+
+	queue := audioSession.Queue()
+
+	var track audiosession.Track
+
+	if position.IsSet() {
+		track, err = queue.Remove(int(position.Get()) - 1)
+	} else {
+		var removed bool
+		track, removed = queue.Dequeue()
+
+		if !removed {
+			err = audiosession.ErrQueueIndexOutOfRange
+		}
+	}
+
+	if errors.Is(err, audiosession.ErrQueueIndexOutOfRange) {
+		interactions.RespondWithMessage(logger, session, interaction, queuePositionOutOfRangeMessage(queue.Len()))
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Remove command due to error while removing the track", "interaction", interaction, "audioSession", audioSession, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Removed **%s**", track.Name))
+	logger.Debug("completed /Remove command", "interaction", interaction, "audioSession", audioSession, "track", track.Name)
+}
+
+// removes every track that is waiting to be played. the track that is playing carries on
+func Clear(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Clear")
+	if !ok {
+		return
+	}
+
+	audioSession.Queue().Clear()
+
+	interactions.RespondWithMessage(logger, session, interaction, "Cleared the queue")
+	logger.Debug("completed /Clear command", "interaction", interaction, "audioSession", audioSession)
+}
+
+func queuePositionOutOfRangeMessage(nTracks int) string {
+	if nTracks == 0 {
+		return "The queue is empty."
+	}
+
+	return fmt.Sprintf("There are only %d tracks in the queue.", nTracks)
+}
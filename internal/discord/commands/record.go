@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// starts or stops recording the audio session that is playing in the voice channel. the recording
+// only follows the session, so it ends once nothing on discord plays the session anymore
+func Record(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	action, err := interactions.GetRequiredStringOpt(interaction, "action")
+	if err != nil {
+		logger.Debug("rejecting /Record command due to missing action", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	opts, ok := audiosession.ConfiguredRecordingOptions()
+	if !ok {
+		interactions.RespondWithMessage(logger, session, interaction, "Recording is not enabled on this FredBoard.")
+		return
+	}
+
+	conn, err := interactions.FindVoiceConn(session, interaction)
+	if err == interactions.ErrVoiceConnectionNotFound {
+		interactions.RespondWithMessage(logger, session, interaction, "FredBoard is not in any voice channel on this server.")
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Record command due to an error while finding the discord voice connection", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	// the voice connection may be playing a session that is owned by another guild
+	output, err := audiosession.FindDiscordVoiceConnOutput(conn)
+	if err != nil {
+		logger.Error("failed to execute /Record command due to error while finding the associated audio session output", "interaction", interaction, "conn", conn, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	audioSession := output.Session()
+
+	switch action {
+	case "start":
+		recording, err := audioSession.GetOrAddRecordingOutput(opts)
+		if err == nil {
+			err = recording.Start()
+		}
+
+		if err != nil {
+			logger.Warn("failed to execute /Record command due to error while starting the recording", "interaction", interaction, "audioSession", audioSession, "error", err)
+			interactions.RespondWithError(logger, session, interaction, err)
+			return
+		}
+
+		interactions.RespondWithMessage(logger, session, interaction, "Recording")
+	case "stop":
+		recording, ok := audioSession.RecordingOutput()
+		if !ok {
+			interactions.RespondWithMessage(logger, session, interaction, "Nothing is being recorded.")
+			return
+		}
+
+		// removing the output writes what is still queued and finishes the file
+		files := recording.Files()
+		audioSession.RemoveOutput(recording)
+
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Stopped recording after %d file(s)", len(files)))
+	default:
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Unknown action %q, use start or stop.", action))
+		return
+	}
+
+	logger.Debug("completed /Record command", "interaction", interaction, "audioSession", audioSession, "action", action)
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+var ErrInvalidPlaybackTime = errors.New("invalid playback time, expected seconds, m:ss or h:mm:ss")
+
+// moves the track that is playing to a position, such as 2:30
+func Seek(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	opt, err := interactions.GetRequiredStringOpt(interaction, "position")
+	if err != nil {
+		logger.Debug("rejecting /Seek command due to missing position", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	position, err := parsePlaybackTime(opt)
+	if err != nil {
+		logger.Debug("rejecting /Seek command due to invalid position", "interaction", interaction, "position", opt, "error", err)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "Positions look like 150, 2:30 or 1:02:30.", err)
+		return
+	}
+
+	audioSession, ok := findOwnAudioSession(logger, session, interaction, "/Seek")
+	if !ok {
+		return
+	}
+
+	input := audioSession.Queue().CurrentInput()
+	if input == nil {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing.")
+		return
+	}
+
+	err = input.Seek(position)
+
+	switch {
+	case errors.Is(err, audiosession.ErrSeekNotSupported):
+		interactions.RespondWithMessage(logger, session, interaction, "The track that is playing cannot be seeked.")
+		return
+	case errors.Is(err, audiosession.ErrSeekOutOfRange):
+		message := "The track is not that long."
+		if duration := input.Duration(); duration.IsSet() {
+			message = fmt.Sprintf("The track is only %s long.", formatPlaybackTime(duration.Get()))
+		}
+
+		interactions.RespondWithMessage(logger, session, interaction, message)
+		return
+	case err != nil:
+		logger.Warn("failed to execute /Seek command due to error while seeking the track", "interaction", interaction, "audioSession", audioSession, "position", position, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Playing from %s", formatPlaybackTime(position)))
+	logger.Debug("completed /Seek command", "interaction", interaction, "audioSession", audioSession, "position", position)
+}
+
+// parses seconds, m:ss or h:mm:ss, the same way that [formatPlaybackTime] formats them
+//
+// returns [ErrInvalidPlaybackTime] if s is in none of them
+func parsePlaybackTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, ErrInvalidPlaybackTime
+	}
+
+	var seconds int
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, ErrInvalidPlaybackTime
+		}
+
+		seconds = seconds*60 + n
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
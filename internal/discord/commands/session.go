@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"errors"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// finds the audio session of the server that the interaction was created in, which is the only
+// session that the server controls. responds to the interaction if there is none
+func findOwnAudioSession(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction, command string) (*audiosession.Session, bool) {
+	audioSession, err := audiosession.Get(interaction.GuildID)
+	if errors.Is(err, audiosession.ErrSessionNotFound) {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing on this server.")
+		return nil, false
+	}
+
+	if err != nil {
+		logger.Error("failed to execute "+command+" command due to error while finding the audio session", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return nil, false
+	}
+
+	return audioSession, true
+}
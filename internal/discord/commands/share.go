@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+// replies with the token that lets other servers play the audio of this server. only the creator of
+// the interaction can see the reply
+func Share(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.AcknowledgeEphemeral(logger, session, interaction) != nil {
+		return
+	}
+
+	audioSession, err := audiosession.Get(interaction.GuildID)
+	if errors.Is(err, audiosession.ErrSessionNotFound) {
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing on this server.")
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Share command due to error while finding the audio session", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	token, err := audioSession.Share()
+	if err != nil {
+		logger.Error("failed to execute /Share command due to error while sharing the audio session", "interaction", interaction, "audioSession", audioSession, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	message := fmt.Sprintf("Other servers can play this server's audio with `/join session:%s token:%s`", audioSession.ID(), token)
+	if config.Get().Web.StreamEnabled {
+		message += fmt.Sprintf("\nIt can also be streamed from `/sessions/%s/stream?token=%s` on the web server", audioSession.ID(), token)
+	}
+
+	interactions.RespondWithMessage(logger, session, interaction, message)
+	logger.Debug("completed /Share command", "interaction", interaction, "audioSession", audioSession)
+}
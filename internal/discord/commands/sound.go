@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/discord/interactions"
+	"accidentallycoded.com/fredboard/v3/internal/soundboard"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+var ErrSoundStartConflict = errors.New("a sound can either be delayed or start with the next track")
+
+type soundCommandOptions struct {
+	name string
+
+	// how long to wait before playing the sound
+	delay time.Duration
+
+	// play the sound when the next track in the queue starts, such as to announce it
+	withNextTrack bool
+}
+
+func getSoundCommandOptions(interaction *discordgo.Interaction) (*soundCommandOptions, error) {
+	name, err := interactions.GetRequiredStringOpt(interaction, "name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get required option \"name\": %w", err)
+	}
+
+	delay, err := interactions.GetOptionalIntOpt(interaction, "delay")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option \"delay\": %w", err)
+	}
+
+	next, err := interactions.GetOptionalBoolOpt(interaction, "next")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get option \"next\": %w", err)
+	}
+
+	opts := &soundCommandOptions{name: name, withNextTrack: next.IsSet() && next.Get()}
+	if delay.IsSet() {
+		opts.delay = time.Duration(delay.Get()) * time.Second
+	}
+
+	if opts.delay > 0 && opts.withNextTrack {
+		return nil, ErrSoundStartConflict
+	}
+
+	return opts, nil
+}
+
+func Sound(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) {
+	if interactions.Acknowledge(logger, session, interaction) != nil {
+		return
+	}
+
+	opts, err := getSoundCommandOptions(interaction)
+	if errors.Is(err, ErrSoundStartConflict) {
+		logger.Debug("rejecting /Sound command due to conflicting options", "interaction", interaction)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "Choose either a delay or the next track.", err)
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Sound command due to failure while getting command options", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	sb, err := soundboard.Get()
+	if err != nil {
+		logger.Debug("rejecting /Sound command due to the soundboard being disabled", "interaction", interaction)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "The soundboard is not enabled.", err)
+		return
+	}
+
+	// check the clip before joining so that an invalid name does not join the voice channel
+	_, err = sb.Clip(opts.name)
+	if err != nil {
+		interactions.RespondWithErrorMessage(logger, session, interaction, fmt.Sprintf("There is no sound named %q.", opts.name), err)
+		return
+	}
+
+	audioSession, output, exists, err := interactions.FindOrCreateAudioSession(logger, session, interaction)
+	if errors.Is(err, interactions.ErrAudioSessionNotOwned) {
+		logger.Debug("rejecting /Sound command due to the voice channel playing another server's audio session", "interaction", interaction)
+		interactions.RespondWithErrorMessage(logger, session, interaction, "Only the server that owns the audio can change what is playing.", err)
+		return
+	}
+
+	if err != nil {
+		logger.Error("failed to execute /Sound command due to failure while finding or creating audio session", "interaction", interaction, "error", err)
+		interactions.RespondWithError(logger, session, interaction, err)
+		return
+	}
+
+	// there is no next track to start with unless something is already playing
+	if opts.withNextTrack && audioSession.Queue().CurrentInput() == nil {
+		if !exists {
+			audioSession.RemoveOutput(output)
+		}
+
+		interactions.RespondWithMessage(logger, session, interaction, "Nothing is playing, so there is no next track to play the sound with.")
+		return
+	}
+
+	switch {
+	case opts.withNextTrack:
+		_, err = sb.TriggerWithNextTrack(audioSession, opts.name)
+	case opts.delay > 0:
+		_, err = sb.TriggerAt(audioSession, opts.name, time.Now().Add(opts.delay))
+	default:
+		_, err = sb.Trigger(audioSession, opts.name)
+	}
+
+	if err != nil {
+		logger.Debug("failed to execute /Sound command due to error while triggering soundboard clip", "interaction", interaction, "audioSession", audioSession, "error", err)
+
+		if !exists {
+			// removes the output, which disconnects the voice connection. the session is only
+			// destroyed if nothing else is playing it
+			audioSession.RemoveOutput(output)
+		}
+
+		if errors.Is(err, soundboard.ErrClipCoolingDown) {
+			interactions.RespondWithErrorMessage(logger, session, interaction, fmt.Sprintf("%q was played too recently.", opts.name), err)
+		} else {
+			interactions.RespondWithError(logger, session, interaction, err)
+		}
+
+		return
+	}
+
+	if !exists {
+		audioSession.RemoveOutputWhenInputsRunOut(output)
+	}
+
+	if audioSession.State() == audiosession.SessionState_NotTicking {
+		go audioSession.StartTicking()
+	}
+
+	switch {
+	case opts.withNextTrack:
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Playing %s when the next track starts", opts.name))
+	case opts.delay > 0:
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Playing %s in %s", opts.name, opts.delay))
+	default:
+		interactions.RespondWithMessage(logger, session, interaction, fmt.Sprintf("Playing %s", opts.name))
+	}
+
+	logger.Debug("completed /Sound command", "interaction", interaction, "audioSession", audioSession, "output", output)
+}
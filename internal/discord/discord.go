@@ -3,12 +3,21 @@ package discord
 import (
 	"context"
 
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
 	"accidentallycoded.com/fredboard/v3/internal/discord/commands"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 	"github.com/bwmarrin/discordgo"
 )
 
+// positions in the queue start at 1, the same as the position that /play replies with
+var minQueuePosition = 1.0
+
+var minShuffleSeed = 0.0
+
+// how long a soundboard clip can be scheduled ahead. the session plays silence until it starts
+var minSoundDelaySeconds, maxSoundDelaySeconds = 0.0, 600.0
+
 type Bot struct {
 	logger *logging.Logger
 	appId  string
@@ -27,6 +36,28 @@ func (bot *Bot) onReady(session *discordgo.Session, event *discordgo.Ready) {
 	logger.Info("session opened", "session", session, "event", event)
 }
 
+// restores the audio sessions that were saved when the bot last shutdown and rejoins their voice
+// channels
+func (bot *Bot) restoreSessions(session *discordgo.Session) {
+	snapshots, err := audiosession.LoadSnapshots()
+	if err != nil {
+		bot.logger.Warn("failed to load some audio session snapshots", "error", err)
+	}
+
+	join := func(guildID, channelID string) (*discordgo.VoiceConnection, error) {
+		const mute = false
+		const deaf = true
+		return session.ChannelVoiceJoin(guildID, channelID, mute, deaf)
+	}
+
+	for _, snapshot := range snapshots {
+		_, err := audiosession.Restore(bot.logger, snapshot, join)
+		if err != nil {
+			bot.logger.Warn("failed to restore audio session", "id", snapshot.ID, "error", err)
+		}
+	}
+}
+
 func (bot *Bot) onInteractionCreate(session *discordgo.Session, event *discordgo.InteractionCreate) {
 	logger := bot.logger.NewChildLogger()
 
@@ -53,6 +84,34 @@ func onApplicationCommandInteraction(session *discordgo.Session, interaction *di
 		go commands.Join(logger, session, interaction)
 	case "leave":
 		go commands.Leave(logger, session, interaction)
+	case "sound":
+		go commands.Sound(logger, session, interaction)
+	case "nowplaying":
+		go commands.NowPlaying(logger, session, interaction)
+	case "share":
+		go commands.Share(logger, session, interaction)
+	case "record":
+		go commands.Record(logger, session, interaction)
+	case "queue":
+		go commands.Queue(logger, session, interaction)
+	case "skip":
+		go commands.Skip(logger, session, interaction)
+	case "move":
+		go commands.Move(logger, session, interaction)
+	case "remove":
+		go commands.Remove(logger, session, interaction)
+	case "clear":
+		go commands.Clear(logger, session, interaction)
+	case "pause":
+		go commands.Pause(logger, session, interaction)
+	case "resume":
+		go commands.Resume(logger, session, interaction)
+	case "seek":
+		go commands.Seek(logger, session, interaction)
+	case "loop":
+		go commands.Loop(logger, session, interaction)
+	case "shuffle":
+		go commands.Shuffle(logger, session, interaction)
 	default:
 		logger.Warn("ignoring invalid command", "session", session, "interaction", interaction, "data", data)
 	}
@@ -87,18 +146,203 @@ func (bot *Bot) Run(ctx context.Context) {
 					Description: "Url to the audio to play",
 					Required:    true,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "stream",
+					Description: "Whether the url is a live stream, such as an internet radio station",
+					Required:    false,
+				},
 			},
 		},
 		{
 			Type:        discordgo.ChatApplicationCommand,
 			Name:        "join",
 			Description: "Join the voice channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "session",
+					Description: "Id of the server whose audio to play, which can be another server",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "token",
+					Description: "Token that the other server shared its audio with",
+					Required:    false,
+				},
+			},
 		},
 		{
 			Type:        discordgo.ChatApplicationCommand,
 			Name:        "leave",
 			Description: "Leave the voice channel",
 		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "sound",
+			Description: "Play a soundboard clip",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Name of the clip to play",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "delay",
+					Description: "Seconds to wait before playing the clip",
+					Required:    false,
+					MinValue:    &minSoundDelaySeconds,
+					MaxValue:    maxSoundDelaySeconds,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "next",
+					Description: "Play the clip when the next track in the queue starts",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "nowplaying",
+			Description: "Show the track that is playing",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "share",
+			Description: "Let other servers play the audio of this server",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "record",
+			Description: "Record the audio that is playing to files on the server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "Whether to start or stop recording",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "start", Value: "start"},
+						{Name: "stop", Value: "stop"},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "queue",
+			Description: "List the tracks that are waiting to be played",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "skip",
+			Description: "Skip the track that is playing",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "move",
+			Description: "Move a track to another position in the queue",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "from",
+					Description: "Position of the track to move",
+					Required:    true,
+					MinValue:    &minQueuePosition,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "to",
+					Description: "Position to move the track to",
+					Required:    true,
+					MinValue:    &minQueuePosition,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "remove",
+			Description: "Remove a track from the queue",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "position",
+					Description: "Position of the track to remove, or the next track if not given",
+					Required:    false,
+					MinValue:    &minQueuePosition,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "clear",
+			Description: "Remove every track from the queue",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "pause",
+			Description: "Pause the track that is playing",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "resume",
+			Description: "Resume the track that is paused",
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "seek",
+			Description: "Play the track that is playing from another position",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "position",
+					Description: "Position to play from, such as 150, 2:30 or 1:02:30",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "loop",
+			Description: "Repeat the track that is playing or the whole queue",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "mode",
+					Description: "What to repeat",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "off", Value: "off"},
+						{Name: "one", Value: "one"},
+						{Name: "all", Value: "all"},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "shuffle",
+			Description: "Play the queue in a random order",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether to shuffle, which is true if not given",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "seed",
+					Description: "Seed to repeat an earlier order with",
+					Required:    false,
+					MinValue:    &minShuffleSeed,
+				},
+			},
+		},
 	})
 
 	if err != nil {
@@ -116,6 +360,8 @@ func (bot *Bot) Run(ctx context.Context) {
 
 	defer bot.logger.Info("discord bot shutdown", "session", session)
 
+	go bot.restoreSessions(session)
+
 	defer func() {
 		err := session.Close()
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
 	"github.com/bwmarrin/discordgo"
 )
@@ -16,6 +17,8 @@ var ErrVoiceChannelNotFound = errors.New("discord voice channel not found")
 
 var ErrVoiceConnectionNotFound = errors.New("discord voice connection does not exist")
 
+var ErrAudioSessionNotOwned = errors.New("the voice channel is playing an audio session that is owned by another server")
+
 // get an option from an interaction
 //
 // returns [ErrOptNotFound] when not found
@@ -40,6 +43,70 @@ func GetRequiredStringOpt(interaction *discordgo.Interaction, name string) (stri
 	return opt.StringValue(), nil
 }
 
+// get an option from an interaction that may not have been given
+func GetOptionalStringOpt(interaction *discordgo.Interaction, name string) (optional.Optional[string], error) {
+	value, err := GetRequiredStringOpt(interaction, name)
+	if err == ErrOptNotFound {
+		return optional.None[string](), nil
+	}
+
+	if err != nil {
+		return optional.None[string](), err
+	}
+
+	return optional.Make(value), nil
+}
+
+// get a boolean option from an interaction that may not have been given
+func GetOptionalBoolOpt(interaction *discordgo.Interaction, name string) (optional.Optional[bool], error) {
+	for _, opt := range interaction.ApplicationCommandData().Options {
+		if opt.Name != name {
+			continue
+		}
+
+		if opt.Type != discordgo.ApplicationCommandOptionBoolean {
+			return optional.None[bool](), ErrInvalidOptType
+		}
+
+		return optional.Make(opt.BoolValue()), nil
+	}
+
+	return optional.None[bool](), nil
+}
+
+// get an integer option from an interaction
+//
+// returns [ErrOptNotFound] when not found
+func GetRequiredIntOpt(interaction *discordgo.Interaction, name string) (int64, error) {
+	value, err := GetOptionalIntOpt(interaction, name)
+	if err != nil {
+		return 0, err
+	}
+
+	if !value.IsSet() {
+		return 0, ErrOptNotFound
+	}
+
+	return value.Get(), nil
+}
+
+// get an integer option from an interaction that may not have been given
+func GetOptionalIntOpt(interaction *discordgo.Interaction, name string) (optional.Optional[int64], error) {
+	for _, opt := range interaction.ApplicationCommandData().Options {
+		if opt.Name != name {
+			continue
+		}
+
+		if opt.Type != discordgo.ApplicationCommandOptionInteger {
+			return optional.None[int64](), ErrInvalidOptType
+		}
+
+		return optional.Make(opt.IntValue()), nil
+	}
+
+	return optional.None[int64](), nil
+}
+
 // search all voice channels of the guild that the interaction was created in for the interaction creator
 func FindCreatorVoiceChannelId(session *discordgo.Session, interaction *discordgo.Interaction) (string, error) {
 	if interaction.Type != discordgo.InteractionApplicationCommand && interaction.Type != discordgo.InteractionApplicationCommandAutocomplete {
@@ -98,20 +165,26 @@ func FindOrCreateAudioSession(logger *logging.Logger, session *discordgo.Session
 		return nil, nil, false, err
 	}
 
+	// the voice connection may be playing a session that is owned by another guild, which only its
+	// owner controls
 	if exists {
 		output, err = audiosession.FindDiscordVoiceConnOutput(conn)
 		if err != nil {
 			return nil, nil, true, err
 		}
 
+		if output.Session().ID() != interaction.GuildID {
+			return nil, nil, true, ErrAudioSessionNotOwned
+		}
+
 		return output.Session(), output, true, nil
 	}
 
-	audioSession = audiosession.New(logger)
-	output, err = audioSession.AddDiscordVoiceConnOutput(conn)
+	// the session of the guild may still exist without the voice connection, such as while another
+	// guild listens to it, in which case the voice connection joins it again
+	audioSession, output, _, err = audiosession.JoinOwnSession(logger, interaction.GuildID, conn)
 	if err != nil {
-		return nil, nil, false, err
-		// TODO: destroy audio session
+		return nil, nil, false, errors.Join(err, conn.Disconnect())
 	}
 
 	return audioSession, output, false, nil
@@ -129,6 +202,23 @@ func Acknowledge(logger *logging.Logger, session *discordgo.Session, interaction
 	return err
 }
 
+// inform discord that the interaction has been acknowledged and will be responded to later with a
+// message that only the creator of the interaction can see
+func AcknowledgeEphemeral(logger *logging.Logger, session *discordgo.Session, interaction *discordgo.Interaction) error {
+	logger.Debug("responding to discord interaction with ephemeral acknowledgment", "session", session, "interaction", interaction)
+
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	if err != nil {
+		logger.Error("failed to respond to discord interaction with ephemeral acknowledgment", "session", session, "interaction", interaction, "error", err)
+	}
+
+	return err
+}
+
 // inform discord that the interaction has been acknowledged and will be responded to later
 func Acknowledge_NoLog(session *discordgo.Session, interaction *discordgo.Interaction) error {
 	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
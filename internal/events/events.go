@@ -1,6 +1,9 @@
 package events
 
-import "math"
+import (
+	"math"
+	"sync"
+)
 
 type DelegateHandle int
 
@@ -10,12 +13,18 @@ const (
 
 type Delegate[TDelegateParam any] func(param TDelegateParam)
 
+// safe for concurrent use. delegates are called without the emitter being locked, so they can add
+// and remove delegates themselves
 type EventEmitter[TDelegateParam any] struct {
+	mu           sync.Mutex
 	delegates    map[DelegateHandle](Delegate[TDelegateParam])
 	nextHandleId int
 }
 
 func (emitter *EventEmitter[TDelegateParam]) AddDelegate(delegate Delegate[TDelegateParam]) DelegateHandle {
+	emitter.mu.Lock()
+	defer emitter.mu.Unlock()
+
 	handle := emitter.nextHandle()
 	emitter.delegates[handle] = delegate
 
@@ -23,6 +32,9 @@ func (emitter *EventEmitter[TDelegateParam]) AddDelegate(delegate Delegate[TDele
 }
 
 func (emitter *EventEmitter[TDelegateParam]) RemoveDelegate(handle DelegateHandle) {
+	emitter.mu.Lock()
+	defer emitter.mu.Unlock()
+
 	delete(emitter.delegates, handle)
 }
 
@@ -33,11 +45,21 @@ func (emitter *EventEmitter[TDelegateParam]) AddChan(channel chan<- TDelegatePar
 }
 
 func (emitter *EventEmitter[TDelegateParam]) Broadcast(param TDelegateParam) {
+	emitter.mu.Lock()
+
+	delegates := make([]Delegate[TDelegateParam], 0, len(emitter.delegates))
 	for _, d := range emitter.delegates {
+		delegates = append(delegates, d)
+	}
+
+	emitter.mu.Unlock()
+
+	for _, d := range delegates {
 		d(param)
 	}
 }
 
+// must be called with the emitter locked
 func (emitter *EventEmitter[TDelegateParam]) nextHandle() DelegateHandle {
 	handle := DelegateHandle(emitter.nextHandleId)
 
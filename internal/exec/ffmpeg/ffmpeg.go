@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"time"
 
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
 	"accidentallycoded.com/fredboard/v3/internal/optional"
 	"accidentallycoded.com/fredboard/v3/internal/syncext"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
@@ -43,18 +45,26 @@ type transcoder struct {
 }
 
 func (t *transcoder) Read(p []byte) (n int, err error) {
+	// the lock is not held while reading, since stdin is only closed once its error has been stored
 	t.err.Lock()
-	defer t.err.Unlock()
+	err = t.err.Data
+	t.err.Unlock()
 
-	if t.err.Data != nil {
-		return 0, t.err.Data
+	if err != nil {
+		return 0, err
+	}
+
+	n, err = t.stdout.Read(p)
+	if err == io.EOF {
+		t.stdout.Close()
 	}
 
-	return t.stdout.Read(p)
+	return n, err
 }
 
 func (t *transcoder) Close() (err error) {
 	t.cancel()
+	t.stdout.Close()
 	return nil
 }
 
@@ -64,6 +74,19 @@ func NewTranscoder(
 	r io.Reader,
 	format string,
 	sampleRateHz, nAudioChannels int,
+) (*transcoder, error, <-chan *exec.ExitError) {
+	return NewTranscoderWithStartOffset(logger, config, r, format, sampleRateHz, nAudioChannels, 0)
+}
+
+// creates a transcoder that discards everything before startOffset. the input is still read
+// from the beginning since stdin cannot be seeked
+func NewTranscoderWithStartOffset(
+	logger *logging.Logger,
+	config Config,
+	r io.Reader,
+	format string,
+	sampleRateHz, nAudioChannels int,
+	startOffset time.Duration,
 ) (*transcoder, error, <-chan *exec.ExitError) {
 	ctx, cancel := context.WithCancel(context.Background())
 	t := &transcoder{cancel: cancel}
@@ -71,6 +94,7 @@ func NewTranscoder(
 	args := []string{
 		"-hide_banner", // supress the copyright and build information
 		"-i", "pipe:0", // read from stdin
+		"-ss", fmt.Sprintf("%f", startOffset.Seconds()), // seek the output so that the position is sample accurate
 		"-f", format,
 		"-ar", fmt.Sprintf("%d", sampleRateHz), // set the sample rate
 		"-ac", fmt.Sprintf("%d", nAudioChannels), // set the number of audio channels
@@ -103,12 +127,6 @@ func NewTranscoder(
 		stdin.Close()
 	}()
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to created stdout pipe: %w", err), nil
-	}
-	t.stdout = stdout
-
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stderr pipe: %w", err), nil
@@ -132,7 +150,7 @@ func NewTranscoder(
 		}
 	}()
 
-	err = cmd.Start()
+	t.stdout, err = ioext.StartWithStdoutPipe(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start ffmpeg cmd: %w", err), nil
 	}
@@ -151,6 +169,11 @@ func NewTranscoder(
 				exit <- &exec.ExitError{ProcessState: err.ProcessState, Stderr: stderrBytes.Data}
 				stderrBytes.Unlock()
 			default:
+				// ffmpeg exited on its own, such as at the end of its input, after the transcoder was closed
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+
 				panic(err)
 			}
 		}
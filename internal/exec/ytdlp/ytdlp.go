@@ -2,11 +2,13 @@ package ytdlp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os/exec"
 
+	"accidentallycoded.com/fredboard/v3/internal/ioext"
 	"accidentallycoded.com/fredboard/v3/internal/optional"
 	"accidentallycoded.com/fredboard/v3/internal/syncext"
 	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
@@ -33,7 +35,10 @@ type Metadata struct {
 	Type        string `json:"_type"`
 	Title       string `json:"title"`
 	Description string `json:"Description"`
-	Thumbnails  []struct {
+
+	// in seconds. 0 if unknown, such as for live streams
+	Duration   float64 `json:"duration"`
+	Thumbnails []struct {
 		Url    string `json:"url"`
 		Height int    `json:"height"`
 		Width  int    `json:"width"`
@@ -82,6 +87,27 @@ func NewMetadataCmd(ctx context.Context, config Config, url string) (cmd *exec.C
 	return exec.CommandContext(ctx, exe, args...), nil
 }
 
+// runs ytdlp to get the metadata of a video or playlist without downloading it
+func GetMetadata(ctx context.Context, config Config, url string) (*Metadata, error) {
+	cmd, err := NewMetadataCmd(ctx, config, url)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ytdlp metadata cmd: %w", err)
+	}
+
+	var metadata Metadata
+	err = json.Unmarshal(out, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ytdlp metadata: %w", err)
+	}
+
+	return &metadata, nil
+}
+
 func NewVideoCmd(ctx context.Context, config Config, url string, quality YtdlpAudioQuality) (cmd *exec.Cmd, err error) {
 	args := []string{
 		url,
@@ -120,11 +146,17 @@ func (r *videoReader) Read(p []byte) (int, error) {
 		return 0, r.err.Data
 	}
 
-	return r.stdout.Read(p)
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		r.stdout.Close()
+	}
+
+	return n, err
 }
 
 func (r *videoReader) Close() error {
 	r.cancel()
+	r.stdout.Close()
 	return nil
 }
 
@@ -137,12 +169,6 @@ func NewVideoReader(logger *logging.Logger, config Config, url string, quality Y
 		return nil, fmt.Errorf("failed to create VideoCmd: %w", err), nil
 	}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to created stdout pipe: %w", err), nil
-	}
-	r.stdout = stdout
-
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stderr pipe: %w", err), nil
@@ -165,7 +191,7 @@ func NewVideoReader(logger *logging.Logger, config Config, url string, quality Y
 		}
 	}()
 
-	err = cmd.Start()
+	r.stdout, err = ioext.StartWithStdoutPipe(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start ytdlp cmd: %w", err), nil
 	}
@@ -0,0 +1,32 @@
+package ioext
+
+import (
+	"os"
+	"os/exec"
+)
+
+// starts cmd with its stdout connected to the returned reader
+//
+// an os.Pipe is used instead of cmd.StdoutPipe because cmd.Wait closes the read end of
+// cmd.StdoutPipe, which would discard any data that has not been read yet instead of producing an
+// io.EOF once all of it is consumed
+func StartWithStdoutPipe(cmd *exec.Cmd) (*os.File, error) {
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdout = stdoutW
+
+	err = cmd.Start()
+
+	// the child has its own copy of the write end, so the reader sees io.EOF once the child exits
+	stdoutW.Close()
+
+	if err != nil {
+		stdout.Close()
+		return nil, err
+	}
+
+	return stdout, nil
+}
@@ -0,0 +1,27 @@
+package ioext
+
+import (
+	"io"
+	"sync"
+)
+
+// calls a function the first time that the underlying reader returns an error, including [io.EOF]
+type ErrNotifyReader struct {
+	r      io.Reader
+	once   sync.Once
+	notify func(err error)
+}
+
+func (r *ErrNotifyReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+
+	if err != nil {
+		r.once.Do(func() { r.notify(err) })
+	}
+
+	return n, err
+}
+
+func NewErrNotifyReader(r io.Reader, notify func(err error)) *ErrNotifyReader {
+	return &ErrNotifyReader{r: r, notify: notify}
+}
@@ -60,6 +60,7 @@ func (o Optional[T]) MarshalJSON() (data []byte, err error) {
 func (o *Optional[T]) UnmarshalJSON(data []byte) error {
 	if len(data) == 4 && string(data) == "null" {
 		o.Unset()
+		return nil
 	}
 
 	var v T
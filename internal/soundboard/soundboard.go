@@ -0,0 +1,285 @@
+package soundboard
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/audiosession"
+	"accidentallycoded.com/fredboard/v3/internal/config"
+	"accidentallycoded.com/fredboard/v3/internal/optional"
+	"accidentallycoded.com/fredboard/v3/internal/syncext"
+	"accidentallycoded.com/fredboard/v3/internal/telemetry/logging"
+)
+
+var (
+	ErrSoundboardDisabled = errors.New("soundboard is not enabled")
+	ErrClipNotFound       = errors.New("soundboard clip not found")
+	ErrClipCoolingDown    = errors.New("soundboard clip is cooling down")
+)
+
+var loaded optional.Optional[*Soundboard]
+
+// a short sound that has been decoded into memory
+type Clip struct {
+	name     string
+	volume   float64
+	cooldown time.Duration
+	pcm      []byte
+}
+
+func (c *Clip) Name() string {
+	return c.name
+}
+
+func (c *Clip) Volume() float64 {
+	return c.volume
+}
+
+// how long a session has to wait before it can play the clip again
+func (c *Clip) Cooldown() time.Duration {
+	return c.cooldown
+}
+
+type cooldownKey struct {
+	clip      string
+	sessionId string
+}
+
+type Soundboard struct {
+	logger *logging.Logger
+	clips  map[string]*Clip
+
+	// when each clip can next be played in each session
+	cooldowns syncext.SyncData[map[cooldownKey]time.Time]
+}
+
+// returns the names of all of the clips in alphabetical order
+func (sb *Soundboard) Names() []string {
+	names := make([]string, 0, len(sb.clips))
+	for name := range sb.clips {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func (sb *Soundboard) Clip(name string) (*Clip, error) {
+	clip, ok := sb.clips[name]
+	if !ok {
+		return nil, ErrClipNotFound
+	}
+
+	return clip, nil
+}
+
+// mixes a clip into a session on top of anything else that is playing. the input is removed from
+// the session once the clip ends
+//
+// returns [ErrClipCoolingDown] if the clip was played in the session too recently
+func (sb *Soundboard) Trigger(session *audiosession.Session, name string) (*audiosession.MemoryInput, error) {
+	input, err := sb.newClipInput(session, name)
+	if err != nil {
+		return nil, err
+	}
+
+	session.AddInput(input)
+
+	sb.logger.Debug("playing soundboard clip", "clip", name, "session", session.ID())
+
+	return input, nil
+}
+
+// mixes a clip into a session at t, as measured by the clock of the session. the cooldown starts
+// when the clip is scheduled. see [Soundboard.Trigger]
+func (sb *Soundboard) TriggerAt(session *audiosession.Session, name string, t time.Time) (*audiosession.ScheduledInput, error) {
+	input, err := sb.newClipInput(session, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sb.logger.Debug("scheduling soundboard clip", "clip", name, "session", session.ID(), "at", t)
+
+	return session.ScheduleInputAt(input, t), nil
+}
+
+// mixes a clip into a session on the same sample that the next track of its queue starts on, such
+// as to announce the track. the cooldown starts when the clip is scheduled. see [Soundboard.Trigger]
+func (sb *Soundboard) TriggerWithNextTrack(session *audiosession.Session, name string) (*audiosession.ScheduledInput, error) {
+	input, err := sb.newClipInput(session, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sb.logger.Debug("scheduling soundboard clip with the next track", "clip", name, "session", session.ID())
+
+	return session.Queue().ScheduleWithNextTrack(input), nil
+}
+
+// creates an input that plays a clip and removes itself from the session once it ends. the input
+// is not added to the session
+func (sb *Soundboard) newClipInput(session *audiosession.Session, name string) (*audiosession.MemoryInput, error) {
+	clip, err := sb.Clip(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sb.startCooldown(clip, session.ID(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// the delegate is added before the input is added to the session, since a clip that is
+	// shorter than a tick stops during its first tick
+	input := session.NewMemoryInput(clip.name, clip.pcm)
+	input.OnStoppedEvent().AddDelegate(func(struct{}) { session.RemoveInput(input) })
+
+	return input, nil
+}
+
+func (sb *Soundboard) startCooldown(clip *Clip, sessionId string, now time.Time) error {
+	sb.cooldowns.Lock()
+	defer sb.cooldowns.Unlock()
+
+	key := cooldownKey{clip: clip.name, sessionId: sessionId}
+
+	if until, ok := sb.cooldowns.Data[key]; ok && now.Before(until) {
+		return fmt.Errorf("%w: %s remaining", ErrClipCoolingDown, until.Sub(now).Round(100*time.Millisecond))
+	}
+
+	// forget cooldowns that are over so that sessions that no longer exist do not accumulate
+	for k, until := range sb.cooldowns.Data {
+		if !now.Before(until) {
+			delete(sb.cooldowns.Data, k)
+		}
+	}
+
+	if clip.cooldown > 0 {
+		sb.cooldowns.Data[key] = now.Add(clip.cooldown)
+	}
+
+	return nil
+}
+
+// scales 16-bit signed little endian pcm in place, clamping samples that overflow
+func applyVolume(pcm []byte, volume float64) {
+	if volume == 1 {
+		return
+	}
+
+	stream := codecs.BytesToS16LE(pcm)
+
+	for i, sample := range stream {
+		f64 := float64(sample) * volume
+		switch {
+		case f64 < math.MinInt16: // underflow so set the sample to the min value
+			stream[i] = math.MinInt16
+		case f64 > math.MaxInt16: // overflow so set the sample to the max value
+			stream[i] = math.MaxInt16
+		default:
+			stream[i] = int16(f64)
+		}
+	}
+
+	copy(pcm, codecs.S16LEToBytes(stream))
+}
+
+// decodes every file in the directory into memory. each clip is named after its file without the
+// extension. files that fail to decode are skipped
+func Load(logger *logging.Logger, cfg config.SoundboardConfig) (*Soundboard, error) {
+	if !cfg.Directory.IsSet() {
+		return nil, ErrSoundboardDisabled
+	}
+
+	entries, err := os.ReadDir(cfg.Directory.Get())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read soundboard directory: %w", err)
+	}
+
+	sb := &Soundboard{
+		logger:    logger,
+		clips:     make(map[string]*Clip),
+		cooldowns: syncext.SyncData[map[cooldownKey]time.Time]{Data: make(map[cooldownKey]time.Time)},
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, ok := sb.clips[name]; ok {
+			logger.Warn("skipping soundboard clip with duplicate name", "name", name, "file", entry.Name())
+			continue
+		}
+
+		clip := &Clip{
+			name:     name,
+			volume:   cfg.Volume,
+			cooldown: time.Duration(cfg.CooldownMs) * time.Millisecond,
+		}
+
+		if clipCfg, ok := cfg.Clips[name]; ok {
+			if clipCfg.Volume.IsSet() {
+				clip.volume = clipCfg.Volume.Get()
+			}
+
+			if clipCfg.CooldownMs.IsSet() {
+				clip.cooldown = time.Duration(clipCfg.CooldownMs.Get()) * time.Millisecond
+			}
+		}
+
+		clip.pcm, err = audiosession.DecodeFile(logger, filepath.Join(cfg.Directory.Get(), entry.Name()))
+		if err != nil {
+			logger.Warn("skipping soundboard clip that failed to decode", "name", name, "file", entry.Name(), "error", err)
+			continue
+		}
+
+		applyVolume(clip.pcm, clip.volume)
+
+		sb.clips[name] = clip
+		logger.Debug("loaded soundboard clip", "name", name, "nBytes", len(clip.pcm))
+	}
+
+	for name := range cfg.Clips {
+		if _, ok := sb.clips[name]; !ok {
+			logger.Warn("soundboard clip is configured but was not loaded", "name", name)
+		}
+	}
+
+	return sb, nil
+}
+
+// loads the soundboard from the configured directory. does nothing if no directory is configured
+func Init(logger *logging.Logger) error {
+	sb, err := Load(logger, config.Get().Soundboard)
+
+	switch {
+	case errors.Is(err, ErrSoundboardDisabled):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	loaded.Set(sb)
+	logger.Info("loaded soundboard", "nClips", len(sb.clips))
+
+	return nil
+}
+
+// returns [ErrSoundboardDisabled] if no soundboard was loaded
+func Get() (*Soundboard, error) {
+	if !loaded.IsSet() {
+		return nil, ErrSoundboardDisabled
+	}
+
+	return loaded.Get(), nil
+}
@@ -0,0 +1,45 @@
+package soundboard
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"accidentallycoded.com/fredboard/v3/internal/audio/codecs"
+	"accidentallycoded.com/fredboard/v3/internal/syncext"
+)
+
+func TestApplyVolume(t *testing.T) {
+	pcm := codecs.S16LEToBytes([]int16{100, -100, 20000, -20000})
+
+	applyVolume(pcm, 2)
+
+	expected := []int16{200, -200, math.MaxInt16, math.MinInt16}
+	if actual := codecs.BytesToS16LE(pcm); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestStartCooldown(t *testing.T) {
+	sb := &Soundboard{cooldowns: syncext.SyncData[map[cooldownKey]time.Time]{Data: make(map[cooldownKey]time.Time)}}
+	clip := &Clip{name: "airhorn", cooldown: time.Second}
+	now := time.Now()
+
+	if err := sb.startCooldown(clip, "a", now); err != nil {
+		t.Fatalf("first trigger returned error: %v", err)
+	}
+
+	if err := sb.startCooldown(clip, "a", now.Add(500*time.Millisecond)); !errors.Is(err, ErrClipCoolingDown) {
+		t.Errorf("trigger during cooldown returned %v, expected %v", err, ErrClipCoolingDown)
+	}
+
+	if err := sb.startCooldown(clip, "b", now.Add(500*time.Millisecond)); err != nil {
+		t.Errorf("trigger in another session returned error: %v", err)
+	}
+
+	if err := sb.startCooldown(clip, "a", now.Add(time.Second)); err != nil {
+		t.Errorf("trigger after cooldown returned error: %v", err)
+	}
+}
@@ -9,6 +9,9 @@ import (
 var (
 	PlayCommandExecutionsCounter metric.Int64Counter
 	ActiveAudioGraphsGauge       metric.Int64Gauge
+	JitterBufferFillGauge        metric.Int64Gauge
+	DiscordDroppedFramesCounter  metric.Int64Counter
+	DiscordLateFramesCounter     metric.Int64Counter
 )
 
 var _ error = (*MetricRegistrationError)(nil)
@@ -36,5 +39,20 @@ func setupMetrics(meter metric.Meter) (err error) {
 		return errors.Join(NewMetricRegistrationError("gauge.audioGraphs.active"), err)
 	}
 
+	JitterBufferFillGauge, err = meter.Int64Gauge("gauge.jitterBuffer.fillMs", metric.WithUnit("ms"))
+	if err != nil {
+		return errors.Join(NewMetricRegistrationError("gauge.jitterBuffer.fillMs"), err)
+	}
+
+	DiscordDroppedFramesCounter, err = meter.Int64Counter("counter.discord.framesDropped")
+	if err != nil {
+		return errors.Join(NewMetricRegistrationError("counter.discord.framesDropped"), err)
+	}
+
+	DiscordLateFramesCounter, err = meter.Int64Counter("counter.discord.framesLate")
+	if err != nil {
+		return errors.Join(NewMetricRegistrationError("counter.discord.framesLate"), err)
+	}
+
 	return nil
 }